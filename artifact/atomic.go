@@ -0,0 +1,138 @@
+package artifact
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// AtomicFile is written next to its destination and only renamed over it by
+// Commit, so an interrupted write never leaves a truncated artifact that
+// looks finished.
+type AtomicFile struct {
+	*os.File
+	path string
+}
+
+// CreateAtomic creates a temporary file to be committed to path.
+func CreateAtomic(path string) (*AtomicFile, error) {
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return nil, err
+	}
+
+	return &AtomicFile{File: file, path: path}, nil
+}
+
+// Commit syncs the file and moves it to its destination.
+func (f *AtomicFile) Commit() error {
+	if err := f.finish(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), f.path)
+}
+
+// CommitWithFingerprint commits the file along with its fingerprint, or
+// without the fingerprint of the artifact it replaces if fingerprint is empty.
+func (f *AtomicFile) CommitWithFingerprint(fingerprint string) error {
+	return f.commitWith(fingerprintSidecar(f.path, fingerprint))
+}
+
+// CommitWithChecksum commits the file along with sum, its hex sha256, and
+// its fingerprint, as CommitWithFingerprint does.
+func (f *AtomicFile) CommitWithChecksum(sum string, fingerprint string) error {
+	return f.commitWith(checksumSidecar(f.path, sum), fingerprintSidecar(f.path, fingerprint))
+}
+
+// sidecar is a file kept next to an artifact. A nil data removes it.
+type sidecar struct {
+	path string
+	data []byte
+}
+
+// commitWith commits the file and its sidecars. They are all written in full
+// before any is moved into place, and the artifact is moved first, so a
+// commit that fails leaves the old artifact next to its own sidecars. A
+// sidecar that can't be moved after the artifact is removed rather than left
+// stale next to the new artifact.
+func (f *AtomicFile) commitWith(sidecars ...sidecar) error {
+	files := make([]*AtomicFile, len(sidecars))
+	for i, sidecar := range sidecars {
+		if sidecar.data == nil {
+			continue
+		}
+
+		file, err := CreateAtomic(sidecar.path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		if _, err := file.Write(sidecar.data); err != nil {
+			return err
+		}
+		if err := file.finish(); err != nil {
+			return err
+		}
+		files[i] = file
+	}
+
+	if err := f.Commit(); err != nil {
+		return err
+	}
+
+	var errs []error
+	for i, file := range files {
+		if file != nil {
+			err := os.Rename(file.Name(), file.path)
+			if err == nil {
+				continue
+			}
+			errs = append(errs, err)
+		}
+		if err := os.Remove(sidecars[i].path); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// finish syncs and closes the file, ready to be moved.
+func (f *AtomicFile) finish() error {
+	if err := f.Chmod(0644); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+
+	return f.File.Close()
+}
+
+// Close drops the file unless it was committed.
+func (f *AtomicFile) Close() error {
+	f.File.Close()
+	if err := os.Remove(f.Name()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// WriteFileAtomic writes data to path through an AtomicFile, so a crash never
+// leaves a truncated file behind.
+func WriteFileAtomic(path string, data []byte) error {
+	file, err := CreateAtomic(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if _, err := file.Write(data); err != nil {
+		return err
+	}
+
+	return file.Commit()
+}
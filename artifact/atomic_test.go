@@ -0,0 +1,76 @@
+package artifact_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zilong-dai/groth16-worker/artifact"
+)
+
+func commitKey(t *testing.T, path string, data string, fingerprint string) error {
+	t.Helper()
+
+	file, err := artifact.CreateAtomic(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(data); err != nil {
+		t.Fatal(err)
+	}
+
+	return file.CommitWithFingerprint(fingerprint)
+}
+
+func TestCommitWithFingerprint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vk")
+
+	if err := commitKey(t, path, "old key", "old"); err != nil {
+		t.Fatal(err)
+	}
+	if err := commitKey(t, path, "new key", "new"); err != nil {
+		t.Fatal(err)
+	}
+	if fingerprint, err := artifact.ReadFingerprint(path); err != nil || fingerprint != "new" {
+		t.Fatalf("expected fingerprint new, got %q, %v", fingerprint, err)
+	}
+
+	// an empty fingerprint drops the one of the key it replaces
+	if err := commitKey(t, path, "unknown key", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path + ".fingerprint"); !os.IsNotExist(err) {
+		t.Fatalf("expected the fingerprint to be removed, got %v", err)
+	}
+}
+
+func TestCommitWithFingerprintFailure(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "vk")
+
+	// a non-empty directory at path can't be renamed over, so the commit
+	// fails after the fingerprint is written
+	if err := os.MkdirAll(filepath.Join(path, "key"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := artifact.WriteFingerprint(path, "old"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := commitKey(t, path, "new key", "new"); err == nil {
+		t.Fatal("expected the commit to fail")
+	}
+	if fingerprint, err := artifact.ReadFingerprint(path); err != nil || fingerprint != "old" {
+		t.Fatalf("expected fingerprint old to be kept, got %q, %v", fingerprint, err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected no temporary files to be left, got %v", entries)
+	}
+}
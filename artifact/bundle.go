@@ -0,0 +1,397 @@
+// Package artifact reads and writes the files a setup produces: bundles and
+// the fingerprint files kept next to standalone keys and circuits. It does not
+// depend on the constraint compiler, so verifier-only builds can use it.
+package artifact
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"runtime/debug"
+	"strings"
+
+	"github.com/consensys/gnark-crypto/ecc"
+)
+
+// A bundle keeps the artifacts of one setup in a single file:
+//
+//	magic (8 bytes) | version (uint16) | sections... | header (JSON) | footer
+//
+// The footer holds the offset and length of the header followed by the magic
+// again, so a reader can find the header from the end of the file and seek
+// straight to the sections it needs. Sections are written in one pass, which
+// keeps multi-gigabyte proving keys out of memory.
+const (
+	BundleVersion = 1
+
+	SectionCircuit      = "r1cs"
+	SectionProvingKey   = "pk"
+	SectionVerifyingKey = "vk"
+	SectionMetadata     = "metadata"
+
+	BackendGroth16 = "groth16"
+
+	gnarkModule = "github.com/consensys/gnark"
+)
+
+var bundleMagic = [8]byte{'G', '1', '6', 'W', 'K', 'R', 'B', 'N'}
+
+// footer: header offset (uint64), header length (uint32), magic
+const bundleFooterSize = 8 + 4 + len(bundleMagic)
+
+var (
+	ErrInvalidBundle    = errors.New("invalid bundle")
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	ErrMissingSection   = errors.New("missing section")
+	ErrCurveMismatch    = errors.New("curve mismatch")
+	// ErrGnarkVersionMismatch is returned for a bundle written by a gnark
+	// release whose encodings may differ from the one the binary was built
+	// with.
+	ErrGnarkVersionMismatch = errors.New("gnark version mismatch")
+)
+
+type BundleHeader struct {
+	Version      int             `json:"version"`
+	Curve        string          `json:"curve"`
+	Backend      string          `json:"backend"`
+	GnarkVersion string          `json:"gnark_version"`
+	Fingerprint  string          `json:"fingerprint"`
+	Sections     []BundleSection `json:"sections"`
+}
+
+type BundleSection struct {
+	Name   string `json:"name"`
+	Offset int64  `json:"offset"`
+	Length int64  `json:"length"`
+	SHA256 string `json:"sha256"`
+}
+
+func (s BundleSection) String() string {
+	return fmt.Sprintf("%s offset=%d length=%d sha256=%s", s.Name, s.Offset, s.Length, s.SHA256)
+}
+
+// BundleMetadata is stored in the metadata section.
+type BundleMetadata struct {
+	NumConstraints  int `json:"num_constraints"`
+	NumPublicInputs int `json:"num_public_inputs"`
+	NumSecretInputs int `json:"num_secret_inputs"`
+}
+
+func (h *BundleHeader) Section(name string) (BundleSection, bool) {
+	for _, section := range h.Sections {
+		if section.Name == name {
+			return section, true
+		}
+	}
+	return BundleSection{}, false
+}
+
+// GnarkVersion returns the gnark version the binary was built with.
+func GnarkVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+
+	for _, dep := range info.Deps {
+		if dep.Path == gnarkModule {
+			if dep.Replace != nil {
+				return dep.Replace.Version
+			}
+			return dep.Version
+		}
+	}
+
+	return "unknown"
+}
+
+// BundleWriter writes a bundle one section at a time.
+type BundleWriter struct {
+	w      *bufio.Writer
+	offset int64
+	header BundleHeader
+}
+
+func NewBundleWriter(w io.Writer, header BundleHeader) (*BundleWriter, error) {
+	header.Version = BundleVersion
+	b := &BundleWriter{w: bufio.NewWriter(w), header: header}
+
+	if _, err := b.w.Write(bundleMagic[:]); err != nil {
+		return nil, err
+	}
+	if err := binary.Write(b.w, binary.BigEndian, uint16(BundleVersion)); err != nil {
+		return nil, err
+	}
+	b.offset = int64(len(bundleMagic)) + 2
+
+	return b, nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (b *BundleWriter) Section(name string, from io.WriterTo) error {
+	h := sha256.New()
+	out := &countingWriter{w: io.MultiWriter(b.w, h)}
+
+	if _, err := from.WriteTo(out); err != nil {
+		return fmt.Errorf("failed to write %s section: %w", name, err)
+	}
+
+	b.header.Sections = append(b.header.Sections, BundleSection{
+		Name:   name,
+		Offset: b.offset,
+		Length: out.n,
+		SHA256: hex.EncodeToString(h.Sum(nil)),
+	})
+	b.offset += out.n
+
+	return nil
+}
+
+// Close writes the header and footer. It does not close the underlying writer.
+func (b *BundleWriter) Close() error {
+	header, err := json.Marshal(b.header)
+	if err != nil {
+		return fmt.Errorf("failed to encode bundle header: %w", err)
+	}
+
+	if _, err := b.w.Write(header); err != nil {
+		return fmt.Errorf("failed to write bundle header: %w", err)
+	}
+
+	footer := make([]byte, 0, bundleFooterSize)
+	footer = binary.BigEndian.AppendUint64(footer, uint64(b.offset))
+	footer = binary.BigEndian.AppendUint32(footer, uint32(len(header)))
+	footer = append(footer, bundleMagic[:]...)
+	if _, err := b.w.Write(footer); err != nil {
+		return fmt.Errorf("failed to write bundle footer: %w", err)
+	}
+
+	return b.w.Flush()
+}
+
+// Bundle is an open bundle file.
+type Bundle struct {
+	Header BundleHeader
+	file   *os.File
+}
+
+func OpenBundle(path string) (*Bundle, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bundle file: %w", err)
+	}
+
+	header, err := readBundleHeader(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &Bundle{Header: header, file: file}, nil
+}
+
+func readBundleHeader(file *os.File) (BundleHeader, error) {
+	var header BundleHeader
+
+	stat, err := file.Stat()
+	if err != nil {
+		return header, fmt.Errorf("failed to stat bundle file: %w", err)
+	}
+	size := stat.Size()
+
+	prefix := make([]byte, len(bundleMagic)+2)
+	if _, err := file.ReadAt(prefix, 0); err != nil {
+		return header, fmt.Errorf("%w: file too short", ErrInvalidBundle)
+	}
+	if !bytes.Equal(prefix[:len(bundleMagic)], bundleMagic[:]) {
+		return header, fmt.Errorf("%w: bad magic", ErrInvalidBundle)
+	}
+	if version := binary.BigEndian.Uint16(prefix[len(bundleMagic):]); version != BundleVersion {
+		return header, fmt.Errorf("%w: unsupported version %d", ErrInvalidBundle, version)
+	}
+
+	if size < int64(len(prefix)+bundleFooterSize) {
+		return header, fmt.Errorf("%w: file too short", ErrInvalidBundle)
+	}
+	footer := make([]byte, bundleFooterSize)
+	if _, err := file.ReadAt(footer, size-int64(bundleFooterSize)); err != nil {
+		return header, fmt.Errorf("failed to read bundle footer: %w", err)
+	}
+	if !bytes.Equal(footer[12:], bundleMagic[:]) {
+		return header, fmt.Errorf("%w: bad footer", ErrInvalidBundle)
+	}
+
+	headerOffset := int64(binary.BigEndian.Uint64(footer[:8]))
+	headerLength := int64(binary.BigEndian.Uint32(footer[8:12]))
+	if headerOffset < int64(len(prefix)) || headerOffset+headerLength != size-int64(bundleFooterSize) {
+		return header, fmt.Errorf("%w: bad header offset", ErrInvalidBundle)
+	}
+
+	raw := make([]byte, headerLength)
+	if _, err := file.ReadAt(raw, headerOffset); err != nil {
+		return header, fmt.Errorf("failed to read bundle header: %w", err)
+	}
+	if err := json.Unmarshal(raw, &header); err != nil {
+		return header, fmt.Errorf("%w: bad header: %s", ErrInvalidBundle, err)
+	}
+
+	for _, section := range header.Sections {
+		if section.Offset < int64(len(prefix)) || section.Length < 0 || section.Offset+section.Length > headerOffset {
+			return header, fmt.Errorf("%w: section %s is out of bounds", ErrInvalidBundle, section.Name)
+		}
+	}
+
+	return header, nil
+}
+
+func (b *Bundle) Close() error {
+	return b.file.Close()
+}
+
+// checksumReader hashes everything read through it.
+type checksumReader struct {
+	r io.Reader
+	h hash.Hash
+}
+
+func (c *checksumReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.h.Write(p[:n])
+	return n, err
+}
+
+// SectionReader returns a reader over the raw bytes of a section.
+func (b *Bundle) SectionReader(name string) (*io.SectionReader, BundleSection, error) {
+	section, ok := b.Header.Section(name)
+	if !ok {
+		return nil, section, fmt.Errorf("%w: %s", ErrMissingSection, name)
+	}
+
+	return io.NewSectionReader(b.file, section.Offset, section.Length), section, nil
+}
+
+// ReadSection decodes a section into to and checks its checksum.
+func (b *Bundle) ReadSection(name string, to io.ReaderFrom) error {
+	reader, section, err := b.SectionReader(name)
+	if err != nil {
+		return err
+	}
+
+	checksum := &checksumReader{r: reader, h: sha256.New()}
+	_, decodeErr := to.ReadFrom(bufio.NewReader(checksum))
+
+	// decoders may stop short of the end, hash whatever they left behind. A
+	// corrupted section usually fails to decode as well, the checksum error
+	// is the more useful one to report.
+	if _, err := io.Copy(io.Discard, checksum); err != nil {
+		return fmt.Errorf("failed to read %s section: %w", name, err)
+	}
+
+	if sum := hex.EncodeToString(checksum.h.Sum(nil)); sum != section.SHA256 {
+		return fmt.Errorf("%w: %s section has sha256 %s, header says %s", ErrChecksumMismatch, name, sum, section.SHA256)
+	}
+
+	if decodeErr != nil {
+		return fmt.Errorf("failed to read %s section: %w", name, decodeErr)
+	}
+
+	return nil
+}
+
+// Verify checks the checksum of every section without decoding them.
+func (b *Bundle) Verify() error {
+	for _, section := range b.Header.Sections {
+		if err := b.ReadSection(section.Name, discard{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type discard struct{}
+
+func (discard) ReadFrom(r io.Reader) (int64, error) {
+	return io.Copy(io.Discard, r)
+}
+
+// ReadMetadata decodes the metadata section.
+func (b *Bundle) ReadMetadata() (BundleMetadata, error) {
+	var metadata BundleMetadata
+	if err := b.ReadSection(SectionMetadata, &metadata); err != nil {
+		return metadata, err
+	}
+	return metadata, nil
+}
+
+func (m *BundleMetadata) ReadFrom(r io.Reader) (int64, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return int64(len(data)), err
+	}
+	return int64(len(data)), json.Unmarshal(data, m)
+}
+
+func (m *BundleMetadata) WriteTo(w io.Writer) (int64, error) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
+// Check makes sure a bundle was written for curveId by the groth16 backend,
+// with a gnark release whose encodings this binary can read.
+func (h *BundleHeader) Check(curveId ecc.ID) error {
+	if h.Backend != BackendGroth16 {
+		return fmt.Errorf("%w: backend %s is not supported", ErrInvalidBundle, h.Backend)
+	}
+	if h.Curve != curveId.String() {
+		return fmt.Errorf("%w: bundle is on %s, expected %s", ErrCurveMismatch, h.Curve, curveId)
+	}
+	if version := GnarkVersion(); !sameGnarkRelease(h.GnarkVersion, version) {
+		return fmt.Errorf("%w: bundle was written with gnark %s, this binary uses %s", ErrGnarkVersionMismatch, h.GnarkVersion, version)
+	}
+	return nil
+}
+
+// sameGnarkRelease reports whether two gnark versions share their major and
+// minor version, which is what gnark keeps its encodings stable across. An
+// unknown version matches any other.
+func sameGnarkRelease(a, b string) bool {
+	if !knownVersion(a) || !knownVersion(b) {
+		return true
+	}
+	return majorMinor(a) == majorMinor(b)
+}
+
+func knownVersion(v string) bool {
+	return strings.HasPrefix(v, "v")
+}
+
+// majorMinor returns the vX.Y prefix of a module version.
+func majorMinor(v string) string {
+	parts := strings.SplitN(v, ".", 3)
+	if len(parts) < 2 {
+		return v
+	}
+	return parts[0] + "." + parts[1]
+}
@@ -0,0 +1,226 @@
+package artifact_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/zilong-dai/groth16-worker/artifact"
+)
+
+func testHeader() artifact.BundleHeader {
+	return artifact.BundleHeader{
+		Curve:        ecc.BLS12_381.String(),
+		Backend:      artifact.BackendGroth16,
+		GnarkVersion: artifact.GnarkVersion(),
+		Fingerprint:  "cubic",
+	}
+}
+
+// writeBundle writes a bundle with a verifying key and a metadata section.
+func writeBundle(t *testing.T) []byte {
+	t.Helper()
+
+	var bundle bytes.Buffer
+	writer, err := artifact.NewBundleWriter(&bundle, testHeader())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Section(artifact.SectionVerifyingKey, bytes.NewBufferString("verifying key")); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Section(artifact.SectionMetadata, &artifact.BundleMetadata{NumConstraints: 3, NumPublicInputs: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return bundle.Bytes()
+}
+
+// assembleBundle lays out a bundle by hand around body, so its header can
+// say anything.
+func assembleBundle(t *testing.T, header artifact.BundleHeader, body []byte) []byte {
+	t.Helper()
+
+	data := []byte("G16WKRBN")
+	data = binary.BigEndian.AppendUint16(data, artifact.BundleVersion)
+	data = append(data, body...)
+	headerOffset := len(data)
+
+	raw, err := json.Marshal(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = append(data, raw...)
+	data = binary.BigEndian.AppendUint64(data, uint64(headerOffset))
+	data = binary.BigEndian.AppendUint32(data, uint32(len(raw)))
+	data = append(data, "G16WKRBN"...)
+
+	return data
+}
+
+func openBundle(t *testing.T, data []byte) (*artifact.Bundle, error) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "setup.bundle")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	bundle, err := artifact.OpenBundle(path)
+	if err == nil {
+		t.Cleanup(func() { bundle.Close() })
+	}
+	return bundle, err
+}
+
+func TestBundleRoundTrip(t *testing.T) {
+	bundle, err := openBundle(t, writeBundle(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := bundle.Header.Check(ecc.BLS12_381); err != nil {
+		t.Fatal(err)
+	}
+	if bundle.Header.Version != artifact.BundleVersion || bundle.Header.Fingerprint != "cubic" || len(bundle.Header.Sections) != 2 {
+		t.Fatalf("unexpected header %+v", bundle.Header)
+	}
+	if err := bundle.Verify(); err != nil {
+		t.Fatal(err)
+	}
+
+	var key bytes.Buffer
+	if err := bundle.ReadSection(artifact.SectionVerifyingKey, &key); err != nil {
+		t.Fatal(err)
+	}
+	if key.String() != "verifying key" {
+		t.Fatalf("unexpected verifying key section %q", key.String())
+	}
+	metadata, err := bundle.ReadMetadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata.NumConstraints != 3 || metadata.NumPublicInputs != 1 {
+		t.Fatalf("unexpected metadata %+v", metadata)
+	}
+
+	if err := bundle.ReadSection(artifact.SectionProvingKey, &key); !errors.Is(err, artifact.ErrMissingSection) {
+		t.Fatalf("expected missing section error, got %v", err)
+	}
+}
+
+func TestBundleInvalid(t *testing.T) {
+	valid := writeBundle(t)
+
+	badVersion := bytes.Clone(valid)
+	binary.BigEndian.PutUint16(badVersion[8:], artifact.BundleVersion+1)
+
+	badFooter := bytes.Clone(valid)
+	badFooter[len(badFooter)-1] ^= 0xff
+
+	badHeaderOffset := bytes.Clone(valid)
+	binary.BigEndian.PutUint64(badHeaderOffset[len(badHeaderOffset)-20:], 1<<40)
+
+	badHeader := assembleBundle(t, testHeader(), nil)
+	badHeader[len(badHeader)-21] = '!'
+
+	outOfRange := func(section artifact.BundleSection) []byte {
+		header := testHeader()
+		header.Version = artifact.BundleVersion
+		header.Sections = []artifact.BundleSection{section}
+		return assembleBundle(t, header, []byte("verifying key"))
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"truncated prefix", valid[:5]},
+		{"truncated footer", valid[:len(valid)-5]},
+		{"truncated header", valid[:12]},
+		{"bad magic", append([]byte("NOTABNDL"), valid[8:]...)},
+		{"wrong version", badVersion},
+		{"bad footer", badFooter},
+		{"header offset out of range", badHeaderOffset},
+		{"header is not json", badHeader},
+		{"section before the body", outOfRange(artifact.BundleSection{Name: artifact.SectionVerifyingKey, Offset: 2, Length: 13})},
+		{"section past the header", outOfRange(artifact.BundleSection{Name: artifact.SectionVerifyingKey, Offset: 10, Length: 100})},
+		{"negative section length", outOfRange(artifact.BundleSection{Name: artifact.SectionVerifyingKey, Offset: 10, Length: -1})},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := openBundle(t, test.data); !errors.Is(err, artifact.ErrInvalidBundle) {
+				t.Fatalf("expected invalid bundle error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestBundleChecksumMismatch(t *testing.T) {
+	data := writeBundle(t)
+	data[bytes.Index(data, []byte("verifying key"))] ^= 0xff
+
+	bundle, err := openBundle(t, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var key bytes.Buffer
+	if err := bundle.ReadSection(artifact.SectionVerifyingKey, &key); !errors.Is(err, artifact.ErrChecksumMismatch) {
+		t.Fatalf("expected checksum mismatch, got %v", err)
+	}
+	if err := bundle.Verify(); !errors.Is(err, artifact.ErrChecksumMismatch) {
+		t.Fatalf("expected checksum mismatch, got %v", err)
+	}
+	// the other sections still read
+	if _, err := bundle.ReadMetadata(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBundleHeaderCheck(t *testing.T) {
+	header := testHeader()
+	if err := header.Check(ecc.BLS12_381); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := header.Check(ecc.BN254); !errors.Is(err, artifact.ErrCurveMismatch) {
+		t.Fatalf("expected curve mismatch, got %v", err)
+	}
+
+	plonk := testHeader()
+	plonk.Backend = "plonk"
+	if err := plonk.Check(ecc.BLS12_381); !errors.Is(err, artifact.ErrInvalidBundle) {
+		t.Fatalf("expected invalid bundle error for another backend, got %v", err)
+	}
+
+	// a bundle from another gnark release is rejected when opened as well
+	other := testHeader()
+	other.Version = artifact.BundleVersion
+	other.GnarkVersion = "v0.1.0"
+	bundle, err := openBundle(t, assembleBundle(t, other, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bundle.Header.Check(ecc.BLS12_381); !errors.Is(err, artifact.ErrGnarkVersionMismatch) {
+		t.Fatalf("expected gnark version mismatch, got %v", err)
+	}
+
+	// patch releases and unknown versions are accepted
+	for _, version := range []string{"unknown", ""} {
+		other.GnarkVersion = version
+		if err := other.Check(ecc.BLS12_381); err != nil {
+			t.Fatalf("expected gnark version %q to be accepted, got %v", version, err)
+		}
+	}
+}
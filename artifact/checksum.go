@@ -0,0 +1,81 @@
+package artifact
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ChecksumSuffix is appended to the path of an artifact to get the file
+// holding its sha256. The file uses the sha256sum format, so it can also be
+// checked with sha256sum -c from the artifact's directory.
+const ChecksumSuffix = ".sha256"
+
+// WriteChecksum stores sum, the hex sha256 of the artifact, next to it.
+func WriteChecksum(artifactPath string, sum string) error {
+	if err := WriteFileAtomic(artifactPath+ChecksumSuffix, checksumSidecar(artifactPath, sum).data); err != nil {
+		return fmt.Errorf("failed to write checksum: %w", err)
+	}
+
+	return nil
+}
+
+// checksumSidecar is the checksum file of an artifact, in the sha256sum
+// format.
+func checksumSidecar(artifactPath string, sum string) sidecar {
+	return sidecar{path: artifactPath + ChecksumSuffix, data: []byte(fmt.Sprintf("%s  %s\n", sum, filepath.Base(artifactPath)))}
+}
+
+// ReadChecksum returns the sha256 stored next to an artifact, or an empty
+// string if there is none.
+func ReadChecksum(artifactPath string) (string, error) {
+	data, err := os.ReadFile(artifactPath + ChecksumSuffix)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read checksum: %w", err)
+	}
+
+	sum, _, _ := strings.Cut(strings.TrimSpace(string(data)), " ")
+	return sum, nil
+}
+
+// FileChecksum returns the hex sha256 of the file at path.
+func FileChecksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// VerifyChecksum hashes the artifact at path and compares it with the checksum
+// stored next to it. It returns false if there is no stored checksum.
+func VerifyChecksum(artifactPath string) (bool, error) {
+	want, err := ReadChecksum(artifactPath)
+	if err != nil || want == "" {
+		return false, err
+	}
+
+	sum, err := FileChecksum(artifactPath)
+	if err != nil {
+		return false, err
+	}
+	if sum != want {
+		return false, fmt.Errorf("%w: %s has sha256 %s, %s%s says %s", ErrChecksumMismatch, artifactPath, sum, artifactPath, ChecksumSuffix, want)
+	}
+
+	return true, nil
+}
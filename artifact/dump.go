@@ -0,0 +1,446 @@
+package artifact
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"unsafe"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	curve "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/consensys/gnark-crypto/ecc/bls12-381/fr/pedersen"
+	"github.com/consensys/gnark/backend/groth16"
+	groth16_bls12381 "github.com/consensys/gnark/backend/groth16/bls12-381"
+)
+
+// A dump stores the points of a key as they are laid out in memory, in
+// Montgomery form, so loading it is a plain copy with no field conversions
+// and no curve or subgroup checks:
+//
+//	magic (8 bytes) | version (uint16) | kind (uint8) | curve (uint16) | byte order mark (uint64) | key
+//
+// Slices are prefixed with their length as a uint64. Dumps can only be read
+// on machines with the byte order they were written with, and must only be
+// loaded from trusted storage.
+const (
+	DumpVersion    = 1
+	DumpHeaderSize = len(dumpMagic) + 2 + 1 + 2 + 8
+
+	DumpProvingKey   = 1
+	DumpVerifyingKey = 2
+
+	dumpByteOrderMark = 0x0102030405060708
+)
+
+var dumpMagic = [8]byte{'G', '1', '6', 'W', 'K', 'D', 'M', 'P'}
+
+var ErrInvalidDump = errors.New("invalid key dump")
+
+// IsDump reports whether prefix starts with the magic of a key dump.
+func IsDump(prefix []byte) bool {
+	return bytes.HasPrefix(prefix, dumpMagic[:])
+}
+
+// DumpKind returns DumpProvingKey or DumpVerifyingKey for the header of a
+// dump, and 0 if header is not one.
+func DumpKind(header []byte) uint8 {
+	if len(header) < DumpHeaderSize || !IsDump(header) {
+		return 0
+	}
+	return header[10]
+}
+
+// dumpWriter writes the fields of a dump and keeps the first error.
+type dumpWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (d *dumpWriter) write(b []byte) {
+	if d.err != nil {
+		return
+	}
+	n, err := d.w.Write(b)
+	d.n += int64(n)
+	d.err = err
+}
+
+func (d *dumpWriter) writerTo(from io.WriterTo) {
+	if d.err != nil {
+		return
+	}
+	n, err := from.WriteTo(d.w)
+	d.n += n
+	d.err = err
+}
+
+func (d *dumpWriter) uint64(v uint64) {
+	d.write(binary.BigEndian.AppendUint64(nil, v))
+}
+
+func (d *dumpWriter) header(kind uint8, curveId ecc.ID) {
+	d.write(dumpMagic[:])
+	d.write(binary.BigEndian.AppendUint16(nil, DumpVersion))
+	d.write([]byte{kind})
+	d.write(binary.BigEndian.AppendUint16(nil, uint16(curveId)))
+	// native byte order, checked on load
+	d.write(asBytes([]uint64{dumpByteOrderMark}))
+}
+
+// dumpSlice writes the memory of s behind its length.
+func dumpSlice[T any](d *dumpWriter, s []T) {
+	d.uint64(uint64(len(s)))
+	d.write(asBytes(s))
+}
+
+func asBytes[T any](s []T) []byte {
+	if len(s) == 0 {
+		return nil
+	}
+	var zero T
+	return unsafe.Slice((*byte)(unsafe.Pointer(&s[0])), len(s)*int(unsafe.Sizeof(zero)))
+}
+
+// dumpReader reads the fields of a dump and keeps the first error. size is
+// the number of bytes the dump can take, or -1 if it isn't known.
+type dumpReader struct {
+	r    io.Reader
+	size int64
+	n    int64
+	err  error
+}
+
+func (d *dumpReader) read(b []byte) {
+	if d.err != nil {
+		return
+	}
+	n, err := io.ReadFull(d.r, b)
+	d.n += int64(n)
+	d.err = err
+}
+
+func (d *dumpReader) readerFrom(to io.ReaderFrom) {
+	if d.err != nil {
+		return
+	}
+	n, err := to.ReadFrom(d.r)
+	d.n += n
+	d.err = err
+}
+
+func (d *dumpReader) uint64() uint64 {
+	var buf [8]byte
+	d.read(buf[:])
+	return binary.BigEndian.Uint64(buf[:])
+}
+
+func (d *dumpReader) header(kind uint8, curveId ecc.ID) {
+	var buf [len(dumpMagic) + 2 + 1 + 2]byte
+	d.read(buf[:])
+	if d.err != nil {
+		return
+	}
+
+	if !IsDump(buf[:]) {
+		d.err = fmt.Errorf("%w: bad magic", ErrInvalidDump)
+		return
+	}
+	if version := binary.BigEndian.Uint16(buf[8:]); version != DumpVersion {
+		d.err = fmt.Errorf("%w: unsupported version %d", ErrInvalidDump, version)
+		return
+	}
+	if buf[10] != kind {
+		d.err = fmt.Errorf("%w: wrong key type", ErrInvalidDump)
+		return
+	}
+	if got := ecc.ID(binary.BigEndian.Uint16(buf[11:])); got != curveId {
+		d.err = fmt.Errorf("%w: dump is on %s, expected %s", ErrCurveMismatch, got, curveId)
+		return
+	}
+
+	mark := make([]uint64, 1)
+	d.read(asBytes(mark))
+	if d.err == nil && mark[0] != dumpByteOrderMark {
+		d.err = fmt.Errorf("%w: written on a machine with a different byte order", ErrInvalidDump)
+	}
+}
+
+// dumpChunk is how many bytes of a slice are read at a time when the size of
+// the dump isn't known.
+const dumpChunk = 1 << 24
+
+// loadSlice reads a slice written by dumpSlice. Its length must fit in what is
+// left of the dump. If the size of the dump isn't known, the slice is grown as
+// its elements are read, so a corrupted length fails at the end of the input
+// instead of allocating terabytes.
+func loadSlice[T any](d *dumpReader) []T {
+	n := d.uint64()
+	if d.err != nil {
+		return nil
+	}
+
+	var zero T
+	size := uint64(unsafe.Sizeof(zero))
+	if d.size >= 0 {
+		if left := uint64(max(d.size-d.n, 0)); n > left/size {
+			d.err = fmt.Errorf("%w: slice of %d elements doesn't fit in the %d bytes left", ErrInvalidDump, n, left)
+			return nil
+		}
+		s := make([]T, n)
+		d.read(asBytes(s))
+		return s
+	}
+
+	chunk := max(dumpChunk/size, 1)
+	s := make([]T, 0, min(n, chunk))
+	for uint64(len(s)) < n && d.err == nil {
+		start := len(s)
+		k := int(min(n-uint64(start), chunk))
+		s = slices.Grow(s, k)[:start+k]
+		d.read(asBytes(s[start:]))
+	}
+	if d.err != nil {
+		return nil
+	}
+	return s
+}
+
+// loadBools reads a []bool written by dumpSlice, checking that every byte is
+// a valid bool.
+func loadBools(d *dumpReader) []bool {
+	s := loadSlice[bool](d)
+	for i, b := range asBytes(s) {
+		if b > 1 {
+			d.err = fmt.Errorf("%w: byte %d of a bool slice is %#x", ErrInvalidDump, i, b)
+			return nil
+		}
+	}
+	return s
+}
+
+// readerSize returns the number of bytes left in r, or -1 if it can't tell.
+func readerSize(r io.Reader) int64 {
+	switch r := r.(type) {
+	case *io.LimitedReader:
+		return r.N
+	case *io.SectionReader:
+		offset, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return -1
+		}
+		return r.Size() - offset
+	case *os.File:
+		info, err := r.Stat()
+		if err != nil || !info.Mode().IsRegular() {
+			return -1
+		}
+		offset, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return -1
+		}
+		return info.Size() - offset
+	case interface{ Len() int }:
+		return int64(r.Len())
+	}
+	return -1
+}
+
+// WriteProvingKeyDump writes pk in the dump format.
+func WriteProvingKeyDump(w io.Writer, pk groth16.ProvingKey) (int64, error) {
+	key, ok := pk.(*groth16_bls12381.ProvingKey)
+	if !ok {
+		return 0, fmt.Errorf("%w: dumps are only supported on %s", ErrInvalidDump, ecc.BLS12_381)
+	}
+
+	d := &dumpWriter{w: w}
+	d.header(DumpProvingKey, ecc.BLS12_381)
+	d.writerTo(&key.Domain)
+	dumpSlice(d, []curve.G1Affine{key.G1.Alpha, key.G1.Beta, key.G1.Delta})
+	dumpSlice(d, key.G1.A)
+	dumpSlice(d, key.G1.B)
+	dumpSlice(d, key.G1.Z)
+	dumpSlice(d, key.G1.K)
+	dumpSlice(d, []curve.G2Affine{key.G2.Beta, key.G2.Delta})
+	dumpSlice(d, key.G2.B)
+	dumpSlice(d, key.InfinityA)
+	dumpSlice(d, key.InfinityB)
+	d.uint64(key.NbInfinityA)
+	d.uint64(key.NbInfinityB)
+
+	// pedersen keys keep their points unexported, they are small enough to go
+	// through their own encoding
+	d.uint64(uint64(len(key.CommitmentKeys)))
+	for i := range key.CommitmentKeys {
+		d.writerTo(rawWriter{&key.CommitmentKeys[i]})
+	}
+
+	return d.n, d.err
+}
+
+// readProvingKeyDump reads a dump written by WriteProvingKeyDump into pk.
+func readProvingKeyDump(r io.Reader, size int64, pk groth16.ProvingKey) (int64, error) {
+	key, ok := pk.(*groth16_bls12381.ProvingKey)
+	if !ok {
+		return 0, fmt.Errorf("%w: dumps are only supported on %s", ErrInvalidDump, ecc.BLS12_381)
+	}
+
+	d := &dumpReader{r: r, size: size}
+	d.header(DumpProvingKey, ecc.BLS12_381)
+	d.readerFrom(&key.Domain)
+
+	g1 := loadSlice[curve.G1Affine](d)
+	if d.err == nil && len(g1) != 3 {
+		d.err = fmt.Errorf("%w: expected 3 fixed G1 points, got %d", ErrInvalidDump, len(g1))
+	}
+	if d.err == nil {
+		key.G1.Alpha, key.G1.Beta, key.G1.Delta = g1[0], g1[1], g1[2]
+	}
+	key.G1.A = loadSlice[curve.G1Affine](d)
+	key.G1.B = loadSlice[curve.G1Affine](d)
+	key.G1.Z = loadSlice[curve.G1Affine](d)
+	key.G1.K = loadSlice[curve.G1Affine](d)
+
+	g2 := loadSlice[curve.G2Affine](d)
+	if d.err == nil && len(g2) != 2 {
+		d.err = fmt.Errorf("%w: expected 2 fixed G2 points, got %d", ErrInvalidDump, len(g2))
+	}
+	if d.err == nil {
+		key.G2.Beta, key.G2.Delta = g2[0], g2[1]
+	}
+	key.G2.B = loadSlice[curve.G2Affine](d)
+
+	key.InfinityA = loadBools(d)
+	key.InfinityB = loadBools(d)
+	key.NbInfinityA = d.uint64()
+	key.NbInfinityB = d.uint64()
+
+	commitments := d.uint64()
+	if d.err == nil && commitments > uint64(len(key.InfinityA)) {
+		d.err = fmt.Errorf("%w: %d commitment keys", ErrInvalidDump, commitments)
+	}
+	if d.err == nil {
+		key.CommitmentKeys = make([]pedersen.ProvingKey, commitments)
+		for i := range key.CommitmentKeys {
+			d.readerFrom(&key.CommitmentKeys[i])
+		}
+	}
+
+	return d.n, d.err
+}
+
+// WriteVerifyingKeyDump writes vk in the dump format.
+func WriteVerifyingKeyDump(w io.Writer, vk groth16.VerifyingKey) (int64, error) {
+	key, ok := vk.(*groth16_bls12381.VerifyingKey)
+	if !ok {
+		return 0, fmt.Errorf("%w: dumps are only supported on %s", ErrInvalidDump, ecc.BLS12_381)
+	}
+
+	d := &dumpWriter{w: w}
+	d.header(DumpVerifyingKey, ecc.BLS12_381)
+	dumpSlice(d, []curve.G1Affine{key.G1.Alpha, key.G1.Beta, key.G1.Delta})
+	dumpSlice(d, key.G1.K)
+	dumpSlice(d, []curve.G2Affine{key.G2.Beta, key.G2.Delta, key.G2.Gamma})
+	d.uint64(uint64(len(key.PublicAndCommitmentCommitted)))
+	for _, committed := range key.PublicAndCommitmentCommitted {
+		indexes := make([]uint64, len(committed))
+		for i, index := range committed {
+			indexes[i] = uint64(index)
+		}
+		dumpSlice(d, indexes)
+	}
+	d.writerTo(rawWriter{&key.CommitmentKey})
+
+	return d.n, d.err
+}
+
+// readVerifyingKeyDump reads a dump written by WriteVerifyingKeyDump into vk.
+func readVerifyingKeyDump(r io.Reader, size int64, vk groth16.VerifyingKey) (int64, error) {
+	key, ok := vk.(*groth16_bls12381.VerifyingKey)
+	if !ok {
+		return 0, fmt.Errorf("%w: dumps are only supported on %s", ErrInvalidDump, ecc.BLS12_381)
+	}
+
+	d := &dumpReader{r: r, size: size}
+	d.header(DumpVerifyingKey, ecc.BLS12_381)
+
+	g1 := loadSlice[curve.G1Affine](d)
+	if d.err == nil && len(g1) != 3 {
+		d.err = fmt.Errorf("%w: expected 3 fixed G1 points, got %d", ErrInvalidDump, len(g1))
+	}
+	if d.err == nil {
+		key.G1.Alpha, key.G1.Beta, key.G1.Delta = g1[0], g1[1], g1[2]
+	}
+	key.G1.K = loadSlice[curve.G1Affine](d)
+
+	g2 := loadSlice[curve.G2Affine](d)
+	if d.err == nil && len(g2) != 3 {
+		d.err = fmt.Errorf("%w: expected 3 fixed G2 points, got %d", ErrInvalidDump, len(g2))
+	}
+	if d.err == nil {
+		key.G2.Beta, key.G2.Delta, key.G2.Gamma = g2[0], g2[1], g2[2]
+	}
+
+	committed := d.uint64()
+	if d.err == nil && committed > uint64(len(key.G1.K)) {
+		d.err = fmt.Errorf("%w: %d committed groups", ErrInvalidDump, committed)
+	}
+	if d.err == nil {
+		key.PublicAndCommitmentCommitted = make([][]int, committed)
+		for i := range key.PublicAndCommitmentCommitted {
+			indexes := loadSlice[uint64](d)
+			key.PublicAndCommitmentCommitted[i] = make([]int, len(indexes))
+			for j, index := range indexes {
+				key.PublicAndCommitmentCommitted[i][j] = int(index)
+			}
+		}
+	}
+	d.readerFrom(&key.CommitmentKey)
+
+	if d.err == nil {
+		// e(α, β), -[δ]₂ and -[γ]₂ are not stored
+		d.err = key.Precompute()
+	}
+
+	return d.n, d.err
+}
+
+// rawWriter writes a key with uncompressed points.
+type rawWriter struct {
+	key interface {
+		WriteRawTo(io.Writer) (int64, error)
+	}
+}
+
+func (r rawWriter) WriteTo(w io.Writer) (int64, error) {
+	return r.key.WriteRawTo(w)
+}
+
+// ReadProvingKey decodes a proving key written by WriteTo, WriteRawTo or
+// WriteProvingKeyDump into pk. The lengths in a dump are checked against the
+// size of r when it is a file, a section or an io.LimitedReader.
+func ReadProvingKey(r io.Reader, pk groth16.ProvingKey) (int64, error) {
+	size := readerSize(r)
+	br := bufio.NewReaderSize(r, 1<<20)
+	if prefix, _ := br.Peek(len(dumpMagic)); IsDump(prefix) {
+		return readProvingKeyDump(br, size, pk)
+	}
+	return pk.ReadFrom(br)
+}
+
+// ReadVerifyingKey decodes a verifying key written by WriteTo, WriteRawTo or
+// WriteVerifyingKeyDump into vk, checking the lengths in a dump as
+// ReadProvingKey does.
+func ReadVerifyingKey(r io.Reader, vk groth16.VerifyingKey) (int64, error) {
+	size := readerSize(r)
+	br := bufio.NewReader(r)
+	if prefix, _ := br.Peek(len(dumpMagic)); IsDump(prefix) {
+		return readVerifyingKeyDump(br, size, vk)
+	}
+	return vk.ReadFrom(br)
+}
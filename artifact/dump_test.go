@@ -0,0 +1,184 @@
+package artifact_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	groth16_bls12381 "github.com/consensys/gnark/backend/groth16/bls12-381"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/zilong-dai/groth16-worker/artifact"
+)
+
+type cubicCircuit struct {
+	X frontend.Variable `gnark:"x"`
+	Y frontend.Variable `gnark:",public"`
+}
+
+func (circuit *cubicCircuit) Define(api frontend.API) error {
+	x3 := api.Mul(circuit.X, circuit.X, circuit.X)
+	api.AssertIsEqual(circuit.Y, api.Add(x3, circuit.X, 5))
+	return nil
+}
+
+func setupCubic(t *testing.T) (groth16.ProvingKey, groth16.VerifyingKey) {
+	t.Helper()
+
+	ccs, err := frontend.Compile(ecc.BLS12_381.ScalarField(), r1cs.NewBuilder, &cubicCircuit{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk, vk, err := groth16.Setup(ccs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pk, vk
+}
+
+func raw(t *testing.T, key interface {
+	WriteRawTo(io.Writer) (int64, error)
+}) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if _, err := key.WriteRawTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func dumpKeys(t *testing.T) (pkDump []byte, vkDump []byte) {
+	t.Helper()
+
+	pk, vk := setupCubic(t)
+
+	var pkBuf, vkBuf bytes.Buffer
+	if _, err := artifact.WriteProvingKeyDump(&pkBuf, pk); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := artifact.WriteVerifyingKeyDump(&vkBuf, vk); err != nil {
+		t.Fatal(err)
+	}
+
+	return pkBuf.Bytes(), vkBuf.Bytes()
+}
+
+func TestDumpRoundTrip(t *testing.T) {
+	pk, vk := setupCubic(t)
+
+	var pkDump, vkDump bytes.Buffer
+	if _, err := artifact.WriteProvingKeyDump(&pkDump, pk); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := artifact.WriteVerifyingKeyDump(&vkDump, vk); err != nil {
+		t.Fatal(err)
+	}
+	if kind := artifact.DumpKind(pkDump.Bytes()); kind != artifact.DumpProvingKey {
+		t.Fatalf("expected a proving key dump, got kind %d", kind)
+	}
+	if kind := artifact.DumpKind(vkDump.Bytes()); kind != artifact.DumpVerifyingKey {
+		t.Fatalf("expected a verifying key dump, got kind %d", kind)
+	}
+
+	loadedPk := groth16.NewProvingKey(ecc.BLS12_381)
+	if n, err := artifact.ReadProvingKey(bytes.NewReader(pkDump.Bytes()), loadedPk); err != nil || n != int64(pkDump.Len()) {
+		t.Fatalf("failed to read proving key dump: read %d of %d bytes, %v", n, pkDump.Len(), err)
+	}
+	if !bytes.Equal(raw(t, pk), raw(t, loadedPk)) {
+		t.Fatal("proving key changed through its dump")
+	}
+
+	loadedVk := groth16.NewVerifyingKey(ecc.BLS12_381)
+	if n, err := artifact.ReadVerifyingKey(bytes.NewReader(vkDump.Bytes()), loadedVk); err != nil || n != int64(vkDump.Len()) {
+		t.Fatalf("failed to read verifying key dump: read %d of %d bytes, %v", n, vkDump.Len(), err)
+	}
+	if !bytes.Equal(raw(t, vk), raw(t, loadedVk)) {
+		t.Fatal("verifying key changed through its dump")
+	}
+
+	// keys that aren't dumps still read
+	loadedVk = groth16.NewVerifyingKey(ecc.BLS12_381)
+	if _, err := artifact.ReadVerifyingKey(bytes.NewReader(raw(t, vk)), loadedVk); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDumpLengthBeyondInput(t *testing.T) {
+	_, vkDump := dumpKeys(t)
+
+	// the first slice of a verifying key dump holds its fixed G1 points
+	corrupted := bytes.Clone(vkDump)
+	binary.BigEndian.PutUint64(corrupted[artifact.DumpHeaderSize:], 1<<50)
+
+	vk := groth16.NewVerifyingKey(ecc.BLS12_381)
+	if _, err := artifact.ReadVerifyingKey(bytes.NewReader(corrupted), vk); !errors.Is(err, artifact.ErrInvalidDump) {
+		t.Fatalf("expected invalid dump error, got %v", err)
+	}
+
+	// without a known size, the slice fails at the end of the input
+	vk = groth16.NewVerifyingKey(ecc.BLS12_381)
+	if _, err := artifact.ReadVerifyingKey(io.MultiReader(bytes.NewReader(corrupted)), vk); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("expected unexpected EOF, got %v", err)
+	}
+}
+
+func TestDumpInvalidBool(t *testing.T) {
+	pk, _ := setupCubic(t)
+	key := pk.(*groth16_bls12381.ProvingKey)
+	if len(key.InfinityA) == 0 || len(key.CommitmentKeys) != 0 {
+		t.Fatalf("unexpected proving key layout")
+	}
+
+	var dump bytes.Buffer
+	if _, err := artifact.WriteProvingKeyDump(&dump, pk); err != nil {
+		t.Fatal(err)
+	}
+
+	// InfinityA is followed by InfinityB and its length, NbInfinityA,
+	// NbInfinityB and the number of commitment keys
+	corrupted := dump.Bytes()
+	end := len(corrupted) - 3*8 - len(key.InfinityB) - 8
+	corrupted[end-1] = 2
+
+	loaded := groth16.NewProvingKey(ecc.BLS12_381)
+	if _, err := artifact.ReadProvingKey(bytes.NewReader(corrupted), loaded); !errors.Is(err, artifact.ErrInvalidDump) {
+		t.Fatalf("expected invalid dump error, got %v", err)
+	}
+}
+
+func TestDumpTruncated(t *testing.T) {
+	pkDump, vkDump := dumpKeys(t)
+
+	for _, size := range []int{0, artifact.DumpHeaderSize - 1, artifact.DumpHeaderSize + 4, len(pkDump) / 2, len(pkDump) - 1} {
+		pk := groth16.NewProvingKey(ecc.BLS12_381)
+		if _, err := artifact.ReadProvingKey(bytes.NewReader(pkDump[:size]), pk); err == nil {
+			t.Fatalf("expected a proving key dump truncated to %d bytes to fail", size)
+		}
+	}
+	for _, size := range []int{artifact.DumpHeaderSize + 4, len(vkDump) / 2, len(vkDump) - 1} {
+		vk := groth16.NewVerifyingKey(ecc.BLS12_381)
+		if _, err := artifact.ReadVerifyingKey(io.MultiReader(bytes.NewReader(vkDump[:size])), vk); err == nil {
+			t.Fatalf("expected a verifying key dump truncated to %d bytes to fail", size)
+		}
+	}
+}
+
+func TestDumpWrongKind(t *testing.T) {
+	pkDump, vkDump := dumpKeys(t)
+
+	vk := groth16.NewVerifyingKey(ecc.BLS12_381)
+	if _, err := artifact.ReadVerifyingKey(bytes.NewReader(pkDump), vk); !errors.Is(err, artifact.ErrInvalidDump) {
+		t.Fatalf("expected invalid dump error, got %v", err)
+	}
+
+	vk = groth16.NewVerifyingKey(ecc.BN254)
+	if _, err := artifact.ReadVerifyingKey(bytes.NewReader(vkDump), vk); !errors.Is(err, artifact.ErrInvalidDump) {
+		t.Fatalf("expected invalid dump error, got %v", err)
+	}
+}
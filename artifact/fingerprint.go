@@ -0,0 +1,53 @@
+package artifact
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// FingerprintSuffix is appended to the path of a circuit or key file to get
+// the file holding the fingerprint of the plonky2 circuit it was set up for.
+// Keeping it next to the artifact leaves the gnark encoding untouched.
+const FingerprintSuffix = ".fingerprint"
+
+// WriteFingerprint stores fingerprint next to an artifact. An empty
+// fingerprint removes the one stored for a previous artifact at the same
+// path, so it isn't taken for the new one's.
+func WriteFingerprint(artifactPath string, fingerprint string) error {
+	if fingerprint == "" {
+		if err := os.Remove(artifactPath + FingerprintSuffix); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove fingerprint: %w", err)
+		}
+		return nil
+	}
+
+	if err := WriteFileAtomic(artifactPath+FingerprintSuffix, fingerprintSidecar(artifactPath, fingerprint).data); err != nil {
+		return fmt.Errorf("failed to write fingerprint: %w", err)
+	}
+
+	return nil
+}
+
+// fingerprintSidecar is the fingerprint file of an artifact, to be removed if
+// fingerprint is empty.
+func fingerprintSidecar(artifactPath string, fingerprint string) sidecar {
+	if fingerprint == "" {
+		return sidecar{path: artifactPath + FingerprintSuffix}
+	}
+	return sidecar{path: artifactPath + FingerprintSuffix, data: []byte(fingerprint + "\n")}
+}
+
+// ReadFingerprint returns the fingerprint stored next to an artifact, or an
+// empty string if there is none.
+func ReadFingerprint(artifactPath string) (string, error) {
+	data, err := os.ReadFile(artifactPath + FingerprintSuffix)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read fingerprint: %w", err)
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
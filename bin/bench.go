@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/zilong-dai/groth16-worker/verifier"
+	"github.com/zilong-dai/groth16-worker/worker"
+)
+
+type benchReport struct {
+	Machine     machineInfo    `json:"machine"`
+	Started     time.Time      `json:"started"`
+	Curve       string         `json:"curve"`
+	Iterations  int            `json:"iterations"`
+	Constraints int            `json:"constraints"`
+	Runs        []benchRun     `json:"runs"`
+	Summary     []benchSummary `json:"summary"`
+}
+
+type benchRun struct {
+	Iteration int `json:"iteration"`
+	worker.PhaseStats
+}
+
+// benchSummary aggregates the wall time of the runs of a phase, in
+// nanoseconds.
+type benchSummary struct {
+	Phase  worker.Phase  `json:"phase"`
+	Label  string        `json:"label,omitempty"`
+	Runs   int           `json:"runs"`
+	Min    time.Duration `json:"min_wall_time"`
+	Median time.Duration `json:"median_wall_time"`
+	Max    time.Duration `json:"max_wall_time"`
+}
+
+func runBench(args []string) error {
+	flags := newFlagSet("bench")
+	addConfigFlag(flags)
+	dir := flags.String("dir", ".", "directory with the plonky2 circuit data and proof")
+	curve := addCurveFlag(flags)
+	iterations := flags.Int("n", 1, "number of iterations")
+	keyFormats := flags.String("key-formats", strings.Join([]string{worker.FormatCompressed, worker.FormatRaw, worker.FormatDump}, ","), "comma separated proving key formats to time the read of")
+	workDir := flags.String("work", "", "directory for the keys written during the run, a temporary directory by default")
+	outPath := flags.String("out", "", "write the json report to this file instead of stdout")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if *iterations < 1 {
+		return usagef("-n must be at least 1")
+	}
+	formats := strings.Split(*keyFormats, ",")
+	for _, format := range formats {
+		switch format {
+		case worker.FormatCompressed, worker.FormatRaw, worker.FormatDump:
+		default:
+			return usagef("unknown key format %q", format)
+		}
+	}
+
+	if *workDir == "" {
+		tmp, err := os.MkdirTemp("", "groth16-bench")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(tmp)
+		*workDir = tmp
+	}
+
+	// every iteration measures a real compile, so provers must neither load
+	// the circuit from the compile cache nor store it there
+	os.Setenv(worker.EnvCacheDir, "")
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	report := &benchReport{
+		Machine:    readMachineInfo(),
+		Started:    time.Now().UTC(),
+		Curve:      curve.id.String(),
+		Iterations: *iterations,
+	}
+	meter := &worker.Meter{Next: logObserver}
+	for i := 0; i < *iterations; i++ {
+		if err := benchIteration(ctx, report, meter, i, *dir, curve.id, *workDir, formats); err != nil {
+			return err
+		}
+	}
+	report.Summary = summarizeBench(report.Runs)
+
+	if *outPath == "" {
+		return writeBenchReport(os.Stdout, report)
+	}
+
+	file, err := os.Create(*outPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := writeBenchReport(file, report); err != nil {
+		return err
+	}
+
+	return file.Close()
+}
+
+func writeBenchReport(w io.Writer, report *benchReport) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+// benchIteration runs every phase once, with a fresh prover so nothing is
+// reused across iterations.
+func benchIteration(ctx context.Context, report *benchReport, meter *worker.Meter, iteration int, dir string, curveId ecc.ID, workDir string, formats []string) error {
+	record := func(label string) {
+		for _, stats := range meter.Take() {
+			if stats.Constraints > 0 {
+				report.Constraints = stats.Constraints
+			}
+			stats.Label = label
+			report.Runs = append(report.Runs, benchRun{Iteration: iteration, PhaseStats: stats})
+		}
+	}
+
+	prover, err := worker.NewGroth16Prover(dir, curveId)
+	if err != nil {
+		return err
+	}
+	prover.Observer = meter
+
+	if err := prover.SetupContext(ctx); err != nil {
+		return err
+	}
+	record("")
+
+	rawPath := filepath.Join(workDir, "proving.key."+worker.FormatRaw)
+	vkPath := filepath.Join(workDir, "verifying.key")
+	if err := prover.WriteProvingKey(rawPath); err != nil {
+		return err
+	}
+	if err := prover.WriteVerifyingKey(vkPath); err != nil {
+		return err
+	}
+	for _, format := range formats {
+		pkPath := filepath.Join(workDir, "proving.key."+format)
+		if format != worker.FormatRaw {
+			if _, err := worker.ConvertKey(rawPath, pkPath, format, curveId, false); err != nil {
+				return err
+			}
+		}
+		if err := prover.ReadProvingKeyContext(ctx, pkPath); err != nil {
+			return err
+		}
+		record(format)
+	}
+
+	if err := prover.GenerateWitnessContext(ctx); err != nil {
+		return err
+	}
+	if err := prover.ProveContext(ctx); err != nil {
+		return err
+	}
+	record("")
+
+	v, err := verifier.NewGroth16VerifierFromFile(vkPath, curveId)
+	if err != nil {
+		return err
+	}
+	v.Proof = prover.Proof
+	if v.PublicWitness, err = prover.Witness.Public(); err != nil {
+		return err
+	}
+	if err := meter.Measure(worker.PhaseVerify, v.Verify); err != nil {
+		return err
+	}
+	record("")
+
+	return nil
+}
+
+func summarizeBench(runs []benchRun) []benchSummary {
+	type key struct {
+		phase worker.Phase
+		label string
+	}
+
+	var keys []key
+	walls := make(map[key][]time.Duration)
+	for _, run := range runs {
+		k := key{run.Phase, run.Label}
+		if _, ok := walls[k]; !ok {
+			keys = append(keys, k)
+		}
+		walls[k] = append(walls[k], run.Wall)
+	}
+
+	summary := make([]benchSummary, 0, len(keys))
+	for _, k := range keys {
+		w := walls[k]
+		slices.Sort(w)
+		summary = append(summary, benchSummary{
+			Phase:  k.phase,
+			Label:  k.label,
+			Runs:   len(w),
+			Min:    w[0],
+			Median: w[len(w)/2],
+			Max:    w[len(w)-1],
+		})
+	}
+
+	return summary
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"os/signal"
+	"reflect"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/consensys/gnark"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/zilong-dai/groth16-worker/artifact"
+	"github.com/zilong-dai/groth16-worker/config"
+	"github.com/zilong-dai/groth16-worker/verifier"
+	"github.com/zilong-dai/groth16-worker/worker"
+)
+
+// Exit codes, so scripts can tell a broken disk from a bad proof.
+const (
+	exitOK           = 0
+	exitFailure      = 1
+	exitUsage        = 2
+	exitIO           = 3
+	exitInvalidProof = 4
+	exitBadInput     = 5
+)
+
+// usageError is returned for missing or conflicting flags.
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+func usagef(format string, args ...any) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+func exitCode(err error) int {
+	var usage *usageError
+	var configErr *config.Error
+	var inputErr *worker.InputError
+	var pathErr *fs.PathError
+
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, flag.ErrHelp):
+		// the flag package already printed the usage
+		return exitOK
+	case errors.As(err, &usage), errors.As(err, &configErr):
+		return exitUsage
+	case errors.Is(err, verifier.ErrInvalidProof):
+		return exitInvalidProof
+	case errors.As(err, &inputErr),
+		// a truncated proof or key also wraps io.ErrUnexpectedEOF
+		errors.Is(err, verifier.ErrInvalidEncoding),
+		errors.Is(err, worker.ErrUnknownArtifact),
+		errors.Is(err, worker.ErrUnsupportedCurve),
+		errors.Is(err, verifier.ErrUnsupportedCurve),
+		errors.Is(err, artifact.ErrCurveMismatch),
+		errors.Is(err, worker.ErrFingerprintMismatch),
+		errors.Is(err, worker.ErrPublicWitnessMismatch):
+		return exitBadInput
+	case errors.As(err, &pathErr),
+		errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, artifact.ErrInvalidBundle),
+		errors.Is(err, artifact.ErrInvalidDump),
+		errors.Is(err, artifact.ErrChecksumMismatch),
+		errors.Is(err, artifact.ErrMissingSection):
+		return exitIO
+	default:
+		return exitFailure
+	}
+}
+
+// newFlagSet returns a flag set that reports errors instead of exiting.
+func newFlagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(os.Stderr)
+	return flags
+}
+
+// parseFlags parses the flags of a command that takes no arguments.
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	if err := parseFlagsWithArgs(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return usagef("unexpected arguments %v", flags.Args())
+	}
+	return nil
+}
+
+func parseFlagsWithArgs(flags *flag.FlagSet, args []string) error {
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return &usageError{msg: err.Error()}
+	}
+
+	if configPath := flags.Lookup("config"); configPath != nil {
+		return applyConfig(flags, configPath.Value.String())
+	}
+	return nil
+}
+
+// configKeys maps flags to the config keys that fill them in.
+var configKeys = map[string]string{
+	"dir":            "dir",
+	"curve":          "curve",
+	"key-format":     "key_format",
+	"circuit":        "artifacts.circuit",
+	"pk":             "artifacts.proving_key",
+	"vk":             "artifacts.verifying_key",
+	"bundle":         "artifacts.bundle",
+	"proof":          "output.proof",
+	"public-inputs":  "output.public_inputs",
+	"proof-encoding": "output.proof_encoding",
+	"format":         "output.format",
+
+	"max-concurrent-proofs": "prover.max_concurrent_proofs",
+	"cpus-per-proof":        "prover.cpus_per_proof",
+	"addr":                  "server.addr",
+	"queue-size":            "server.queue_size",
+	"grpc-addr":             "server.grpc_addr",
+	"store":                 "server.store",
+	"result-ttl":            "server.result_ttl",
+	"max-finished-jobs":     "server.max_finished_jobs",
+}
+
+// exclusiveFlags lists, per command, groups of flags that can't be combined.
+// A config setting several groups only fills in the first one, and none if
+// a flag of another group is given on the command line.
+var exclusiveFlags = map[string][][]string{
+	"prove":        {{"circuit", "pk"}, {"bundle"}},
+	"serve":        {{"circuit", "pk"}, {"bundle"}},
+	"serve-verify": {{"vk"}, {"bundle"}},
+	"verify":       {{"vk"}, {"bundle"}},
+}
+
+// addConfigFlag registers -config. parseFlags then fills in every flag that
+// isn't given on the command line from the config file and the environment.
+func addConfigFlag(flags *flag.FlagSet) {
+	flags.String("config", os.Getenv(config.EnvConfig), "yaml config filling in the flags that aren't set, $"+config.EnvConfig+" by default")
+}
+
+func applyConfig(flags *flag.FlagSet, path string) error {
+	cfg, err := config.Load(path)
+	if err != nil {
+		return err
+	}
+
+	given := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+
+	// the group given on the command line wins, then the first one the
+	// config sets
+	groups := exclusiveFlags[flags.Name()]
+	chosen := slices.IndexFunc(groups, func(group []string) bool {
+		return slices.ContainsFunc(group, func(name string) bool { return given[name] })
+	})
+	if chosen == -1 {
+		chosen = slices.IndexFunc(groups, func(group []string) bool {
+			return slices.ContainsFunc(group, func(name string) bool { return cfg.IsSet(configKeys[name]) })
+		})
+	}
+	skip := make(map[string]bool)
+	for i, group := range groups {
+		for _, name := range group {
+			skip[name] = i != chosen
+		}
+	}
+
+	for name, key := range configKeys {
+		if given[name] || skip[name] || flags.Lookup(name) == nil || !cfg.IsSet(key) {
+			continue
+		}
+		if err := flags.Set(name, cfg.Get(key)); err != nil {
+			return &config.Error{Source: cfg.Source(key), Key: key, Err: err}
+		}
+	}
+
+	return nil
+}
+
+// curveFlag parses curve names as printed by ecc.ID, with either - or _.
+type curveFlag struct {
+	id ecc.ID
+}
+
+func (c *curveFlag) String() string {
+	return c.id.String()
+}
+
+func (c *curveFlag) Set(s string) error {
+	name := strings.ReplaceAll(strings.ToLower(s), "-", "_")
+	for _, id := range gnark.Curves() {
+		if id.String() == name {
+			c.id = id
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown curve %q", s)
+}
+
+func addCurveFlag(flags *flag.FlagSet) *curveFlag {
+	curve := &curveFlag{id: ecc.BLS12_381}
+	flags.Var(curve, "curve", "outer curve of the groth16 proof")
+	return curve
+}
+
+type formatFlag string
+
+const (
+	formatText = "text"
+	formatJSON = "json"
+)
+
+func (f *formatFlag) String() string {
+	return string(*f)
+}
+
+func (f *formatFlag) Set(s string) error {
+	switch s {
+	case formatText, formatJSON:
+		*f = formatFlag(s)
+		return nil
+	default:
+		return fmt.Errorf("unknown format %q, expected %s or %s", s, formatText, formatJSON)
+	}
+}
+
+func addFormatFlag(flags *flag.FlagSet) *formatFlag {
+	format := formatFlag(formatText)
+	flags.Var(&format, "format", "output format, text or json")
+	return &format
+}
+
+// stringsFlag collects the values of a repeated flag.
+type stringsFlag []string
+
+func (s *stringsFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringsFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// writeResult prints a command result. Results are structs; the text format
+// prints one "name: value" line per non-zero field, named after its json tag,
+// and one line per element of slices.
+func writeResult(w io.Writer, format *formatFlag, result any) error {
+	if *format == formatJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(result)
+	}
+
+	v := reflect.Indirect(reflect.ValueOf(result))
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := v.Field(i)
+		if field.IsZero() && field.Kind() != reflect.Bool {
+			continue
+		}
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		values := []reflect.Value{field}
+		if field.Kind() == reflect.Slice {
+			values = values[:0]
+			for j := 0; j < field.Len(); j++ {
+				values = append(values, field.Index(j))
+			}
+		}
+		for _, value := range values {
+			if _, err := fmt.Fprintf(w, "%s: %v\n", name, value.Interface()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// logObserver logs phase events to stderr.
+var logObserver = worker.ObserverFunc(func(e worker.Event) {
+	phase := string(e.Phase)
+	if e.Estimated {
+		phase += " (estimated)"
+	}
+
+	switch {
+	case e.Kind == worker.PhaseStarted:
+		log.Printf("%s started", phase)
+	case e.Err != nil:
+		log.Printf("%s failed after %s: %v", phase, e.Duration, e.Err)
+	case e.Constraints > 0:
+		log.Printf("%s %s after %s, %d constraints", phase, e.Kind, e.Duration, e.Constraints)
+	case e.BytesRead > 0:
+		log.Printf("%s %s after %s, %d bytes read", phase, e.Kind, e.Duration, e.BytesRead)
+	default:
+		log.Printf("%s %s after %s", phase, e.Kind, e.Duration)
+	}
+})
+
+// signalContext is cancelled on SIGINT and SIGTERM, so key reads and
+// long phases return instead of running to the end.
+func signalContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
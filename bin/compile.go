@@ -0,0 +1,51 @@
+package main
+
+import (
+	"os"
+
+	"github.com/zilong-dai/groth16-worker/worker"
+)
+
+type compileResult struct {
+	Curve       string `json:"curve"`
+	Constraints int    `json:"constraints"`
+	Circuit     string `json:"circuit"`
+}
+
+func runCompile(args []string) error {
+	flags := newFlagSet("compile")
+	addConfigFlag(flags)
+	dir := flags.String("dir", ".", "directory with the plonky2 circuit data")
+	curve := addCurveFlag(flags)
+	circuitPath := flags.String("circuit", "", "write the r1cs to this file")
+	format := addFormatFlag(flags)
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if *circuitPath == "" {
+		return usagef("-circuit is required")
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	setupworker, err := worker.NewGroth16Worker(*dir, curve.id)
+	if err != nil {
+		return err
+	}
+	setupworker.Observer = logObserver
+
+	if err := setupworker.CompileContext(ctx); err != nil {
+		return err
+	}
+	if err := setupworker.WriteCircuit(*circuitPath); err != nil {
+		return err
+	}
+
+	return writeResult(os.Stdout, format, compileResult{
+		Curve:       curve.id.String(),
+		Constraints: setupworker.Constraints(),
+		Circuit:     *circuitPath,
+	})
+}
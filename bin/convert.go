@@ -0,0 +1,36 @@
+package main
+
+import (
+	"os"
+
+	"github.com/zilong-dai/groth16-worker/worker"
+)
+
+func runConvertKey(args []string) error {
+	flags := newFlagSet("convert-key")
+	curve := addCurveFlag(flags)
+	inPath := flags.String("in", "", "proving or verifying key to read, in any format")
+	outPath := flags.String("out", "", "file to write the converted key to")
+	to := flags.String("to", worker.FormatCompressed, "output format: compressed, raw, or dump (fast to load, unchecked, trusted storage only)")
+	verify := flags.Bool("verify", false, "read the output back, check it holds the same key and measure the load time")
+	format := addFormatFlag(flags)
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if *inPath == "" || *outPath == "" {
+		return usagef("-in and -out are required")
+	}
+	switch *to {
+	case worker.FormatCompressed, worker.FormatRaw, worker.FormatDump:
+	default:
+		return usagef("unknown key format %q, expected %s, %s or %s", *to, worker.FormatCompressed, worker.FormatRaw, worker.FormatDump)
+	}
+
+	conversion, err := worker.ConvertKey(*inPath, *outPath, *to, curve.id, *verify)
+	if err != nil {
+		return err
+	}
+
+	return writeResult(os.Stdout, format, conversion)
+}
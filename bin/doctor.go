@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+
+	"github.com/zilong-dai/groth16-worker/worker"
+)
+
+func runDoctor(args []string) error {
+	flags := newFlagSet("doctor")
+	addConfigFlag(flags)
+	phase := flags.String("for", string(worker.PhaseProve), "run the checks for a setup or a prove")
+	dir := flags.String("dir", "", "directory with the plonky2 circuit data and proof")
+	curve := addCurveFlag(flags)
+	circuitPath := flags.String("circuit", "", "r1cs the run will read")
+	pkPath := flags.String("pk", "", "proving key the run will read")
+	vkPath := flags.String("vk", "", "verifying key the run will read")
+	bundlePath := flags.String("bundle", "", "bundle the run will read")
+	var outputs stringsFlag
+	flags.Var(&outputs, "output", "file the run will write, may be repeated")
+	constraints := flags.Int("constraints", 0, "expected constraint count, when no circuit, proving key or bundle is given")
+	format := addFormatFlag(flags)
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	switch worker.Phase(*phase) {
+	case worker.PhaseSetup, worker.PhaseProve:
+	default:
+		return usagef("-for must be %s or %s", worker.PhaseSetup, worker.PhaseProve)
+	}
+
+	report := worker.Preflight(worker.PreflightOptions{
+		Phase:        worker.Phase(*phase),
+		Curve:        curve.id,
+		Dir:          *dir,
+		Circuit:      *circuitPath,
+		ProvingKey:   *pkPath,
+		VerifyingKey: *vkPath,
+		Bundle:       *bundlePath,
+		Outputs:      outputs,
+		Constraints:  *constraints,
+	})
+
+	if err := writeResult(os.Stdout, format, report); err != nil {
+		return err
+	}
+
+	return report.Err()
+}
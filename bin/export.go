@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/zilong-dai/groth16-worker/artifact"
+)
+
+type exportResult struct {
+	Bundle       string `json:"bundle"`
+	Curve        string `json:"curve"`
+	Fingerprint  string `json:"fingerprint,omitempty"`
+	Circuit      string `json:"circuit,omitempty"`
+	ProvingKey   string `json:"proving_key,omitempty"`
+	VerifyingKey string `json:"verifying_key,omitempty"`
+}
+
+func runExport(args []string) error {
+	flags := newFlagSet("export")
+	addConfigFlag(flags)
+	curve := addCurveFlag(flags)
+	bundlePath := flags.String("bundle", "", "bundle to read")
+	circuitPath := flags.String("circuit", "", "write the r1cs to this file")
+	pkPath := flags.String("pk", "", "write the proving key to this file")
+	vkPath := flags.String("vk", "", "write the verifying key to this file")
+	format := addFormatFlag(flags)
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if *bundlePath == "" {
+		return usagef("-bundle is required")
+	}
+	if *circuitPath == "" && *pkPath == "" && *vkPath == "" {
+		return usagef("nothing to export, set at least one of -circuit, -pk or -vk")
+	}
+
+	bundle, err := artifact.OpenBundle(*bundlePath)
+	if err != nil {
+		return err
+	}
+	defer bundle.Close()
+
+	if err := bundle.Header.Check(curve.id); err != nil {
+		return err
+	}
+
+	// sections hold the same encoding as the standalone files, so they are
+	// copied as they are and only checked against their checksum
+	outputs := []struct{ section, path string }{
+		{artifact.SectionCircuit, *circuitPath},
+		{artifact.SectionProvingKey, *pkPath},
+		{artifact.SectionVerifyingKey, *vkPath},
+	}
+	for _, output := range outputs {
+		if output.path == "" {
+			continue
+		}
+		if err := exportSection(bundle, output.section, output.path); err != nil {
+			return err
+		}
+	}
+
+	return writeResult(os.Stdout, format, exportResult{
+		Bundle:       *bundlePath,
+		Curve:        bundle.Header.Curve,
+		Fingerprint:  bundle.Header.Fingerprint,
+		Circuit:      *circuitPath,
+		ProvingKey:   *pkPath,
+		VerifyingKey: *vkPath,
+	})
+}
+
+func exportSection(bundle *artifact.Bundle, section string, path string) error {
+	file, err := artifact.CreateAtomic(path)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer file.Close()
+
+	if err := bundle.ReadSection(section, file); err != nil {
+		return err
+	}
+	if err := file.CommitWithFingerprint(bundle.Header.Fingerprint); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/zilong-dai/groth16-worker/worker"
+)
+
+func runInspect(args []string) error {
+	flags := newFlagSet("inspect")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: groth16-worker inspect [flags] file...\n")
+		flags.PrintDefaults()
+	}
+	curve := addCurveFlag(flags)
+	format := addFormatFlag(flags)
+	if err := parseFlagsWithArgs(flags, args); err != nil {
+		return err
+	}
+
+	if flags.NArg() == 0 {
+		return usagef("no files to inspect")
+	}
+
+	for i, path := range flags.Args() {
+		info, err := worker.InspectArtifact(path, curve.id)
+		if err != nil {
+			return err
+		}
+
+		if i > 0 && *format == formatText {
+			fmt.Println()
+		}
+		if err := writeResult(os.Stdout, format, info); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
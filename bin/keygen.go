@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+
+	"github.com/zilong-dai/groth16-worker/worker"
+)
+
+func runKeyGen(args []string) error {
+	flags := newFlagSet("keygen")
+	addConfigFlag(flags)
+	dir := flags.String("dir", ".", "directory with the plonky2 circuit data, if any, to check the circuit was compiled from it")
+	curve := addCurveFlag(flags)
+	circuitPath := flags.String("circuit", "", "read the r1cs written by compile from this file")
+	pkPath := flags.String("pk", "", "write the proving key to this file")
+	vkPath := flags.String("vk", "", "write the verifying key to this file")
+	bundlePath := flags.String("bundle", "", "write the r1cs and both keys to this bundle")
+	keyFormat := flags.String("key-format", worker.FormatRaw, "encoding of the proving key: compressed, raw or dump")
+	format := addFormatFlag(flags)
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if *circuitPath == "" {
+		return usagef("-circuit is required")
+	}
+	if *pkPath == "" && *vkPath == "" && *bundlePath == "" {
+		return usagef("nothing to write, set at least one of -pk, -vk or -bundle")
+	}
+	if err := checkKeyFormat(*keyFormat); err != nil {
+		return err
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	setupworker, err := worker.NewGroth16Worker(*dir, curve.id)
+	if err != nil {
+		return err
+	}
+	setupworker.Observer = logObserver
+
+	if err := setupworker.ReadCircuitContext(ctx, *circuitPath); err != nil {
+		return err
+	}
+	if err := setupworker.KeyGenContext(ctx); err != nil {
+		return err
+	}
+	if err := writeKeys(setupworker, *pkPath, *vkPath, *bundlePath, *keyFormat); err != nil {
+		return err
+	}
+
+	return writeResult(os.Stdout, format, setupResult{
+		Curve:        curve.id.String(),
+		Constraints:  setupworker.Constraints(),
+		Circuit:      *circuitPath,
+		ProvingKey:   *pkPath,
+		VerifyingKey: *vkPath,
+		Bundle:       *bundlePath,
+	})
+}
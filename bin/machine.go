@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+
+	"github.com/zilong-dai/groth16-worker/artifact"
+)
+
+// machineInfo tags reports with what they were measured on. Fields read from
+// /proc are left empty on other platforms.
+type machineInfo struct {
+	Hostname     string `json:"hostname,omitempty"`
+	OS           string `json:"os"`
+	Arch         string `json:"arch"`
+	CPU          string `json:"cpu,omitempty"`
+	NumCPU       int    `json:"num_cpu"`
+	GOMAXPROCS   int    `json:"gomaxprocs"`
+	Memory       int64  `json:"memory,omitempty"`
+	GoVersion    string `json:"go_version"`
+	GnarkVersion string `json:"gnark_version"`
+	Version      string `json:"version,omitempty"`
+}
+
+func readMachineInfo() machineInfo {
+	info := machineInfo{
+		OS:           runtime.GOOS,
+		Arch:         runtime.GOARCH,
+		CPU:          procField("/proc/cpuinfo", "model name"),
+		NumCPU:       runtime.NumCPU(),
+		GOMAXPROCS:   runtime.GOMAXPROCS(0),
+		Memory:       procMemory("MemTotal"),
+		GoVersion:    runtime.Version(),
+		GnarkVersion: artifact.GnarkVersion(),
+	}
+	info.Hostname, _ = os.Hostname()
+	if build, ok := debug.ReadBuildInfo(); ok {
+		info.Version = build.Main.Version
+	}
+
+	return info
+}
+
+// procField returns the value of the first "key: value" line of a /proc file.
+func procField(path, key string) string {
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		name, value, ok := strings.Cut(scanner.Text(), ":")
+		if ok && strings.TrimSpace(name) == key {
+			return strings.TrimSpace(value)
+		}
+	}
+
+	return ""
+}
+
+// procMemory returns a field of /proc/meminfo in bytes, or 0.
+func procMemory(key string) int64 {
+	value, _ := strings.CutSuffix(procField("/proc/meminfo", key), " kB")
+	kb, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return kb << 10
+}
@@ -1,100 +1,85 @@
+// Command groth16-worker sets up, proves and verifies groth16 wrappers of
+// plonky2 proofs.
+//
+// Usage:
+//
+//	groth16-worker <command> [flags]
+//
+// Run a command with -h to list its flags.
 package main
 
 import (
 	"fmt"
-	"log"
-	"time"
-
-	"github.com/consensys/gnark-crypto/ecc"
-	"github.com/zilong-dai/groth16-worker/worker"
+	"io"
+	"os"
+	"sort"
 )
 
-func main() {
-	// reference: https://github.com/cf/gnark-plonky2-verifier/blob/feat/poseidon-bls12-381/benchmark.go
-
-	workerpath := "./testdata/groth16"
-	proverPath := "./testdata/groth16"
-	verifierPath := "./testdata/groth16"
-	pkpath := "./testdata/groth16/proving.key2"
-	vkpath := "./testdata/groth16/verifying.key2"
-	proofpath := "./testdata/groth16/proof.proof2"
-	publicinputpath := "./testdata/groth16/witness2"
-	circuitpath := "./testdata/groth16/circuit2"
-
-	curveId := ecc.BLS12_381
-
-	{
-		// setup, save pk, vk and circuit
-		setupworker, err := worker.NewGroth16Worker(workerpath, curveId)
-		if err != nil {
-			log.Fatalf("error creating setup worker: %v", err)
-		}
-
-		// about 15mins to setup
-		fmt.Println("setup start", time.Now())
-		if err := setupworker.Setup(); err != nil {
-			log.Fatalf("error setting up worker: %v", err)
-		}
+type command struct {
+	usage string
+	run   func(args []string) error
+}
 
-		if err := setupworker.WriteProvingKey(pkpath); err != nil {
-			log.Fatalf("error writing proving key: %v", err)
-		}
-		if err := setupworker.WriteVerifyingKey(vkpath); err != nil {
-			log.Fatalf("error writing verifying key: %v", err)
-		}
-		if err := setupworker.WriteCircuit(circuitpath); err != nil {
-			log.Fatalf("error writing circuit: %v", err)
-		}
-	}
+var commands = map[string]command{
+	"setup":        {"compile the circuit and generate the proving and verifying keys, compile then keygen", runSetup},
+	"compile":      {"compile the circuit and write the r1cs", runCompile},
+	"keygen":       {"generate the proving and verifying keys of a compiled r1cs", runKeyGen},
+	"prove":        {"prove a plonky2 proof with a proving key", runProve},
+	"witness":      {"generate the witness of a plonky2 proof, to prove it elsewhere", runWitness},
+	"verify":       {"verify a groth16 proof with a verifying key", runVerify},
+	"serve":        {"prove plonky2 proofs over http with the proving key kept loaded", runServe},
+	"serve-verify": {"verify groth16 proofs over http against pinned verifying keys, without a proving key", runServeVerify},
+	"export":       {"extract the circuit and keys from a bundle", runExport},
+	"inspect":      {"describe circuits, keys, proofs, witnesses and bundles", runInspect},
+	"bench":        {"time and measure every phase on the plonky2 proof in a directory", runBench},
+	"doctor":       {"check inputs, artifacts, memory, disk and outputs before a setup or prove", runDoctor},
+	"convert-key":  {"re-encode a proving or verifying key as compressed, raw or dump", runConvertKey},
+}
 
-	prover, err := worker.NewGroth16Prover(proverPath, curveId)
-	if err != nil {
-		log.Fatalf("error creating prover: %v", err)
-	}
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
 
-	// read pk, about 5mins for raw pk, 15mins for compressed pk
-	fmt.Println("Read proving key start", time.Now())
-	if err := prover.ReadProvingKey(pkpath); err != nil {
-		log.Fatalf("error reading proving key: %v", err)
+func run(args []string) int {
+	if len(args) == 0 {
+		usage(os.Stderr)
+		return exitUsage
 	}
-	fmt.Println("Read proving key end", time.Now())
 
-	// read circuit
-	if err := prover.ReadCircuit(circuitpath); err != nil {
-		log.Fatalf("error reading circuit: %v", err)
+	name := args[0]
+	if name == "help" || name == "-h" || name == "--help" {
+		usage(os.Stdout)
+		return exitOK
 	}
 
-	// generate witness
-	if err := prover.GenerateWitness(); err != nil {
-		log.Fatalf("error generating witness: %v", err)
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+		usage(os.Stderr)
+		return exitUsage
 	}
 
-	fmt.Println("prove start", time.Now())
-	if err := prover.Prove(); err != nil {
-		log.Fatalf("error running prover: %v", err)
+	if err := cmd.run(args[1:]); err != nil {
+		code := exitCode(err)
+		if code != exitOK {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+		}
+		return code
 	}
 
-	fmt.Println("prove end", time.Now())
-
-	prover.WriteProof(proofpath)
-	prover.WritePublicInputs(publicinputpath)
+	return exitOK
+}
 
-	verifier, err := worker.NewGroth16Verifier(verifierPath, curveId)
-	if err != nil {
-		log.Fatalf("error creating verifier: %v", err)
-	}
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "usage: groth16-worker <command> [flags]\n\ncommands:\n")
 
-	if err := verifier.ReadVerifyingKey(vkpath); err != nil {
-		log.Fatalf("error reading verifying key: %v", err)
-	}
-	if err := verifier.ReadProof(proofpath); err != nil {
-		log.Fatalf("error reading proof: %v", err)
-	}
-	if err := verifier.ReadPublicInputs(publicinputpath); err != nil {
-		log.Fatalf("error reading public inputs: %v", err)
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
 	}
+	sort.Strings(names)
 
-	if err := verifier.Verify(); err != nil {
-		log.Fatalf("error running verifier: %v", err)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-12s %s\n", name, commands[name].usage)
 	}
 }
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/zilong-dai/groth16-worker/artifact"
+)
+
+type cubicCircuit struct {
+	X frontend.Variable `gnark:"x"`
+	Y frontend.Variable `gnark:",public"`
+}
+
+func (c *cubicCircuit) Define(api frontend.API) error {
+	x3 := api.Mul(c.X, c.X, c.X)
+	api.AssertIsEqual(c.Y, api.Add(x3, c.X, 5))
+	return nil
+}
+
+// writeCubicProof writes a verifying key, proof and public witness of
+// cubicCircuit to dir, with y as the public input.
+func writeCubicProof(t *testing.T, dir string, y int) (vkPath, proofPath, publicInputsPath string) {
+	t.Helper()
+
+	ccs, err := frontend.Compile(ecc.BLS12_381.ScalarField(), r1cs.NewBuilder, &cubicCircuit{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk, vk, err := groth16.Setup(ccs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	witness, err := frontend.NewWitness(&cubicCircuit{X: 3, Y: 35}, ecc.BLS12_381.ScalarField())
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof, err := groth16.Prove(ccs, pk, witness)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	public, err := frontend.NewWitness(&cubicCircuit{Y: y}, ecc.BLS12_381.ScalarField(), frontend.PublicOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vkPath = filepath.Join(dir, "verifying.key")
+	proofPath = filepath.Join(dir, "proof")
+	publicInputsPath = filepath.Join(dir, "witness")
+
+	for path, from := range map[string]interface {
+		WriteTo(w io.Writer) (int64, error)
+	}{vkPath: vk, proofPath: proof, publicInputsPath: public} {
+		file, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := from.WriteTo(file); err != nil {
+			t.Fatal(err)
+		}
+		file.Close()
+	}
+
+	return vkPath, proofPath, publicInputsPath
+}
+
+func TestVerifyExitCodes(t *testing.T) {
+	dir := t.TempDir()
+	vkPath, proofPath, publicInputsPath := writeCubicProof(t, dir, 35)
+
+	if code := run([]string{"verify", "-vk", vkPath, "-proof", proofPath, "-public-inputs", publicInputsPath, "-format", "json"}); code != exitOK {
+		t.Fatalf("expected a valid proof, got exit code %d", code)
+	}
+
+	wrongDir := filepath.Join(dir, "wrong")
+	if err := os.Mkdir(wrongDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	vkPath, proofPath, publicInputsPath = writeCubicProof(t, wrongDir, 36)
+
+	if code := run([]string{"verify", "-vk", vkPath, "-proof", proofPath, "-public-inputs", publicInputsPath}); code != exitInvalidProof {
+		t.Fatalf("expected exit code %d for an invalid proof, got %d", exitInvalidProof, code)
+	}
+
+	if code := run([]string{"verify", "-vk", filepath.Join(dir, "missing"), "-proof", proofPath, "-public-inputs", publicInputsPath}); code != exitIO {
+		t.Fatalf("expected exit code %d for a missing key, got %d", exitIO, code)
+	}
+
+	garbage := filepath.Join(dir, "garbage")
+	if err := os.WriteFile(garbage, bytes.Repeat([]byte{0xff}, 256), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if code := run([]string{"verify", "-vk", vkPath, "-proof", garbage, "-public-inputs", publicInputsPath, "-format", "json"}); code != exitBadInput {
+		t.Fatalf("expected exit code %d for a proof that doesn't decode, got %d", exitBadInput, code)
+	}
+
+	proof, err := os.ReadFile(proofPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	truncated := filepath.Join(dir, "truncated")
+	if err := os.WriteFile(truncated, proof[:len(proof)/2], 0644); err != nil {
+		t.Fatal(err)
+	}
+	if code := run([]string{"verify", "-vk", vkPath, "-proof", truncated, "-public-inputs", publicInputsPath, "-format", "json"}); code != exitBadInput {
+		t.Fatalf("expected exit code %d for a truncated proof, got %d", exitBadInput, code)
+	}
+
+	if code := run([]string{"verify", "-proof", proofPath}); code != exitUsage {
+		t.Fatalf("expected exit code %d for missing flags, got %d", exitUsage, code)
+	}
+}
+
+func TestProveBadInputs(t *testing.T) {
+	if code := run([]string{"compile", "-curve", "bn254", "-dir", "../testdata", "-circuit", filepath.Join(t.TempDir(), "circuit")}); code != exitBadInput {
+		t.Fatalf("expected exit code %d for an unsupported curve, got %d", exitBadInput, code)
+	}
+}
+
+func TestInspect(t *testing.T) {
+	dir := t.TempDir()
+	vkPath, proofPath, publicInputsPath := writeCubicProof(t, dir, 35)
+
+	if code := run([]string{"inspect", vkPath, proofPath, publicInputsPath}); code != exitOK {
+		t.Fatalf("expected exit code %d, got %d", exitOK, code)
+	}
+
+	garbagePath := filepath.Join(dir, "garbage")
+	if err := os.WriteFile(garbagePath, []byte("not an artifact"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if code := run([]string{"inspect", "-format", "json", garbagePath}); code != exitBadInput {
+		t.Fatalf("expected exit code %d for an unknown artifact, got %d", exitBadInput, code)
+	}
+}
+
+func TestDoctor(t *testing.T) {
+	dir := t.TempDir()
+	vkPath, _, _ := writeCubicProof(t, dir, 35)
+
+	if code := run([]string{"doctor", "-dir", "../testdata", "-vk", vkPath, "-output", filepath.Join(dir, "proof")}); code != exitOK {
+		t.Fatalf("expected exit code %d, got %d", exitOK, code)
+	}
+	if code := run([]string{"doctor", "-format", "json", "-vk", filepath.Join(dir, "missing")}); code != exitFailure {
+		t.Fatalf("expected exit code %d for a missing key, got %d", exitFailure, code)
+	}
+	if code := run([]string{"doctor", "-for", "verify"}); code != exitUsage {
+		t.Fatalf("expected exit code %d for an unknown phase, got %d", exitUsage, code)
+	}
+}
+
+func TestConfig(t *testing.T) {
+	dir := t.TempDir()
+	writeCubicProof(t, dir, 35)
+
+	// written by writeCubicProof, relative to the config file
+	configPath := filepath.Join(dir, "worker.yaml")
+	content := "artifacts:\n  verifying_key: verifying.key\n  bundle: missing.bundle\noutput:\n  proof: proof\n  public_inputs: witness\n  format: json\n"
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if code := run([]string{"verify", "-config", configPath}); code != exitOK {
+		t.Fatalf("expected a valid proof, got exit code %d", code)
+	}
+
+	// the bundle given on the command line replaces the key of the config
+	if code := run([]string{"verify", "-config", configPath, "-bundle", filepath.Join(dir, "missing.bundle")}); code != exitIO {
+		t.Fatalf("expected exit code %d for a missing bundle, got %d", exitIO, code)
+	}
+
+	t.Setenv("GROTH16_WORKER_CURVE", "bn999")
+	if code := run([]string{"verify", "-config", configPath}); code != exitUsage {
+		t.Fatalf("expected exit code %d for a bad curve, got %d", exitUsage, code)
+	}
+}
+
+func TestKeyGen(t *testing.T) {
+	dir := t.TempDir()
+
+	ccs, err := frontend.Compile(ecc.BLS12_381.ScalarField(), r1cs.NewBuilder, &cubicCircuit{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	circuitPath := filepath.Join(dir, "circuit")
+	file, err := os.Create(circuitPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ccs.WriteTo(file); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	pkPath, vkPath := filepath.Join(dir, "proving.key"), filepath.Join(dir, "verifying.key")
+	if code := run([]string{"keygen", "-dir", dir, "-circuit", circuitPath, "-pk", pkPath, "-vk", vkPath}); code != exitOK {
+		t.Fatalf("expected exit code %d, got %d", exitOK, code)
+	}
+	info, err := os.Stat(pkPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every step is done, so resuming the setup must not touch the keys
+	if code := run([]string{"setup", "-resume", "-dir", dir, "-circuit", circuitPath, "-pk", pkPath, "-vk", vkPath}); code != exitOK {
+		t.Fatalf("expected exit code %d when resuming a finished setup, got %d", exitOK, code)
+	}
+	resumed, err := os.Stat(pkPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resumed.ModTime().Equal(info.ModTime()) {
+		t.Fatal("expected resuming a finished setup to keep the proving key")
+	}
+
+	if code := run([]string{"keygen", "-pk", pkPath}); code != exitUsage {
+		t.Fatalf("expected exit code %d without -circuit, got %d", exitUsage, code)
+	}
+}
+
+func TestWitness(t *testing.T) {
+	dir := t.TempDir()
+	witnessPath, publicInputsPath := filepath.Join(dir, "witness"), filepath.Join(dir, "public_inputs")
+
+	if code := run([]string{"witness", "-dir", "../testdata", "-witness", witnessPath, "-public-inputs", publicInputsPath}); code != exitOK {
+		t.Fatalf("expected exit code %d, got %d", exitOK, code)
+	}
+	for _, path := range []string{witnessPath, witnessPath + artifact.FingerprintSuffix, publicInputsPath} {
+		if _, err := os.Stat(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if code := run([]string{"witness", "-dir", "../testdata"}); code != exitUsage {
+		t.Fatalf("expected exit code %d without -witness, got %d", exitUsage, code)
+	}
+}
+
+func TestServeUsage(t *testing.T) {
+	if code := run([]string{"serve", "-dir", "../testdata"}); code != exitUsage {
+		t.Fatalf("expected exit code %d without a circuit and proving key, got %d", exitUsage, code)
+	}
+	if code := run([]string{"serve", "-bundle", "setup.bundle", "-pk", "proving.key"}); code != exitUsage {
+		t.Fatalf("expected exit code %d for -bundle with -pk, got %d", exitUsage, code)
+	}
+}
+
+func TestServeVerifyUsage(t *testing.T) {
+	if code := run([]string{"serve-verify"}); code != exitUsage {
+		t.Fatalf("expected exit code %d without keys, got %d", exitUsage, code)
+	}
+	if code := run([]string{"serve-verify", "-vk", "a=missing.key"}); code != exitIO {
+		t.Fatalf("expected exit code %d for a missing key, got %d", exitIO, code)
+	}
+}
+
+func TestExport(t *testing.T) {
+	dir := t.TempDir()
+	bundlePath := filepath.Join(dir, "setup.bundle")
+
+	var bundle bytes.Buffer
+	writer, err := artifact.NewBundleWriter(&bundle, artifact.BundleHeader{
+		Curve:        ecc.BLS12_381.String(),
+		Backend:      artifact.BackendGroth16,
+		GnarkVersion: artifact.GnarkVersion(),
+		Fingerprint:  "cubic",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Section(artifact.SectionVerifyingKey, bytes.NewBufferString("verifying key")); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(bundlePath, bundle.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	vkPath := filepath.Join(dir, "verifying.key")
+	if code := run([]string{"export", "-bundle", bundlePath, "-vk", vkPath}); code != exitOK {
+		t.Fatalf("expected exit code %d, got %d", exitOK, code)
+	}
+	if data, err := os.ReadFile(vkPath); err != nil || string(data) != "verifying key" {
+		t.Fatalf("unexpected verifying key %q: %v", data, err)
+	}
+	if fingerprint, err := artifact.ReadFingerprint(vkPath); err != nil || fingerprint != "cubic" {
+		t.Fatalf("unexpected fingerprint %q: %v", fingerprint, err)
+	}
+
+	// a corrupted section leaves the key exported before in place
+	data := bundle.Bytes()
+	data[bytes.Index(data, []byte("verifying key"))] ^= 0xff
+	if err := os.WriteFile(bundlePath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if code := run([]string{"export", "-bundle", bundlePath, "-vk", vkPath}); code == exitOK {
+		t.Fatal("expected a corrupted section to fail")
+	}
+	if data, err := os.ReadFile(vkPath); err != nil || string(data) != "verifying key" {
+		t.Fatalf("unexpected verifying key %q: %v", data, err)
+	}
+	if entries, err := os.ReadDir(dir); err != nil || len(entries) != 3 {
+		t.Fatalf("expected the bundle, key and fingerprint only, got %v: %v", entries, err)
+	}
+}
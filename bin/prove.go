@@ -0,0 +1,102 @@
+package main
+
+import (
+	"os"
+
+	"github.com/zilong-dai/groth16-worker/worker"
+)
+
+type proveResult struct {
+	Curve        string `json:"curve"`
+	Proof        string `json:"proof"`
+	PublicInputs string `json:"public_inputs"`
+}
+
+func runProve(args []string) error {
+	flags := newFlagSet("prove")
+	addConfigFlag(flags)
+	dir := flags.String("dir", ".", "directory with the plonky2 circuit data and proof")
+	curve := addCurveFlag(flags)
+	circuitPath := flags.String("circuit", "", "read the r1cs from this file")
+	pkPath := flags.String("pk", "", "read the proving key from this file")
+	bundlePath := flags.String("bundle", "", "read the r1cs and proving key from this bundle")
+	proofPath := flags.String("proof", "", "write the groth16 proof to this file")
+	publicInputsPath := flags.String("public-inputs", "", "write the public witness to this file")
+	witnessPath := flags.String("witness", "", "read the full witness written by the witness command instead of generating it")
+	expectedPublicInputsPath := flags.String("expect-public-inputs", "", "check the witness against the public witness written with it")
+	proofEncoding := flags.String("proof-encoding", worker.FormatCompressed, "encoding of the proof points: compressed or raw")
+	format := addFormatFlag(flags)
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if *bundlePath != "" {
+		if *circuitPath != "" || *pkPath != "" {
+			return usagef("-bundle can't be combined with -circuit or -pk")
+		}
+	} else if *circuitPath == "" || *pkPath == "" {
+		return usagef("set either -bundle or both -circuit and -pk")
+	}
+	if *proofPath == "" || *publicInputsPath == "" {
+		return usagef("-proof and -public-inputs are required")
+	}
+	if *proofEncoding != worker.FormatCompressed && *proofEncoding != worker.FormatRaw {
+		return usagef("unknown proof encoding %q, expected %s or %s", *proofEncoding, worker.FormatCompressed, worker.FormatRaw)
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	prover, err := worker.NewGroth16Prover(*dir, curve.id)
+	if err != nil {
+		return err
+	}
+	prover.Observer = logObserver
+
+	if *bundlePath != "" {
+		if err := prover.ReadBundle(*bundlePath); err != nil {
+			return err
+		}
+	} else {
+		if err := prover.ReadCircuitContext(ctx, *circuitPath); err != nil {
+			return err
+		}
+		if err := prover.ReadProvingKeyContext(ctx, *pkPath); err != nil {
+			return err
+		}
+	}
+
+	if *expectedPublicInputsPath != "" {
+		if err := prover.ReadPublicInputs(*expectedPublicInputsPath); err != nil {
+			return err
+		}
+	}
+	if *witnessPath != "" {
+		err = prover.ReadWitness(*witnessPath)
+	} else {
+		err = prover.GenerateWitnessContext(ctx)
+	}
+	if err != nil {
+		return err
+	}
+	if err := prover.ProveContext(ctx); err != nil {
+		return err
+	}
+
+	writeProof := prover.WriteProof
+	if *proofEncoding == worker.FormatRaw {
+		writeProof = prover.WriteRawProof
+	}
+	if err := writeProof(*proofPath); err != nil {
+		return err
+	}
+	if err := prover.WritePublicInputs(*publicInputsPath); err != nil {
+		return err
+	}
+
+	return writeResult(os.Stdout, format, proveResult{
+		Curve:        curve.id.String(),
+		Proof:        *proofPath,
+		PublicInputs: *publicInputsPath,
+	})
+}
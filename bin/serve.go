@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/zilong-dai/groth16-worker/rpc"
+	"github.com/zilong-dai/groth16-worker/server"
+	"github.com/zilong-dai/groth16-worker/verifier"
+	"github.com/zilong-dai/groth16-worker/worker"
+	"google.golang.org/grpc"
+)
+
+// shutdownTimeout is how long serve waits for open requests on shutdown.
+const shutdownTimeout = 10 * time.Second
+
+func runServe(args []string) error {
+	flags := newFlagSet("serve")
+	addConfigFlag(flags)
+	addr := flags.String("addr", "localhost:8080", "listen on this address")
+	dir := flags.String("dir", ".", "directory with the plonky2 common and verifier-only circuit data")
+	curve := addCurveFlag(flags)
+	circuitPath := flags.String("circuit", "", "read the r1cs from this file")
+	pkPath := flags.String("pk", "", "read the proving key from this file")
+	bundlePath := flags.String("bundle", "", "read the r1cs and proving key from this bundle")
+	maxConcurrentProofs := flags.Int("max-concurrent-proofs", 0, "number of proofs run at the same time, 0 for one")
+	cpusPerProof := flags.Int("cpus-per-proof", 0, "cpus a proof needs, caps concurrent proofs to the cpus available, 0 for no cap")
+	queueSize := flags.Int("queue-size", server.DefaultQueueSize, "number of jobs waiting for a prover before submissions are refused")
+	grpcAddr := flags.String("grpc-addr", "", "also serve the gRPC api on this address")
+	vkPath := flags.String("vk", "", "verifying key for gRPC Verify calls that don't send one, the bundle's by default")
+	storeDir := flags.String("store", "", "keep jobs in this directory so they survive restarts, in memory if empty")
+	resultTTL := flags.Duration("result-ttl", 0, "how long finished jobs are kept and returned for duplicate submissions, 0 is forever")
+	maxFinishedJobs := flags.Int("max-finished-jobs", server.DefaultMaxFinishedJobs, "number of finished jobs kept, the oldest are removed beyond it")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if *bundlePath != "" {
+		if *circuitPath != "" || *pkPath != "" {
+			return usagef("-bundle can't be combined with -circuit or -pk")
+		}
+	} else if *circuitPath == "" || *pkPath == "" {
+		return usagef("set either -bundle or both -circuit and -pk")
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	inputs, err := worker.NewPlonky2InputsFromDir(*dir)
+	if err != nil {
+		return err
+	}
+
+	prover, err := worker.NewGroth16Prover(*dir, curve.id)
+	if err != nil {
+		return err
+	}
+	prover.Observer = logObserver
+	limits := worker.ProverLimits{MaxConcurrentProofs: *maxConcurrentProofs, CPUsPerProof: *cpusPerProof}
+	prover.SetLimits(limits)
+
+	if *bundlePath != "" {
+		if err := prover.ReadBundle(*bundlePath); err != nil {
+			return err
+		}
+	} else {
+		if err := prover.ReadCircuitContext(ctx, *circuitPath); err != nil {
+			return err
+		}
+		if err := prover.ReadProvingKeyContext(ctx, *pkPath); err != nil {
+			return err
+		}
+	}
+	if err := prover.SetCircuitData(inputs); err != nil {
+		return err
+	}
+
+	var store *server.Store
+	if *storeDir != "" {
+		if store, err = server.OpenStore(*storeDir); err != nil {
+			return err
+		}
+		defer store.Close()
+	}
+
+	srv, err := server.New(prover, server.Options{
+		Curve:           curve.id,
+		Inputs:          inputs,
+		Workers:         limits.Slots(),
+		QueueSize:       *queueSize,
+		Store:           store,
+		ResultTTL:       *resultTTL,
+		MaxFinishedJobs: *maxFinishedJobs,
+	})
+	if err != nil {
+		return err
+	}
+	defer srv.Close()
+
+	if *grpcAddr != "" {
+		vk, err := readServeVerifyingKey(*vkPath, *bundlePath, curve.id)
+		if err != nil {
+			return err
+		}
+		grpcListener, err := net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			return err
+		}
+		grpcServer := grpc.NewServer(grpc.MaxRecvMsgSize(server.DefaultMaxBodySize))
+		rpc.NewService(srv, rpc.Options{Curve: curve.id, VerifyingKey: vk}).Register(grpcServer)
+		defer grpcServer.Stop()
+
+		fmt.Fprintf(os.Stderr, "serving gRPC on %s\n", grpcListener.Addr())
+		go grpcServer.Serve(grpcListener)
+	}
+
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "serving on %s\n", listener.Addr())
+
+	err = serveHTTP(ctx, listener, srv)
+	// a second signal kills the process while Close waits for running proofs
+	stop()
+	return err
+}
+
+// serveHTTP serves handler until ctx is done, then waits for open requests
+// for up to shutdownTimeout.
+func serveHTTP(ctx context.Context, listener net.Listener, handler http.Handler) error {
+	httpServer := &http.Server{Handler: handler}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+
+	if err := httpServer.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// readServeVerifyingKey reads the key of gRPC Verify calls from vkPath, or
+// else from the bundle. It is nil if there is neither.
+func readServeVerifyingKey(vkPath, bundlePath string, curve ecc.ID) (groth16.VerifyingKey, error) {
+	switch {
+	case vkPath != "":
+		groth16Verifier, err := verifier.NewGroth16VerifierFromFile(vkPath, curve)
+		if err != nil {
+			return nil, err
+		}
+		return groth16Verifier.Vk, nil
+	case bundlePath != "":
+		groth16Verifier, err := readBundleVerifier(bundlePath, curve)
+		if err != nil {
+			return nil, err
+		}
+		return groth16Verifier.Vk, nil
+	default:
+		return nil, nil
+	}
+}
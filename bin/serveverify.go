@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/zilong-dai/groth16-worker/verifier"
+	"github.com/zilong-dai/groth16-worker/verifyserver"
+)
+
+// defaultKeyID pins keys given without an id, like the one of a config file.
+const defaultKeyID = "default"
+
+func runServeVerify(args []string) error {
+	flags := newFlagSet("serve-verify")
+	addConfigFlag(flags)
+	addr := flags.String("addr", "localhost:8081", "listen on this address")
+	curve := addCurveFlag(flags)
+	var vkPaths, bundlePaths stringsFlag
+	flags.Var(&vkPaths, "vk", "pin the verifying key in this file, as id=path, or path for the id "+defaultKeyID+"; repeatable")
+	flags.Var(&bundlePaths, "bundle", "pin the verifying key of this bundle, as id=path, or path for the id "+defaultKeyID+"; repeatable")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if len(vkPaths) == 0 && len(bundlePaths) == 0 {
+		return usagef("set at least one -vk or -bundle")
+	}
+
+	keys := make(map[string]*verifier.Groth16Verifier)
+	pin := func(values []string, read func(path string, curveId ecc.ID) (*verifier.Groth16Verifier, error)) error {
+		for _, value := range values {
+			id, path, ok := strings.Cut(value, "=")
+			if !ok {
+				id, path = defaultKeyID, value
+			}
+			if _, ok := keys[id]; ok {
+				return usagef("key id %s is pinned twice", id)
+			}
+
+			groth16Verifier, err := read(path, curve.id)
+			if err != nil {
+				return err
+			}
+			keys[id] = groth16Verifier
+		}
+		return nil
+	}
+	if err := pin(vkPaths, verifier.NewGroth16VerifierFromFile); err != nil {
+		return err
+	}
+	if err := pin(bundlePaths, readBundleVerifier); err != nil {
+		return err
+	}
+
+	srv, err := verifyserver.New(keys)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "verifying with %d keys on %s\n", len(keys), listener.Addr())
+
+	return serveHTTP(ctx, listener, srv)
+}
+
+func readBundleVerifier(bundlePath string, curveId ecc.ID) (*verifier.Groth16Verifier, error) {
+	groth16Verifier, err := verifier.NewGroth16Verifier(curveId)
+	if err != nil {
+		return nil, err
+	}
+	if err := groth16Verifier.ReadBundle(bundlePath); err != nil {
+		return nil, err
+	}
+
+	return groth16Verifier, nil
+}
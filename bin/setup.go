@@ -0,0 +1,143 @@
+package main
+
+import (
+	"os"
+
+	"github.com/zilong-dai/groth16-worker/worker"
+)
+
+type setupResult struct {
+	Curve        string `json:"curve"`
+	Constraints  int    `json:"constraints"`
+	Circuit      string `json:"circuit,omitempty"`
+	ProvingKey   string `json:"proving_key,omitempty"`
+	VerifyingKey string `json:"verifying_key,omitempty"`
+	Bundle       string `json:"bundle,omitempty"`
+	// Skipped lists the steps -resume found already done.
+	Skipped []string `json:"skipped,omitempty"`
+}
+
+func runSetup(args []string) error {
+	flags := newFlagSet("setup")
+	addConfigFlag(flags)
+	dir := flags.String("dir", ".", "directory with the plonky2 circuit data")
+	curve := addCurveFlag(flags)
+	circuitPath := flags.String("circuit", "", "write the r1cs to this file")
+	pkPath := flags.String("pk", "", "write the proving key to this file")
+	vkPath := flags.String("vk", "", "write the verifying key to this file")
+	bundlePath := flags.String("bundle", "", "write the r1cs and both keys to this bundle")
+	keyFormat := flags.String("key-format", worker.FormatRaw, "encoding of the proving key: compressed, raw or dump")
+	resume := flags.Bool("resume", false, "skip the steps whose outputs already exist: read -circuit instead of compiling, and stop if the keys are all there")
+	format := addFormatFlag(flags)
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if *circuitPath == "" && *pkPath == "" && *vkPath == "" && *bundlePath == "" {
+		return usagef("nothing to write, set at least one of -circuit, -pk, -vk or -bundle")
+	}
+	if err := checkKeyFormat(*keyFormat); err != nil {
+		return err
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	setupworker, err := worker.NewGroth16Worker(*dir, curve.id)
+	if err != nil {
+		return err
+	}
+	setupworker.Observer = logObserver
+
+	result := setupResult{
+		Curve:        curve.id.String(),
+		Circuit:      *circuitPath,
+		ProvingKey:   *pkPath,
+		VerifyingKey: *vkPath,
+		Bundle:       *bundlePath,
+	}
+
+	// outputs are written atomically, so any file that exists is complete
+	if *resume && *circuitPath != "" && exists(*circuitPath) {
+		if err := setupworker.ReadCircuitContext(ctx, *circuitPath); err != nil {
+			return err
+		}
+		result.Skipped = append(result.Skipped, "compile")
+	} else {
+		if err := setupworker.CompileContext(ctx); err != nil {
+			return err
+		}
+		if *circuitPath != "" {
+			if err := setupworker.WriteCircuit(*circuitPath); err != nil {
+				return err
+			}
+		}
+	}
+
+	keys := []string{*pkPath, *vkPath, *bundlePath}
+	if *resume && allExist(keys) {
+		result.Skipped = append(result.Skipped, "keygen")
+	} else {
+		if err := setupworker.KeyGenContext(ctx); err != nil {
+			return err
+		}
+		if err := writeKeys(setupworker, *pkPath, *vkPath, *bundlePath, *keyFormat); err != nil {
+			return err
+		}
+	}
+
+	result.Constraints = setupworker.Constraints()
+	return writeResult(os.Stdout, format, result)
+}
+
+func checkKeyFormat(keyFormat string) error {
+	switch keyFormat {
+	case worker.FormatCompressed, worker.FormatRaw, worker.FormatDump:
+		return nil
+	default:
+		return usagef("unknown key format %q, expected %s, %s or %s", keyFormat, worker.FormatCompressed, worker.FormatRaw, worker.FormatDump)
+	}
+}
+
+// writeKeys writes the keys of the last key generation to the paths that are
+// set.
+func writeKeys(setupworker *worker.Groth16Worker, pkPath, vkPath, bundlePath, keyFormat string) error {
+	if pkPath != "" {
+		if err := setupworker.WriteProvingKeyFormat(pkPath, keyFormat); err != nil {
+			return err
+		}
+	}
+	if vkPath != "" {
+		if err := setupworker.WriteVerifyingKey(vkPath); err != nil {
+			return err
+		}
+	}
+	if bundlePath != "" {
+		if err := setupworker.WriteBundle(bundlePath); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// allExist reports whether every path that is set exists, and at least one
+// is set.
+func allExist(paths []string) bool {
+	set := false
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		if !exists(path) {
+			return false
+		}
+		set = true
+	}
+	return set
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+
+	"github.com/zilong-dai/groth16-worker/verifier"
+)
+
+func runVerify(args []string) error {
+	flags := newFlagSet("verify")
+	addConfigFlag(flags)
+	curve := addCurveFlag(flags)
+	vkPath := flags.String("vk", "", "read the verifying key from this file")
+	bundlePath := flags.String("bundle", "", "read the verifying key from this bundle")
+	proofPath := flags.String("proof", "", "read the groth16 proof from this file")
+	publicInputsPath := flags.String("public-inputs", "", "read the public witness from this file")
+	format := addFormatFlag(flags)
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if (*vkPath == "") == (*bundlePath == "") {
+		return usagef("set exactly one of -vk or -bundle")
+	}
+	if *proofPath == "" || *publicInputsPath == "" {
+		return usagef("-proof and -public-inputs are required")
+	}
+
+	groth16Verifier, err := verifier.NewGroth16Verifier(curve.id)
+	if err != nil {
+		return err
+	}
+
+	if *bundlePath != "" {
+		err = groth16Verifier.ReadBundle(*bundlePath)
+	} else {
+		err = groth16Verifier.ReadVerifyingKey(*vkPath)
+	}
+	if err == nil {
+		err = groth16Verifier.ReadProof(*proofPath)
+	}
+	if err == nil {
+		err = groth16Verifier.ReadPublicInputs(*publicInputsPath)
+	}
+	if err != nil {
+		// files that were read but don't decode are still reported, missing
+		// or unreadable ones are not
+		if verifier.Classify(err) == "" {
+			return err
+		}
+		report := &verifier.Report{Curve: curve.id.String(), Fingerprint: groth16Verifier.Fingerprint()}
+		report.Fail(err)
+		if err := writeResult(os.Stdout, format, report); err != nil {
+			return err
+		}
+		return err
+	}
+
+	report, verifyErr := groth16Verifier.Report()
+	if err := writeResult(os.Stdout, format, report); err != nil {
+		return err
+	}
+
+	return verifyErr
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+
+	"github.com/zilong-dai/groth16-worker/worker"
+)
+
+type witnessResult struct {
+	Curve        string `json:"curve"`
+	Witness      string `json:"witness"`
+	PublicInputs string `json:"public_inputs,omitempty"`
+}
+
+func runWitness(args []string) error {
+	flags := newFlagSet("witness")
+	addConfigFlag(flags)
+	dir := flags.String("dir", ".", "directory with the plonky2 circuit data and proof")
+	curve := addCurveFlag(flags)
+	witnessPath := flags.String("witness", "", "write the full witness to this file")
+	publicInputsPath := flags.String("public-inputs", "", "write the public witness to this file")
+	format := addFormatFlag(flags)
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if *witnessPath == "" {
+		return usagef("-witness is required")
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	// the witness is generated from the inputs alone, without a prover,
+	// circuit or keys
+	inputs, err := worker.NewPlonky2InputsFromDir(*dir)
+	if err != nil {
+		return err
+	}
+	fingerprint, err := inputs.Fingerprint()
+	if err != nil {
+		return err
+	}
+
+	full, err := worker.GenerateWitness(ctx, inputs, curve.id, logObserver)
+	if err != nil {
+		return err
+	}
+	if err := worker.WriteWitness(*witnessPath, full, fingerprint); err != nil {
+		return err
+	}
+	if *publicInputsPath != "" {
+		if err := worker.WritePublicInputs(*publicInputsPath, full); err != nil {
+			return err
+		}
+	}
+
+	return writeResult(os.Stdout, format, witnessResult{
+		Curve:        curve.id.String(),
+		Witness:      *witnessPath,
+		PublicInputs: *publicInputsPath,
+	})
+}
@@ -0,0 +1,354 @@
+// Package config loads the YAML file describing a circuit and where its
+// artifacts live, so runs don't have to repeat the same flags. Every value can
+// be overridden with an environment variable, see EnvPrefix.
+//
+// A config file looks like:
+//
+//	dir: plonky2                 # plonky2 inputs
+//	curve: bls12_381
+//	backend: groth16
+//	key_format: raw              # compressed, raw or dump
+//	artifacts:
+//	  circuit: build/circuit.r1cs
+//	  proving_key: build/proving.key
+//	  verifying_key: build/verifying.key
+//	  bundle: build/setup.bundle
+//	prover:
+//	  max_concurrent_proofs: 2
+//	  cpus_per_proof: 16
+//	output:
+//	  proof: out/proof
+//	  public_inputs: out/public_inputs
+//	  proof_encoding: compressed # compressed or raw
+//	  format: json               # text or json
+//	server:
+//	  addr: localhost:8080
+//	  queue_size: 64             # jobs waiting for a prover
+//	  grpc_addr: localhost:9090  # also serve gRPC, off if empty
+//	  store: jobs                # keep jobs on disk, in memory if empty
+//	  result_ttl: 24h            # keep finished jobs this long, 0 is forever
+//	  max_finished_jobs: 1024    # keep at most this many finished jobs
+//
+// Relative paths are relative to the directory of the config file.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/consensys/gnark"
+	"github.com/consensys/gnark-crypto/ecc"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix starts the environment variables that override config values.
+// The rest of the name is the key path in upper case with dots replaced by
+// underscores, e.g. GROTH16_WORKER_PROVER_MAX_CONCURRENT_PROOFS.
+const EnvPrefix = "GROTH16_WORKER_"
+
+// EnvConfig names the environment variable holding the path of the config
+// file to load when none is given.
+const EnvConfig = EnvPrefix + "CONFIG"
+
+type Config struct {
+	Dir       string    `yaml:"dir"`
+	Curve     string    `yaml:"curve"`
+	Backend   string    `yaml:"backend"`
+	KeyFormat string    `yaml:"key_format"`
+	Artifacts Artifacts `yaml:"artifacts"`
+	Prover    Prover    `yaml:"prover"`
+	Output    Output    `yaml:"output"`
+	Server    Server    `yaml:"server"`
+
+	// sources maps the keys set by environment variables to the variable,
+	// so errors point at where a bad value came from; set holds the keys
+	// set by the file.
+	sources map[string]string
+	set     map[string]bool
+	path    string
+}
+
+type Artifacts struct {
+	Circuit      string `yaml:"circuit"`
+	ProvingKey   string `yaml:"proving_key"`
+	VerifyingKey string `yaml:"verifying_key"`
+	Bundle       string `yaml:"bundle"`
+}
+
+type Prover struct {
+	MaxConcurrentProofs int `yaml:"max_concurrent_proofs"`
+	CPUsPerProof        int `yaml:"cpus_per_proof"`
+}
+
+type Output struct {
+	Proof         string `yaml:"proof"`
+	PublicInputs  string `yaml:"public_inputs"`
+	ProofEncoding string `yaml:"proof_encoding"`
+	Format        string `yaml:"format"`
+}
+
+type Server struct {
+	Addr            string `yaml:"addr"`
+	QueueSize       int    `yaml:"queue_size"`
+	GRPCAddr        string `yaml:"grpc_addr"`
+	Store           string `yaml:"store"`
+	ResultTTL       string `yaml:"result_ttl"`
+	MaxFinishedJobs int    `yaml:"max_finished_jobs"`
+}
+
+// Error is a bad value in a config. Source is the config file or the
+// environment variable the value came from, Key its dotted path.
+type Error struct {
+	Source string
+	Key    string
+	Err    error
+}
+
+func (e *Error) Error() string {
+	msg := e.Source
+	if e.Key != "" {
+		msg += ": " + e.Key
+	}
+	return msg + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+var ErrInvalidValue = errors.New("invalid value")
+
+// Default returns the config used when no file is given.
+func Default() *Config {
+	return &Config{
+		Dir:       ".",
+		Curve:     ecc.BLS12_381.String(),
+		Backend:   "groth16",
+		KeyFormat: "raw",
+		Output: Output{
+			ProofEncoding: "compressed",
+			Format:        "text",
+		},
+		Server: Server{
+			Addr:      "localhost:8080",
+			QueueSize: 64,
+		},
+	}
+}
+
+// Load reads the config file at path on top of the defaults, applies the
+// environment overrides and validates the result. An empty path loads only
+// the defaults and the environment. Validation errors are all reported,
+// joined, as *Error.
+func Load(path string) (*Config, error) {
+	c := Default()
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config: %w", err)
+		}
+
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+			return nil, &Error{Source: path, Err: yamlError(err)}
+		}
+
+		var keys map[string]any
+		if err := yaml.Unmarshal(data, &keys); err != nil {
+			return nil, &Error{Source: path, Err: yamlError(err)}
+		}
+		c.set = make(map[string]bool)
+		flattenKeys(keys, "", c.set)
+
+		c.path = path
+		c.resolvePaths(filepath.Dir(path))
+	}
+
+	if err := c.applyEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// yamlError drops the "yaml: " prefix, the source already says it is the
+// config.
+func yamlError(err error) error {
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		return errors.New(strings.Join(typeErr.Errors, "; "))
+	}
+	return errors.New(strings.TrimPrefix(err.Error(), "yaml: "))
+}
+
+func flattenKeys(m map[string]any, prefix string, keys map[string]bool) {
+	for key, value := range m {
+		if nested, ok := value.(map[string]any); ok {
+			flattenKeys(nested, prefix+key+".", keys)
+			continue
+		}
+		keys[prefix+key] = true
+	}
+}
+
+// resolvePaths makes the paths of the file relative to dir.
+func (c *Config) resolvePaths(dir string) {
+	for _, path := range c.paths() {
+		if *path != "" && !filepath.IsAbs(*path) {
+			*path = filepath.Join(dir, *path)
+		}
+	}
+}
+
+func (c *Config) paths() []*string {
+	return []*string{
+		&c.Dir,
+		&c.Artifacts.Circuit,
+		&c.Artifacts.ProvingKey,
+		&c.Artifacts.VerifyingKey,
+		&c.Artifacts.Bundle,
+		&c.Output.Proof,
+		&c.Output.PublicInputs,
+		&c.Server.Store,
+	}
+}
+
+// applyEnv overrides every key that has an environment variable set.
+func (c *Config) applyEnv(lookup func(string) (string, bool)) error {
+	var errs []error
+	walk(reflect.ValueOf(c).Elem(), "", func(key string, field reflect.Value) {
+		name := EnvName(key)
+		value, ok := lookup(name)
+		if !ok {
+			return
+		}
+
+		if c.sources == nil {
+			c.sources = make(map[string]string)
+		}
+		c.sources[key] = name
+
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString(value)
+		case reflect.Int:
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				errs = append(errs, &Error{Source: name, Key: key, Err: fmt.Errorf("%w: %q is not an integer", ErrInvalidValue, value)})
+				return
+			}
+			field.SetInt(int64(n))
+		}
+	})
+
+	return errors.Join(errs...)
+}
+
+// walk calls fn for every string and int field of v, named by the dotted
+// path of its yaml keys.
+func walk(v reflect.Value, prefix string, fn func(key string, field reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag.Get("yaml")
+		if tag == "" {
+			continue
+		}
+		key := prefix + tag
+
+		field := v.Field(i)
+		if field.Kind() == reflect.Struct {
+			walk(field, key+".", fn)
+		} else {
+			fn(key, field)
+		}
+	}
+}
+
+// EnvName returns the environment variable overriding key.
+func EnvName(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// IsSet reports whether key was set by the config file or the environment,
+// rather than left at its default.
+func (c *Config) IsSet(key string) bool {
+	_, env := c.sources[key]
+	return env || c.set[key]
+}
+
+// Get returns the value of key as a string, or an empty string if there is no
+// such key.
+func (c *Config) Get(key string) string {
+	var value string
+	walk(reflect.ValueOf(c).Elem(), "", func(k string, field reflect.Value) {
+		if k == key {
+			value = fmt.Sprint(field.Interface())
+		}
+	})
+	return value
+}
+
+// Source returns the config file or environment variable the value of key
+// came from.
+func (c *Config) Source(key string) string {
+	if name, ok := c.sources[key]; ok {
+		return name
+	}
+	if c.path != "" {
+		return c.path
+	}
+	return "config"
+}
+
+// Validate checks every value and reports all bad ones.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(key string, ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, &Error{Source: c.Source(key), Key: key, Err: fmt.Errorf("%w: "+format, append([]any{ErrInvalidValue}, args...)...)})
+		}
+	}
+
+	_, err := c.CurveID()
+	check("curve", err == nil, "unknown curve %q", c.Curve)
+	check("backend", c.Backend == "groth16", "unsupported backend %q, only groth16 is", c.Backend)
+	check("key_format", slices.Contains([]string{"compressed", "raw", "dump"}, c.KeyFormat), "unknown key format %q, expected compressed, raw or dump", c.KeyFormat)
+	check("prover.max_concurrent_proofs", c.Prover.MaxConcurrentProofs >= 0, "must not be negative")
+	check("prover.cpus_per_proof", c.Prover.CPUsPerProof >= 0, "must not be negative")
+	check("output.proof_encoding", c.Output.ProofEncoding == "compressed" || c.Output.ProofEncoding == "raw", "unknown proof encoding %q, expected compressed or raw", c.Output.ProofEncoding)
+	check("output.format", c.Output.Format == "text" || c.Output.Format == "json", "unknown format %q, expected text or json", c.Output.Format)
+	check("server.queue_size", c.Server.QueueSize > 0, "must be positive")
+	if c.Server.ResultTTL != "" {
+		ttl, err := time.ParseDuration(c.Server.ResultTTL)
+		check("server.result_ttl", err == nil && ttl >= 0, "invalid duration %q", c.Server.ResultTTL)
+	}
+	check("server.max_finished_jobs", c.Server.MaxFinishedJobs >= 0, "must not be negative")
+
+	return errors.Join(errs...)
+}
+
+// CurveID parses Curve, as printed by ecc.ID with either - or _.
+func (c *Config) CurveID() (ecc.ID, error) {
+	name := strings.ReplaceAll(strings.ToLower(c.Curve), "-", "_")
+	for _, id := range gnark.Curves() {
+		if id.String() == name {
+			return id, nil
+		}
+	}
+	return ecc.UNKNOWN, fmt.Errorf("unknown curve %q", c.Curve)
+}
@@ -0,0 +1,98 @@
+package config_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/zilong-dai/groth16-worker/config"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "worker.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	path := writeConfig(t, `
+dir: plonky2
+artifacts:
+  proving_key: build/proving.key
+  bundle: /srv/setup.bundle
+prover:
+  max_concurrent_proofs: 2
+output:
+  format: json
+server:
+  addr: 0.0.0.0:9000
+  store: jobs
+`)
+	t.Setenv(config.EnvName("prover.cpus_per_proof"), "8")
+	t.Setenv(config.EnvName("output.format"), "text")
+
+	c, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := filepath.Dir(path)
+	if c.Dir != filepath.Join(dir, "plonky2") || c.Artifacts.ProvingKey != filepath.Join(dir, "build/proving.key") || c.Artifacts.Bundle != "/srv/setup.bundle" {
+		t.Fatalf("paths not resolved against the config file: %+v", c)
+	}
+	if c.Prover.MaxConcurrentProofs != 2 || c.Prover.CPUsPerProof != 8 || c.Output.Format != "text" {
+		t.Fatalf("unexpected config %+v", c)
+	}
+	if c.Server.Addr != "0.0.0.0:9000" || c.Server.QueueSize != config.Default().Server.QueueSize || c.Server.Store != filepath.Join(dir, "jobs") {
+		t.Fatalf("unexpected server config %+v", c.Server)
+	}
+	if curve, err := c.CurveID(); err != nil || curve != ecc.BLS12_381 {
+		t.Fatalf("expected the default curve, got %v, %v", curve, err)
+	}
+
+	if !c.IsSet("artifacts.proving_key") || !c.IsSet("prover.cpus_per_proof") || c.IsSet("artifacts.circuit") || c.IsSet("curve") {
+		t.Fatal("unexpected set keys")
+	}
+	if got := c.Get("prover.max_concurrent_proofs"); got != "2" {
+		t.Fatalf("expected 2, got %q", got)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	path := writeConfig(t, "curve: bn999\nkey_format: zip\nprover:\n  max_concurrent_proofs: -1\nserver:\n  result_ttl: a day\n")
+	t.Setenv(config.EnvName("output.format"), "xml")
+
+	_, err := config.Load(path)
+	if !errors.Is(err, config.ErrInvalidValue) {
+		t.Fatalf("expected invalid values, got %v", err)
+	}
+	for _, want := range []string{
+		path + ": curve: ",
+		path + ": key_format: ",
+		path + ": prover.max_concurrent_proofs: ",
+		path + ": server.result_ttl: ",
+		"GROTH16_WORKER_OUTPUT_FORMAT: output.format: ",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("%q doesn't point to %q", err, want)
+		}
+	}
+
+	t.Setenv(config.EnvName("output.format"), "")
+	t.Setenv(config.EnvName("prover.cpus_per_proof"), "many")
+	var configErr *config.Error
+	if _, err := config.Load(""); !errors.As(err, &configErr) || configErr.Source != "GROTH16_WORKER_PROVER_CPUS_PER_PROOF" {
+		t.Fatalf("expected an error on the environment variable, got %v", err)
+	}
+
+	if _, err := config.Load(writeConfig(t, "artifact:\n  circuit: r1cs\n")); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Fatalf("expected an unknown key error with its line, got %v", err)
+	}
+}
@@ -0,0 +1,263 @@
+// The gRPC API of the groth16 worker: it proves plonky2 proofs with a
+// resident proving key and verifies groth16 proofs.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v4.25.3
+// source: worker.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	Groth16Worker_SubmitProof_FullMethodName     = "/groth16worker.v1.Groth16Worker/SubmitProof"
+	Groth16Worker_GetJob_FullMethodName          = "/groth16worker.v1.Groth16Worker/GetJob"
+	Groth16Worker_StreamJobStatus_FullMethodName = "/groth16worker.v1.Groth16Worker/StreamJobStatus"
+	Groth16Worker_Verify_FullMethodName          = "/groth16worker.v1.Groth16Worker/Verify"
+)
+
+// Groth16WorkerClient is the client API for Groth16Worker service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type Groth16WorkerClient interface {
+	// SubmitProof queues a plonky2 proof for proving and returns its job. A
+	// proof already submitted gets its job, unless that failed or expired.
+	SubmitProof(ctx context.Context, in *SubmitProofRequest, opts ...grpc.CallOption) (*Job, error)
+	// GetJob returns a job, with its proof once it is done.
+	GetJob(ctx context.Context, in *GetJobRequest, opts ...grpc.CallOption) (*Job, error)
+	// StreamJobStatus streams the status changes and phase events of a job,
+	// starting with its current state, until it is done or failed.
+	StreamJobStatus(ctx context.Context, in *GetJobRequest, opts ...grpc.CallOption) (Groth16Worker_StreamJobStatusClient, error)
+	// Verify checks a groth16 proof against a verifying key.
+	Verify(ctx context.Context, in *VerifyRequest, opts ...grpc.CallOption) (*VerifyResponse, error)
+}
+
+type groth16WorkerClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewGroth16WorkerClient(cc grpc.ClientConnInterface) Groth16WorkerClient {
+	return &groth16WorkerClient{cc}
+}
+
+func (c *groth16WorkerClient) SubmitProof(ctx context.Context, in *SubmitProofRequest, opts ...grpc.CallOption) (*Job, error) {
+	out := new(Job)
+	err := c.cc.Invoke(ctx, Groth16Worker_SubmitProof_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *groth16WorkerClient) GetJob(ctx context.Context, in *GetJobRequest, opts ...grpc.CallOption) (*Job, error) {
+	out := new(Job)
+	err := c.cc.Invoke(ctx, Groth16Worker_GetJob_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *groth16WorkerClient) StreamJobStatus(ctx context.Context, in *GetJobRequest, opts ...grpc.CallOption) (Groth16Worker_StreamJobStatusClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Groth16Worker_ServiceDesc.Streams[0], Groth16Worker_StreamJobStatus_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &groth16WorkerStreamJobStatusClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Groth16Worker_StreamJobStatusClient interface {
+	Recv() (*JobEvent, error)
+	grpc.ClientStream
+}
+
+type groth16WorkerStreamJobStatusClient struct {
+	grpc.ClientStream
+}
+
+func (x *groth16WorkerStreamJobStatusClient) Recv() (*JobEvent, error) {
+	m := new(JobEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *groth16WorkerClient) Verify(ctx context.Context, in *VerifyRequest, opts ...grpc.CallOption) (*VerifyResponse, error) {
+	out := new(VerifyResponse)
+	err := c.cc.Invoke(ctx, Groth16Worker_Verify_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Groth16WorkerServer is the server API for Groth16Worker service.
+// All implementations must embed UnimplementedGroth16WorkerServer
+// for forward compatibility
+type Groth16WorkerServer interface {
+	// SubmitProof queues a plonky2 proof for proving and returns its job. A
+	// proof already submitted gets its job, unless that failed or expired.
+	SubmitProof(context.Context, *SubmitProofRequest) (*Job, error)
+	// GetJob returns a job, with its proof once it is done.
+	GetJob(context.Context, *GetJobRequest) (*Job, error)
+	// StreamJobStatus streams the status changes and phase events of a job,
+	// starting with its current state, until it is done or failed.
+	StreamJobStatus(*GetJobRequest, Groth16Worker_StreamJobStatusServer) error
+	// Verify checks a groth16 proof against a verifying key.
+	Verify(context.Context, *VerifyRequest) (*VerifyResponse, error)
+	mustEmbedUnimplementedGroth16WorkerServer()
+}
+
+// UnimplementedGroth16WorkerServer must be embedded to have forward compatible implementations.
+type UnimplementedGroth16WorkerServer struct {
+}
+
+func (UnimplementedGroth16WorkerServer) SubmitProof(context.Context, *SubmitProofRequest) (*Job, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SubmitProof not implemented")
+}
+func (UnimplementedGroth16WorkerServer) GetJob(context.Context, *GetJobRequest) (*Job, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetJob not implemented")
+}
+func (UnimplementedGroth16WorkerServer) StreamJobStatus(*GetJobRequest, Groth16Worker_StreamJobStatusServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamJobStatus not implemented")
+}
+func (UnimplementedGroth16WorkerServer) Verify(context.Context, *VerifyRequest) (*VerifyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Verify not implemented")
+}
+func (UnimplementedGroth16WorkerServer) mustEmbedUnimplementedGroth16WorkerServer() {}
+
+// UnsafeGroth16WorkerServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to Groth16WorkerServer will
+// result in compilation errors.
+type UnsafeGroth16WorkerServer interface {
+	mustEmbedUnimplementedGroth16WorkerServer()
+}
+
+func RegisterGroth16WorkerServer(s grpc.ServiceRegistrar, srv Groth16WorkerServer) {
+	s.RegisterService(&Groth16Worker_ServiceDesc, srv)
+}
+
+func _Groth16Worker_SubmitProof_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubmitProofRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(Groth16WorkerServer).SubmitProof(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Groth16Worker_SubmitProof_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(Groth16WorkerServer).SubmitProof(ctx, req.(*SubmitProofRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Groth16Worker_GetJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetJobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(Groth16WorkerServer).GetJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Groth16Worker_GetJob_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(Groth16WorkerServer).GetJob(ctx, req.(*GetJobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Groth16Worker_StreamJobStatus_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetJobRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(Groth16WorkerServer).StreamJobStatus(m, &groth16WorkerStreamJobStatusServer{stream})
+}
+
+type Groth16Worker_StreamJobStatusServer interface {
+	Send(*JobEvent) error
+	grpc.ServerStream
+}
+
+type groth16WorkerStreamJobStatusServer struct {
+	grpc.ServerStream
+}
+
+func (x *groth16WorkerStreamJobStatusServer) Send(m *JobEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Groth16Worker_Verify_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VerifyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(Groth16WorkerServer).Verify(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Groth16Worker_Verify_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(Groth16WorkerServer).Verify(ctx, req.(*VerifyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Groth16Worker_ServiceDesc is the grpc.ServiceDesc for Groth16Worker service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Groth16Worker_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "groth16worker.v1.Groth16Worker",
+	HandlerType: (*Groth16WorkerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SubmitProof",
+			Handler:    _Groth16Worker_SubmitProof_Handler,
+		},
+		{
+			MethodName: "GetJob",
+			Handler:    _Groth16Worker_GetJob_Handler,
+		},
+		{
+			MethodName: "Verify",
+			Handler:    _Groth16Worker_Verify_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamJobStatus",
+			Handler:       _Groth16Worker_StreamJobStatus_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "worker.proto",
+}
@@ -0,0 +1,207 @@
+// Package rpc serves the job server of package server over gRPC, along with
+// verification, as the Groth16Worker service of pb/worker.proto. Proofs and
+// public witnesses are in the same encodings as Groth16Prover.WriteProof and
+// WritePublicInputs.
+package rpc
+
+//go:generate protoc -I pb --go_out=pb --go_opt=paths=source_relative --go-grpc_out=pb --go-grpc_opt=paths=source_relative worker.proto
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/zilong-dai/groth16-worker/rpc/pb"
+	"github.com/zilong-dai/groth16-worker/server"
+	"github.com/zilong-dai/groth16-worker/verifier"
+	"github.com/zilong-dai/groth16-worker/worker"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type Options struct {
+	// Curve of the prover, verifying keys sent to Verify are decoded on it.
+	Curve ecc.ID
+	// VerifyingKey is used by Verify calls that don't send a key. Nil means
+	// they must.
+	VerifyingKey groth16.VerifyingKey
+}
+
+// Service implements pb.Groth16WorkerServer with the jobs of a server.Server.
+type Service struct {
+	pb.UnimplementedGroth16WorkerServer
+
+	server *server.Server
+	curve  ecc.ID
+	vk     groth16.VerifyingKey
+}
+
+func NewService(srv *server.Server, opts Options) *Service {
+	return &Service{server: srv, curve: opts.Curve, vk: opts.VerifyingKey}
+}
+
+// Register adds the service to a grpc.Server.
+func (s *Service) Register(registrar grpc.ServiceRegistrar) {
+	pb.RegisterGroth16WorkerServer(registrar, s)
+}
+
+func (s *Service) SubmitProof(ctx context.Context, req *pb.SubmitProofRequest) (*pb.Job, error) {
+	job, err := s.server.Submit(req.ProofWithPublicInputs)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return jobMessage(job), nil
+}
+
+func (s *Service) GetJob(ctx context.Context, req *pb.GetJobRequest) (*pb.Job, error) {
+	job, ok := s.server.Job(req.Id)
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "no job %s", req.Id)
+	}
+
+	return jobMessage(job), nil
+}
+
+func (s *Service) StreamJobStatus(req *pb.GetJobRequest, stream pb.Groth16Worker_StreamJobStatusServer) error {
+	events, err := s.server.Watch(stream.Context(), req.Id)
+	if err != nil {
+		return statusError(err)
+	}
+
+	for event := range events {
+		message := &pb.JobEvent{Job: jobMessage(event.Job)}
+		if event.Phase != nil {
+			message.Phase = phaseMessage(event.Phase)
+		}
+		if err := stream.Send(message); err != nil {
+			return err
+		}
+	}
+
+	return stream.Context().Err()
+}
+
+// Verify verifies a proof like the verify command. Proofs that don't verify,
+// including ones that don't decode, are a response with Valid unset, not an
+// error.
+func (s *Service) Verify(ctx context.Context, req *pb.VerifyRequest) (*pb.VerifyResponse, error) {
+	var groth16Verifier *verifier.Groth16Verifier
+	var err error
+	switch {
+	case len(req.VerifyingKey) > 0:
+		groth16Verifier, err = verifier.NewGroth16VerifierFromUntrustedBytes(req.VerifyingKey, s.curve)
+	case s.vk != nil:
+		groth16Verifier, err = verifier.NewGroth16VerifierFromKey(s.vk)
+	default:
+		return nil, status.Error(codes.FailedPrecondition, "no verifying key is loaded, send one with the request")
+	}
+	if err == nil {
+		err = groth16Verifier.ReadProofFrom(bytes.NewReader(req.Proof))
+	}
+	if err == nil {
+		err = groth16Verifier.ReadPublicInputsFrom(bytes.NewReader(req.PublicWitness))
+	}
+	if err != nil {
+		if verifier.Classify(err) == "" {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		report := &verifier.Report{Curve: s.curve.String()}
+		report.Fail(err)
+		return verifyResponse(report), nil
+	}
+
+	report, err := groth16Verifier.Report()
+	if err != nil && report.Failure == "" {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return verifyResponse(report), nil
+}
+
+// statusError maps the errors of the job server to gRPC codes.
+func statusError(err error) error {
+	var inputErr *worker.InputError
+	switch {
+	case errors.As(err, &inputErr):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, server.ErrUnknownJob):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, server.ErrQueueFull):
+		return status.Error(codes.Unavailable, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+var jobStatuses = map[server.Status]pb.JobStatus{
+	server.StatusQueued:  pb.JobStatus_JOB_STATUS_QUEUED,
+	server.StatusSolving: pb.JobStatus_JOB_STATUS_SOLVING,
+	server.StatusProving: pb.JobStatus_JOB_STATUS_PROVING,
+	server.StatusDone:    pb.JobStatus_JOB_STATUS_DONE,
+	server.StatusFailed:  pb.JobStatus_JOB_STATUS_FAILED,
+}
+
+func jobMessage(job server.Job) *pb.Job {
+	return &pb.Job{
+		Id:               job.ID,
+		Status:           jobStatuses[job.Status],
+		CreatedUnixNano:  unixNano(&job.Created),
+		StartedUnixNano:  unixNano(job.Started),
+		FinishedUnixNano: unixNano(job.Finished),
+		PublicInputs:     job.PublicInputs,
+		Proof:            job.Proof(),
+		PublicWitness:    job.PublicWitness(),
+		Error:            job.Error,
+		Attempts:         int64(job.Attempts),
+		Hash:             job.Hash,
+	}
+}
+
+var phaseKinds = map[worker.EventKind]pb.PhaseEventKind{
+	worker.PhaseStarted:  pb.PhaseEventKind_PHASE_EVENT_KIND_STARTED,
+	worker.PhaseProgress: pb.PhaseEventKind_PHASE_EVENT_KIND_PROGRESS,
+	worker.PhaseFinished: pb.PhaseEventKind_PHASE_EVENT_KIND_FINISHED,
+}
+
+func phaseMessage(event *worker.Event) *pb.PhaseEvent {
+	message := &pb.PhaseEvent{
+		Phase:         string(event.Phase),
+		Kind:          phaseKinds[event.Kind],
+		UnixNano:      unixNano(&event.Time),
+		DurationNanos: int64(event.Duration),
+		Constraints:   int64(event.Constraints),
+		BytesRead:     event.BytesRead,
+		Estimated:     event.Estimated,
+	}
+	if event.Err != nil {
+		message.Error = event.Err.Error()
+	}
+
+	return message
+}
+
+func verifyResponse(report *verifier.Report) *pb.VerifyResponse {
+	return &pb.VerifyResponse{
+		Valid:              report.Valid,
+		Failure:            string(report.Failure),
+		Error:              report.Error,
+		PublicInputs:       report.PublicInputs,
+		VerifyingKeySha256: report.VerifyingKey,
+		ProofSha256:        report.Proof,
+	}
+}
+
+// unixNano returns t in nanoseconds since the unix epoch, or zero if t is nil
+// or zero.
+func unixNano(t *time.Time) int64 {
+	if t == nil || t.IsZero() {
+		return 0
+	}
+
+	return t.UnixNano()
+}
@@ -0,0 +1,291 @@
+package rpc_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/zilong-dai/groth16-worker/artifact"
+	"github.com/zilong-dai/groth16-worker/rpc"
+	"github.com/zilong-dai/groth16-worker/rpc/pb"
+	"github.com/zilong-dai/groth16-worker/server"
+	"github.com/zilong-dai/groth16-worker/worker"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type cubicCircuit struct {
+	X frontend.Variable `gnark:"x"`
+	Y frontend.Variable `gnark:",public"`
+}
+
+func (c *cubicCircuit) Define(api frontend.API) error {
+	x3 := api.Mul(c.X, c.X, c.X)
+	api.AssertIsEqual(c.Y, api.Add(x3, c.X, 5))
+	// decomposing x calls a hint, so proves are split into solve and msm
+	api.ToBinary(c.X, 8)
+	return nil
+}
+
+// cubicProver stands in for a prover of the plonky2 verifier circuit, as in
+// the server tests: it proves the same cubic witness whatever it is given,
+// once release is closed.
+type cubicProver struct {
+	prover  *worker.Groth16Prover
+	witness witness.Witness
+	release chan struct{}
+}
+
+func (p *cubicProver) ProvePlonky2(ctx context.Context, proofWithPublicInputs []byte) (*worker.Groth16Proof, error) {
+	select {
+	case <-p.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return p.prover.ProveWitness(ctx, p.witness)
+}
+
+func newCubicProver(t *testing.T) (*cubicProver, groth16.VerifyingKey) {
+	t.Helper()
+
+	ccs, err := frontend.Compile(ecc.BLS12_381.ScalarField(), r1cs.NewBuilder, &cubicCircuit{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk, vk, err := groth16.Setup(ccs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	circuitPath, pkPath := filepath.Join(dir, "circuit"), filepath.Join(dir, "proving.key")
+	for path, from := range map[string]io.WriterTo{circuitPath: ccs, pkPath: pk} {
+		file, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := from.WriteTo(file); err != nil {
+			t.Fatal(err)
+		}
+		file.Close()
+	}
+
+	t.Setenv(worker.EnvCacheDir, "")
+	prover, err := worker.NewGroth16Prover(dir, ecc.BLS12_381)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := prover.ReadCircuit(circuitPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := prover.ReadProvingKey(pkPath); err != nil {
+		t.Fatal(err)
+	}
+
+	full, err := frontend.NewWitness(&cubicCircuit{X: 3, Y: 35}, ecc.BLS12_381.ScalarField())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &cubicProver{prover: prover, witness: full, release: make(chan struct{})}, vk
+}
+
+// newClient serves the service over an in-memory listener.
+func newClient(t *testing.T, prover server.Prover, opts rpc.Options) (pb.Groth16WorkerClient, *worker.Plonky2Inputs) {
+	t.Helper()
+
+	inputs, err := worker.NewPlonky2InputsFromDir("../testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv, err := server.New(prover, server.Options{Curve: ecc.BLS12_381, Inputs: inputs})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listener := bufconn.Listen(1 << 20)
+	grpcServer := grpc.NewServer()
+	opts.Curve = ecc.BLS12_381
+	rpc.NewService(srv, opts).Register(grpcServer)
+	go grpcServer.Serve(listener)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		grpcServer.Stop()
+		srv.Close()
+	})
+
+	return pb.NewGroth16WorkerClient(conn), inputs
+}
+
+func TestService(t *testing.T) {
+	prover, vk := newCubicProver(t)
+	client, inputs := newClient(t, prover, rpc.Options{})
+	ctx := context.Background()
+
+	job, err := client.SubmitProof(ctx, &pb.SubmitProofRequest{ProofWithPublicInputs: inputs.ProofWithPublicInputs})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Id == "" || job.CreatedUnixNano == 0 {
+		t.Fatalf("expected a submitted job, got %v", job)
+	}
+
+	stream, err := client.StreamJobStatus(ctx, &pb.GetJobRequest{Id: job.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the first event is the queued job, proving starts once it is streamed
+	first, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Job.Status != pb.JobStatus_JOB_STATUS_QUEUED && first.Job.Status != pb.JobStatus_JOB_STATUS_SOLVING {
+		t.Fatalf("expected the stream to start with the pending job, got %v", first.Job)
+	}
+	close(prover.release)
+
+	var last *pb.Job
+	phases := make(map[string]bool)
+	estimated := make(map[string]bool)
+	for {
+		event, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if event.Phase != nil {
+			phases[event.Phase.Phase] = true
+			if event.Phase.Estimated {
+				estimated[event.Phase.Phase] = true
+			}
+		}
+		last = event.Job
+	}
+	if last.Status != pb.JobStatus_JOB_STATUS_DONE || len(last.Proof) == 0 {
+		t.Fatalf("expected the stream to end with the finished job, got %v", last)
+	}
+	if !phases[string(worker.PhaseProve)] {
+		t.Fatalf("expected the prove phase in the stream, got %v", phases)
+	}
+	if !estimated[string(worker.PhaseSolve)] || estimated[string(worker.PhaseProve)] {
+		t.Fatalf("expected only the solve and msm phases to be estimated, got %v", estimated)
+	}
+
+	job, err = client.GetJob(ctx, &pb.GetJobRequest{Id: job.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(job.PublicInputs) != 1 || job.PublicInputs[0] != 35 || job.FinishedUnixNano == 0 {
+		t.Fatalf("unexpected finished job %v", job)
+	}
+
+	var vkBuf bytes.Buffer
+	if _, err := vk.WriteTo(&vkBuf); err != nil {
+		t.Fatal(err)
+	}
+	verified, err := client.Verify(ctx, &pb.VerifyRequest{VerifyingKey: vkBuf.Bytes(), Proof: job.Proof, PublicWitness: job.PublicWitness})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !verified.Valid || len(verified.PublicInputs) != 1 || verified.ProofSha256 == "" {
+		t.Fatalf("expected the served proof to verify, got %v", verified)
+	}
+
+	verified, err = client.Verify(ctx, &pb.VerifyRequest{VerifyingKey: vkBuf.Bytes(), Proof: []byte("garbage"), PublicWitness: job.PublicWitness})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verified.Valid || verified.Failure != "encoding" {
+		t.Fatalf("expected an encoding failure for a garbage proof, got %v", verified)
+	}
+
+	var rawVk, dumpVk bytes.Buffer
+	if _, err := vk.WriteRawTo(&rawVk); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := artifact.WriteVerifyingKeyDump(&dumpVk, vk); err != nil {
+		t.Fatal(err)
+	}
+	verified, err = client.Verify(ctx, &pb.VerifyRequest{VerifyingKey: rawVk.Bytes(), Proof: job.Proof, PublicWitness: job.PublicWitness})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !verified.Valid {
+		t.Fatalf("expected the served proof to verify with a raw key, got %v", verified)
+	}
+
+	// keys sent by clients are decoded by gnark only, and a count can't ask
+	// for more points than the key holds
+	header := 3*48 + 3*96
+	huge := append(bytes.Clone(vkBuf.Bytes()[:header]), 0xff, 0xff, 0xff, 0xff)
+	for name, key := range map[string][]byte{"dump": dumpVk.Bytes(), "huge count": huge} {
+		verified, err = client.Verify(ctx, &pb.VerifyRequest{VerifyingKey: key, Proof: job.Proof, PublicWitness: job.PublicWitness})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if verified.Valid || verified.Failure != "encoding" {
+			t.Fatalf("%s: expected an encoding failure, got %v", name, verified)
+		}
+	}
+}
+
+func TestServiceErrors(t *testing.T) {
+	prover, _ := newCubicProver(t)
+	client, _ := newClient(t, prover, rpc.Options{})
+	ctx := context.Background()
+
+	for _, test := range []struct {
+		name string
+		call func() error
+		code codes.Code
+	}{
+		{"malformed proof", func() error {
+			_, err := client.SubmitProof(ctx, &pb.SubmitProofRequest{ProofWithPublicInputs: []byte(`{"proof": {}}`)})
+			return err
+		}, codes.InvalidArgument},
+		{"unknown job", func() error {
+			_, err := client.GetJob(ctx, &pb.GetJobRequest{Id: "unknown"})
+			return err
+		}, codes.NotFound},
+		{"unknown job stream", func() error {
+			stream, err := client.StreamJobStatus(ctx, &pb.GetJobRequest{Id: "unknown"})
+			if err != nil {
+				return err
+			}
+			_, err = stream.Recv()
+			return err
+		}, codes.NotFound},
+		{"no verifying key", func() error {
+			_, err := client.Verify(ctx, &pb.VerifyRequest{})
+			return err
+		}, codes.FailedPrecondition},
+	} {
+		if code := status.Code(test.call()); code != test.code {
+			t.Errorf("%s: expected %s, got %s", test.name, test.code, code)
+		}
+	}
+}
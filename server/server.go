@@ -0,0 +1,720 @@
+// Package server proves plonky2 proofs over HTTP with a Groth16Prover whose
+// circuit and proving key are loaded once, so requests don't pay for reading
+// the key.
+//
+// Proofs are submitted as jobs and proven in the background, optionally kept
+// in a Store so they survive restarts. A proof submitted again while its job
+// is queued, running or done gets that job instead of a new one. Finished
+// jobs are forgotten once Options.ResultTTL has passed, or once
+// Options.MaxFinishedJobs newer ones have finished:
+//
+//	POST /proofs                      proof_with_public_inputs JSON, returns the job
+//	GET  /proofs/{id}                 the job and its status
+//	GET  /proofs/{id}/proof           the groth16 proof, compressed, once done
+//	GET  /proofs/{id}/public-witness  the public witness, once done
+//	GET  /healthz                     the curve, circuit and queue length
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/zilong-dai/groth16-worker/verifier"
+	"github.com/zilong-dai/groth16-worker/worker"
+)
+
+// DefaultQueueSize is the number of jobs waiting for a prover when
+// Options.QueueSize is zero.
+const DefaultQueueSize = 64
+
+// DefaultMaxBodySize bounds a submitted proof when Options.MaxBodySize is
+// zero.
+const DefaultMaxBodySize = 64 << 20
+
+// DefaultMaxFinishedJobs is the number of finished jobs kept when
+// Options.MaxFinishedJobs is zero.
+const DefaultMaxFinishedJobs = 1024
+
+// maxAttempts is the number of times a job can be cut off by a restart before
+// it fails instead of being queued again, so a job crashing the process
+// doesn't do it forever.
+const maxAttempts = 3
+
+// minExpireInterval bounds how often finished jobs are checked for expiry.
+const minExpireInterval = time.Second
+
+var (
+	ErrQueueFull  = errors.New("job queue is full")
+	ErrUnknownJob = errors.New("unknown job")
+)
+
+type Status string
+
+const (
+	StatusQueued Status = "queued"
+	// StatusSolving is a job whose witness is being generated.
+	StatusSolving Status = "solving"
+	// StatusProving is a job whose groth16 proof is being computed.
+	StatusProving Status = "proving"
+	StatusDone    Status = "done"
+	StatusFailed  Status = "failed"
+)
+
+// Job is a submitted proof. PublicInputs are the plonky2 public inputs
+// decoded from the public witness, set once it is done. Attempts counts the
+// times it started running. Hash is the worker.Plonky2Inputs.ProofHash of
+// the proof, duplicates are submissions with the same one.
+type Job struct {
+	ID           string     `json:"id"`
+	Status       Status     `json:"status"`
+	Created      time.Time  `json:"created"`
+	Started      *time.Time `json:"started,omitempty"`
+	Finished     *time.Time `json:"finished,omitempty"`
+	PublicInputs []uint64   `json:"public_inputs,omitempty"`
+	Error        string     `json:"error,omitempty"`
+	Attempts     int        `json:"attempts,omitempty"`
+	Hash         string     `json:"hash"`
+
+	input         []byte
+	proof         []byte
+	publicWitness []byte
+}
+
+// Proof returns the groth16 proof of a done job, in the encoding of
+// Groth16Prover.WriteProof.
+func (j *Job) Proof() []byte {
+	return j.proof
+}
+
+// PublicWitness returns the public witness of a done job, in the encoding of
+// Groth16Prover.WritePublicInputs.
+func (j *Job) PublicWitness() []byte {
+	return j.publicWitness
+}
+
+type Options struct {
+	// Curve of the prover, reported by /healthz.
+	Curve ecc.ID
+	// Inputs holds the common and verifier-only circuit data of the loaded
+	// circuit, the proof in it is ignored. Submitted proofs are checked
+	// against it before they are queued.
+	Inputs *worker.Plonky2Inputs
+	// Workers is the number of jobs proven at the same time, usually the
+	// prover's ProverLimits.Slots(). Zero means one.
+	Workers     int
+	QueueSize   int
+	MaxBodySize int64
+	// Store keeps the jobs on disk. New loads its jobs and queues again the
+	// ones that were cut off, or marks them done if their proof was stored.
+	// Nil keeps jobs in memory only. It isn't closed by Close.
+	Store *Store
+	// ResultTTL is how long a finished job is kept, after which it is
+	// removed, from the store too, and its proof is proven again if it is
+	// submitted again. Zero keeps jobs until MaxFinishedJobs removes them.
+	ResultTTL time.Duration
+	// MaxFinishedJobs is the number of finished jobs kept, the ones that
+	// finished first are removed beyond it, from the store too. Zero means
+	// DefaultMaxFinishedJobs.
+	MaxFinishedJobs int
+	// ErrorLog logs the errors of background work, such as storing the new
+	// status of a job or removing expired jobs from the store. Nil logs with
+	// the log package's standard logger.
+	ErrorLog *log.Logger
+}
+
+// Prover proves plonky2 proofs, it is implemented by *worker.Groth16Prover
+// with its circuit data set. It must be safe for concurrent use.
+type Prover interface {
+	ProvePlonky2(ctx context.Context, proofWithPublicInputs []byte) (*worker.Groth16Proof, error)
+}
+
+// Server is an http.Handler proving submitted jobs with a single prover.
+type Server struct {
+	prover      Prover
+	inputs      *worker.Plonky2Inputs
+	curve       ecc.ID
+	fingerprint string
+	maxBodySize int64
+	store       *Store
+	resultTTL   time.Duration
+	maxFinished int
+	errorLog    *log.Logger
+
+	mux   *http.ServeMux
+	queue chan *Job
+
+	// mu guards the jobs and their watchers. The store is only written
+	// without it, by the goroutine that changed the job.
+	mu       sync.Mutex
+	jobs     map[string]*Job
+	byHash   map[string]*Job
+	watchers map[string][]*watcher
+	// finished are the finished jobs in the order they finished, once their
+	// final record is stored.
+	finished []*Job
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New starts the workers of a server proving with prover, which must have
+// its circuit, proving key and circuit data loaded. Close stops them.
+func New(prover Prover, opts Options) (*Server, error) {
+	if opts.Inputs == nil {
+		return nil, fmt.Errorf("circuit data is not set")
+	}
+	fingerprint, err := opts.Inputs.Fingerprint()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fingerprint circuit: %w", err)
+	}
+
+	queueSize := opts.QueueSize
+	if queueSize <= 0 {
+		queueSize = DefaultQueueSize
+	}
+	maxBodySize := opts.MaxBodySize
+	if maxBodySize <= 0 {
+		maxBodySize = DefaultMaxBodySize
+	}
+	maxFinished := opts.MaxFinishedJobs
+	if maxFinished <= 0 {
+		maxFinished = DefaultMaxFinishedJobs
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Server{
+		prover:      prover,
+		inputs:      opts.Inputs,
+		curve:       opts.Curve,
+		fingerprint: fingerprint,
+		maxBodySize: maxBodySize,
+		store:       opts.Store,
+		resultTTL:   opts.ResultTTL,
+		maxFinished: maxFinished,
+		errorLog:    opts.ErrorLog,
+		mux:         http.NewServeMux(),
+		jobs:        make(map[string]*Job),
+		byHash:      make(map[string]*Job),
+		watchers:    make(map[string][]*watcher),
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+
+	var pending []*Job
+	if s.store != nil {
+		if pending, err = s.recoverJobs(); err != nil {
+			cancel()
+			return nil, err
+		}
+	}
+	s.queue = make(chan *Job, queueSize+len(pending))
+	for _, job := range pending {
+		s.queue <- job
+	}
+
+	s.mux.HandleFunc("POST /proofs", s.handleSubmit)
+	s.mux.HandleFunc("GET /proofs/{id}", s.handleJob)
+	s.mux.HandleFunc("GET /proofs/{id}/proof", s.handleProof)
+	s.mux.HandleFunc("GET /proofs/{id}/public-witness", s.handlePublicWitness)
+	s.mux.HandleFunc("GET /healthz", s.handleHealth)
+
+	for i := 0; i < max(1, opts.Workers); i++ {
+		s.wg.Add(1)
+		go s.work()
+	}
+	if s.resultTTL > 0 {
+		s.wg.Add(1)
+		go s.expire()
+	}
+
+	return s, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Close cancels the running jobs and waits for the workers to return, then
+// for the proofs gnark keeps computing after their cancellation, see
+// worker.Wait. Jobs still queued are left queued. With a store, running jobs
+// are left as they are too, so they are queued again when a server is
+// started on it.
+func (s *Server) Close() error {
+	s.cancel()
+	s.wg.Wait()
+	return worker.Wait(context.Background())
+}
+
+// Submit checks proofWithPublicInputs against the circuit and queues it.
+// Bad inputs are returned as *worker.InputError. If the same proof already
+// has a job that hasn't failed or expired, that job is returned instead.
+func (s *Server) Submit(proofWithPublicInputs []byte) (Job, error) {
+	inputs := s.inputs.WithProof(proofWithPublicInputs)
+	if _, err := inputs.ReadProofWithPublicInputs(); err != nil {
+		return Job{}, err
+	}
+	hash, err := inputs.ProofHash()
+	if err != nil {
+		return Job{}, err
+	}
+
+	// checked before the job is stored too, so duplicates and refused jobs
+	// aren't written
+	if duplicate, ok, err := s.admit(hash); ok || err != nil {
+		return duplicate, err
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		return Job{}, err
+	}
+	job := &Job{ID: id, Status: StatusQueued, Created: time.Now().UTC(), Hash: hash, input: proofWithPublicInputs}
+
+	if s.store != nil {
+		if err := s.store.add(job); err != nil {
+			return Job{}, err
+		}
+	}
+
+	queued, result, err := s.enqueue(job)
+	if !queued {
+		s.remove(job)
+	}
+	return result, err
+}
+
+// admit returns the job of the proof with hash, if there is one, or
+// ErrQueueFull if a new job can't be queued.
+func (s *Server) admit(hash string) (Job, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if duplicate, ok := s.duplicate(hash); ok {
+		return *duplicate, true, nil
+	}
+	if len(s.queue) == cap(s.queue) {
+		return Job{}, false, ErrQueueFull
+	}
+	return Job{}, false, nil
+}
+
+// enqueue queues job and returns it, unless a job of the same proof was
+// queued since admit, which is returned instead, or the queue filled up.
+func (s *Server) enqueue(job *Job) (bool, Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if duplicate, ok := s.duplicate(job.Hash); ok {
+		return false, *duplicate, nil
+	}
+	// only senders hold s.mu, so the queue can't fill up after this check
+	if len(s.queue) == cap(s.queue) {
+		return false, Job{}, ErrQueueFull
+	}
+	s.queue <- job
+	s.jobs[job.ID] = job
+	s.byHash[job.Hash] = job
+
+	return true, *job, nil
+}
+
+// duplicate returns the job of the proof with hash, unless it failed or
+// expired. s.mu must be held.
+func (s *Server) duplicate(hash string) (*Job, bool) {
+	job, ok := s.byHash[hash]
+	if !ok || job.Status == StatusFailed || s.expired(job, time.Now()) {
+		return nil, false
+	}
+	return job, true
+}
+
+// expired reports whether job finished more than ResultTTL before now.
+func (s *Server) expired(job *Job, now time.Time) bool {
+	return s.resultTTL > 0 && job.Done() && job.Finished != nil && now.Sub(*job.Finished) > s.resultTTL
+}
+
+// expire removes expired jobs until the server is closed.
+func (s *Server) expire() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(max(s.resultTTL, minExpireInterval))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			s.removeFinished(nil)
+		}
+	}
+}
+
+// removeFinished adds job, if set, to the finished jobs, then forgets the
+// ones that expired or are beyond MaxFinishedJobs and removes them from the
+// store.
+func (s *Server) removeFinished(job *Job) {
+	s.mu.Lock()
+	if job != nil {
+		s.finished = append(s.finished, job)
+	}
+	removed := s.popFinished()
+	s.mu.Unlock()
+
+	for _, job := range removed {
+		s.remove(job)
+	}
+}
+
+// popFinished forgets the finished jobs that expired or are beyond
+// MaxFinishedJobs and returns them. s.mu must be held.
+func (s *Server) popFinished() []*Job {
+	var removed []*Job
+
+	now := time.Now()
+	for len(s.finished) > 0 && (len(s.finished) > s.maxFinished || s.expired(s.finished[0], now)) {
+		removed = append(removed, s.finished[0])
+		s.forget(s.finished[0])
+		s.finished = s.finished[1:]
+	}
+
+	return removed
+}
+
+// remove removes job from the store, if there is one.
+func (s *Server) remove(job *Job) {
+	if s.store == nil {
+		return
+	}
+	if err := s.store.remove(job.ID); err != nil {
+		s.logf("%v", err)
+	}
+}
+
+// forget removes job from the server, but not from the store. s.mu must be
+// held.
+func (s *Server) forget(job *Job) {
+	delete(s.jobs, job.ID)
+	if s.byHash[job.Hash] == job {
+		delete(s.byHash, job.Hash)
+	}
+}
+
+func (s *Server) logf(format string, args ...any) {
+	if s.errorLog != nil {
+		s.errorLog.Printf(format, args...)
+	} else {
+		log.Printf(format, args...)
+	}
+}
+
+// Job returns a copy of the job with id.
+func (s *Server) Job(id string) (Job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+func newJobID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("failed to generate job id: %w", err)
+	}
+	return hex.EncodeToString(id), nil
+}
+
+func (s *Server) work() {
+	defer s.wg.Done()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case job := <-s.queue:
+			s.run(job)
+		}
+	}
+}
+
+func (s *Server) run(job *Job) {
+	s.update(job, func() {
+		now := time.Now().UTC()
+		job.Status, job.Started = StatusSolving, &now
+		job.Attempts++
+	})
+
+	ctx := worker.WithObserver(s.ctx, worker.ObserverFunc(func(e worker.Event) {
+		if e.Phase == worker.PhaseProve && e.Kind == worker.PhaseStarted {
+			s.update(job, func() {
+				if job.Status == StatusSolving {
+					job.Status = StatusProving
+				}
+			})
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.publish(job, &e)
+	}))
+	proof, publicWitness, publicInputs, err := s.prove(ctx, job.input)
+	if s.store != nil && s.ctx.Err() != nil {
+		// cut off by Close, the next server on the store queues it again
+		return
+	}
+	if err == nil && s.store != nil {
+		err = s.store.saveResult(job.ID, proof, publicWitness)
+	}
+
+	s.update(job, func() {
+		now := time.Now().UTC()
+		job.Finished = &now
+		job.input = nil
+		if err != nil {
+			job.Status, job.Error = StatusFailed, err.Error()
+			return
+		}
+		job.Status = StatusDone
+		job.proof, job.publicWitness, job.PublicInputs = proof, publicWitness, publicInputs
+	})
+	s.removeFinished(job)
+}
+
+// update changes job with fn, tells its watchers, then stores it. Only the
+// goroutine running the job updates it, so its records are stored in order.
+func (s *Server) update(job *Job, fn func()) {
+	s.mu.Lock()
+	fn()
+	s.publish(job, nil)
+	record := *job
+	s.mu.Unlock()
+
+	s.save(&record)
+}
+
+// save stores the record of job. Failing to is logged but not fatal: a job
+// whose record is behind is queued again after a restart, or resumed from
+// its stored proof.
+func (s *Server) save(job *Job) {
+	if s.store == nil {
+		return
+	}
+	if err := s.store.save(job); err != nil {
+		s.logf("%v", err)
+	}
+}
+
+// recoverJobs loads the jobs of the store and returns the ones to queue.
+// Jobs cut off after their proof was stored are done, others are queued
+// again until they have been cut off maxAttempts times. Finished jobs are
+// removed if they expired or are beyond MaxFinishedJobs. The store must have
+// been written for the circuit of the server.
+func (s *Server) recoverJobs() ([]*Job, error) {
+	if err := s.store.bind(s.fingerprint, s.curve); err != nil {
+		return nil, err
+	}
+	jobs, err := s.store.load()
+	if err != nil {
+		return nil, err
+	}
+
+	var pending, finished []*Job
+	for _, job := range jobs {
+		if !job.Done() {
+			now := time.Now().UTC()
+			publicInputs, resumeErr := s.decodePublicInputs(job.publicWitness)
+			switch {
+			case job.proof != nil && resumeErr == nil:
+				job.Status, job.Finished, job.PublicInputs = StatusDone, &now, publicInputs
+			case job.input == nil:
+				job.Status, job.Finished, job.Error = StatusFailed, &now, "the stored input is missing"
+			case job.Attempts >= maxAttempts:
+				job.Status, job.Finished = StatusFailed, &now
+				job.Error = fmt.Sprintf("cut off by a restart %d times", job.Attempts)
+			default:
+				job.Status, job.Started = StatusQueued, nil
+				pending = append(pending, job)
+			}
+			if job.Done() {
+				job.input = nil
+			}
+			if err := s.store.save(job); err != nil {
+				return nil, err
+			}
+		}
+
+		if job.Done() {
+			if job.Finished == nil {
+				job.Finished = &job.Created
+			}
+			finished = append(finished, job)
+		}
+		s.jobs[job.ID] = job
+		if job.Hash != "" {
+			s.byHash[job.Hash] = job
+		}
+	}
+
+	slices.SortStableFunc(finished, func(a, b *Job) int {
+		return a.Finished.Compare(*b.Finished)
+	})
+	s.finished = finished
+	for _, job := range s.popFinished() {
+		if err := s.store.remove(job.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	return pending, nil
+}
+
+// decodePublicInputs decodes the plonky2 public inputs of a stored public
+// witness.
+func (s *Server) decodePublicInputs(publicWitness []byte) ([]uint64, error) {
+	if publicWitness == nil {
+		return nil, fmt.Errorf("no public witness")
+	}
+	decoded, err := witness.New(s.curve.ScalarField())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := decoded.ReadFrom(bytes.NewReader(publicWitness)); err != nil {
+		return nil, err
+	}
+
+	return verifier.DecodePublicInputs(decoded)
+}
+
+func (s *Server) prove(ctx context.Context, input []byte) (proof, publicWitness []byte, publicInputs []uint64, err error) {
+	result, err := s.prover.ProvePlonky2(ctx, input)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// the encodings of Groth16Prover.WriteProof and WritePublicInputs
+	var proofBuf, publicWitnessBuf bytes.Buffer
+	if _, err := result.Proof.WriteTo(&proofBuf); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to encode proof: %w", err)
+	}
+	if _, err := result.PublicWitness.WriteTo(&publicWitnessBuf); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to encode public witness: %w", err)
+	}
+	if publicInputs, err = verifier.DecodePublicInputs(result.PublicWitness); err != nil {
+		return nil, nil, nil, err
+	}
+
+	return proofBuf.Bytes(), publicWitnessBuf.Bytes(), publicInputs, nil
+}
+
+func (s *Server) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.maxBodySize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, err)
+			return
+		}
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	job, err := s.Submit(body)
+	if err != nil {
+		var inputErr *worker.InputError
+		switch {
+		case errors.As(err, &inputErr):
+			writeError(w, http.StatusBadRequest, err)
+		case errors.Is(err, ErrQueueFull):
+			writeError(w, http.StatusServiceUnavailable, err)
+		default:
+			writeError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	w.Header().Set("Location", "/proofs/"+job.ID)
+	if job.Status == StatusDone {
+		writeJSON(w, http.StatusOK, job)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+func (s *Server) handleJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.Job(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no job %s", r.PathValue("id")))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, job)
+}
+
+func (s *Server) handleProof(w http.ResponseWriter, r *http.Request) {
+	s.writeResult(w, r.PathValue("id"), func(job Job) []byte { return job.proof })
+}
+
+func (s *Server) handlePublicWitness(w http.ResponseWriter, r *http.Request) {
+	s.writeResult(w, r.PathValue("id"), func(job Job) []byte { return job.publicWitness })
+}
+
+// writeResult writes a binary result of a finished job, or why there is none.
+func (s *Server) writeResult(w http.ResponseWriter, id string, result func(Job) []byte) {
+	job, ok := s.Job(id)
+	switch {
+	case !ok:
+		writeError(w, http.StatusNotFound, fmt.Errorf("no job %s", id))
+	case job.Status == StatusFailed:
+		writeError(w, http.StatusConflict, fmt.Errorf("job %s failed: %s", id, job.Error))
+	case job.Status != StatusDone:
+		writeError(w, http.StatusConflict, fmt.Errorf("job %s is %s", id, job.Status))
+	default:
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.WriteHeader(http.StatusOK)
+		w.Write(result(job))
+	}
+}
+
+type health struct {
+	Curve       string `json:"curve"`
+	Fingerprint string `json:"fingerprint"`
+	Queued      int    `json:"queued"`
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, health{
+		Curve:       s.curve.String(),
+		Fingerprint: s.fingerprint,
+		Queued:      len(s.queue),
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, struct {
+		Error string `json:"error"`
+	}{err.Error()})
+}
@@ -0,0 +1,607 @@
+package server_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/zilong-dai/groth16-worker/server"
+	"github.com/zilong-dai/groth16-worker/verifier"
+	"github.com/zilong-dai/groth16-worker/worker"
+)
+
+type cubicCircuit struct {
+	X frontend.Variable `gnark:"x"`
+	Y frontend.Variable `gnark:",public"`
+}
+
+func (c *cubicCircuit) Define(api frontend.API) error {
+	x3 := api.Mul(c.X, c.X, c.X)
+	api.AssertIsEqual(c.Y, api.Add(x3, c.X, 5))
+	return nil
+}
+
+// cubicProver stands in for a prover of the plonky2 verifier circuit, which
+// is too large to set up in a test: it proves the same cubic witness
+// whatever it is given. It waits for release, if set, and fails with err, if
+// set.
+type cubicProver struct {
+	prover  *worker.Groth16Prover
+	witness witness.Witness
+	release chan struct{}
+	err     error
+}
+
+func (p *cubicProver) ProvePlonky2(ctx context.Context, proofWithPublicInputs []byte) (*worker.Groth16Proof, error) {
+	if p.release != nil {
+		select {
+		case <-p.release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return p.prover.ProveWitness(ctx, p.witness)
+}
+
+func newCubicProver(t *testing.T) (*cubicProver, groth16.VerifyingKey) {
+	t.Helper()
+
+	ccs, err := frontend.Compile(ecc.BLS12_381.ScalarField(), r1cs.NewBuilder, &cubicCircuit{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk, vk, err := groth16.Setup(ccs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	circuitPath, pkPath := filepath.Join(dir, "circuit"), filepath.Join(dir, "proving.key")
+	for path, from := range map[string]io.WriterTo{circuitPath: ccs, pkPath: pk} {
+		file, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := from.WriteTo(file); err != nil {
+			t.Fatal(err)
+		}
+		file.Close()
+	}
+
+	t.Setenv(worker.EnvCacheDir, "")
+	prover, err := worker.NewGroth16Prover(dir, ecc.BLS12_381)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := prover.ReadCircuit(circuitPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := prover.ReadProvingKey(pkPath); err != nil {
+		t.Fatal(err)
+	}
+
+	full, err := frontend.NewWitness(&cubicCircuit{X: 3, Y: 35}, ecc.BLS12_381.ScalarField())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &cubicProver{prover: prover, witness: full}, vk
+}
+
+func newServer(t *testing.T, prover server.Prover, opts server.Options) (*httptest.Server, *server.Server, *worker.Plonky2Inputs) {
+	t.Helper()
+
+	inputs, err := worker.NewPlonky2InputsFromDir("../testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.Curve, opts.Inputs = ecc.BLS12_381, inputs
+
+	srv, err := server.New(prover, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(srv)
+	t.Cleanup(func() {
+		ts.Close()
+		srv.Close()
+	})
+
+	return ts, srv, inputs
+}
+
+func submit(t *testing.T, ts *httptest.Server, body []byte) (*http.Response, server.Job) {
+	t.Helper()
+
+	resp, err := http.Post(ts.URL+"/proofs", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var job server.Job
+	if resp.StatusCode == http.StatusAccepted || resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(&job); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp, job
+}
+
+// variant returns the test proof with its first public input set to n, so it
+// isn't a duplicate of the other variants.
+func variant(t *testing.T, inputs *worker.Plonky2Inputs, n int) []byte {
+	t.Helper()
+
+	var proof map[string]any
+	if err := json.Unmarshal(inputs.ProofWithPublicInputs, &proof); err != nil {
+		t.Fatal(err)
+	}
+	proof["public_inputs"].([]any)[0] = n
+	body, err := json.Marshal(proof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+// waitFor polls the job until it has status.
+func waitFor(t *testing.T, ts *httptest.Server, id string, status server.Status) server.Job {
+	t.Helper()
+	return waitForWithin(t, ts, id, status, 30*time.Second)
+}
+
+// waitForWithin is waitFor with a deadline of timeout.
+func waitForWithin(t *testing.T, ts *httptest.Server, id string, status server.Status, timeout time.Duration) server.Job {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for {
+		resp, err := http.Get(ts.URL + "/proofs/" + id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var job server.Job
+		err = json.NewDecoder(resp.Body).Decode(&job)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if job.Status == status {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s is still %s, expected %s", id, job.Status, status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func get(t *testing.T, url string) (int, []byte) {
+	t.Helper()
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, body
+}
+
+func TestServer(t *testing.T) {
+	prover, vk := newCubicProver(t)
+	ts, _, inputs := newServer(t, prover, server.Options{})
+
+	resp, job := submit(t, ts, inputs.ProofWithPublicInputs)
+	if resp.StatusCode != http.StatusAccepted || job.ID == "" || resp.Header.Get("Location") != "/proofs/"+job.ID {
+		t.Fatalf("expected an accepted job, got %d %+v", resp.StatusCode, job)
+	}
+
+	job = waitFor(t, ts, job.ID, server.StatusDone)
+	if len(job.PublicInputs) != 1 || job.PublicInputs[0] != 35 || job.Started == nil || job.Finished == nil {
+		t.Fatalf("unexpected finished job %+v", job)
+	}
+
+	code, proofBytes := get(t, ts.URL+"/proofs/"+job.ID+"/proof")
+	if code != http.StatusOK {
+		t.Fatalf("expected the proof, got %d: %s", code, proofBytes)
+	}
+	code, publicWitnessBytes := get(t, ts.URL+"/proofs/"+job.ID+"/public-witness")
+	if code != http.StatusOK {
+		t.Fatalf("expected the public witness, got %d: %s", code, publicWitnessBytes)
+	}
+
+	proof := groth16.NewProof(ecc.BLS12_381)
+	if _, err := proof.ReadFrom(bytes.NewReader(proofBytes)); err != nil {
+		t.Fatal(err)
+	}
+	publicWitness, err := witness.New(ecc.BLS12_381.ScalarField())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := publicWitness.UnmarshalBinary(publicWitnessBytes); err != nil {
+		t.Fatal(err)
+	}
+	if err := groth16.Verify(proof, vk, publicWitness); err != nil {
+		t.Fatalf("expected the served proof to verify: %v", err)
+	}
+
+	if resp, _ := submit(t, ts, []byte(`{"proof": {}}`)); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected a bad request for a malformed proof, got %d", resp.StatusCode)
+	}
+	if code, _ := get(t, ts.URL+"/proofs/unknown"); code != http.StatusNotFound {
+		t.Fatalf("expected an unknown job to be not found, got %d", code)
+	}
+	if code, body := get(t, ts.URL+"/healthz"); code != http.StatusOK || !bytes.Contains(body, []byte(`"curve":"bls12_381"`)) {
+		t.Fatalf("unexpected health %d: %s", code, body)
+	}
+}
+
+func TestServerPlonky2(t *testing.T) {
+	if testing.Short() {
+		t.Skip("sets up the plonky2 verifier circuit")
+	}
+
+	// the keys are set up with the proof in testdata, the server proves another one
+	t.Setenv(worker.EnvCacheDir, "")
+	prover, err := worker.NewGroth16Prover("../testdata", ecc.BLS12_381)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := prover.Setup(); err != nil {
+		t.Fatal(err)
+	}
+	vkPath := filepath.Join(t.TempDir(), "verifying.key")
+	if err := prover.WriteVerifyingKey(vkPath); err != nil {
+		t.Fatal(err)
+	}
+	ts, _, inputs := newServer(t, prover, server.Options{})
+
+	other, err := os.ReadFile(filepath.Join("../testdata/groth16", worker.ProofWithPublicInputsFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(other, inputs.ProofWithPublicInputs) {
+		t.Fatal("expected another proof than the one of the setup")
+	}
+
+	resp, job := submit(t, ts, other)
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected an accepted job, got %d", resp.StatusCode)
+	}
+	job = waitForWithin(t, ts, job.ID, server.StatusDone, time.Hour)
+	if want := []uint64{6129017628557890706, 6129017628557890706}; !slices.Equal(job.PublicInputs, want) {
+		t.Fatalf("expected public inputs %v, got %v", want, job.PublicInputs)
+	}
+
+	code, proofBytes := get(t, ts.URL+"/proofs/"+job.ID+"/proof")
+	if code != http.StatusOK {
+		t.Fatalf("expected the proof, got %d: %s", code, proofBytes)
+	}
+	code, publicWitnessBytes := get(t, ts.URL+"/proofs/"+job.ID+"/public-witness")
+	if code != http.StatusOK {
+		t.Fatalf("expected the public witness, got %d: %s", code, publicWitnessBytes)
+	}
+	groth16Verifier, err := verifier.NewGroth16VerifierFromFile(vkPath, ecc.BLS12_381)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := groth16Verifier.ReadProofFrom(bytes.NewReader(proofBytes)); err != nil {
+		t.Fatal(err)
+	}
+	if err := groth16Verifier.ReadPublicInputsFrom(bytes.NewReader(publicWitnessBytes)); err != nil {
+		t.Fatal(err)
+	}
+	if err := groth16Verifier.Verify(); err != nil {
+		t.Fatalf("expected the served proof to verify: %v", err)
+	}
+
+	// a tampered proof with the same public inputs fails instead of being proven
+	tampered := bytes.Replace(other, []byte(`"pow_witness":720575940211511267`), []byte(`"pow_witness":720575940211511268`), 1)
+	if bytes.Equal(tampered, other) {
+		t.Fatal("expected the proof to be tampered with")
+	}
+	_, job = submit(t, ts, tampered)
+	waitForWithin(t, ts, job.ID, server.StatusFailed, time.Hour)
+}
+
+func TestServerFailedJob(t *testing.T) {
+	prover, _ := newCubicProver(t)
+	prover.err = errors.New("out of memory")
+	ts, _, inputs := newServer(t, prover, server.Options{})
+
+	_, job := submit(t, ts, inputs.ProofWithPublicInputs)
+	job = waitFor(t, ts, job.ID, server.StatusFailed)
+	if job.Error != "out of memory" {
+		t.Fatalf("expected the prover error, got %q", job.Error)
+	}
+	if code, _ := get(t, ts.URL+"/proofs/"+job.ID+"/proof"); code != http.StatusConflict {
+		t.Fatalf("expected no proof for a failed job, got %d", code)
+	}
+}
+
+func TestServerQueueFull(t *testing.T) {
+	prover, _ := newCubicProver(t)
+	prover.release = make(chan struct{})
+	ts, _, inputs := newServer(t, prover, server.Options{Workers: 1, QueueSize: 1})
+	defer close(prover.release)
+
+	_, running := submit(t, ts, variant(t, inputs, 1))
+	waitFor(t, ts, running.ID, server.StatusSolving)
+
+	resp, queued := submit(t, ts, variant(t, inputs, 2))
+	if resp.StatusCode != http.StatusAccepted || queued.Status != server.StatusQueued {
+		t.Fatalf("expected a queued job, got %d %+v", resp.StatusCode, queued)
+	}
+	if code, _ := get(t, ts.URL+"/proofs/"+queued.ID+"/proof"); code != http.StatusConflict {
+		t.Fatalf("expected no proof for a queued job, got %d", code)
+	}
+
+	if resp, _ := submit(t, ts, variant(t, inputs, 3)); resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected a full queue to refuse jobs, got %d", resp.StatusCode)
+	}
+}
+
+func TestServerWatch(t *testing.T) {
+	prover, _ := newCubicProver(t)
+	prover.release = make(chan struct{})
+	_, srv, inputs := newServer(t, prover, server.Options{})
+
+	if _, err := srv.Watch(context.Background(), "unknown"); !errors.Is(err, server.ErrUnknownJob) {
+		t.Fatalf("expected an unknown job, got %v", err)
+	}
+
+	job, err := srv.Submit(inputs.ProofWithPublicInputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, err := srv.Watch(context.Background(), job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	close(prover.release)
+
+	var statuses []server.Status
+	phases := make(map[worker.Phase]bool)
+	for event := range events {
+		if event.Phase != nil {
+			phases[event.Phase.Phase] = true
+			continue
+		}
+		if n := len(statuses); n == 0 || statuses[n-1] != event.Job.Status {
+			statuses = append(statuses, event.Job.Status)
+		}
+	}
+
+	// the job may have left the queue before Watch
+	want := []server.Status{server.StatusSolving, server.StatusProving, server.StatusDone}
+	if len(statuses) < len(want) || !slices.Equal(statuses[len(statuses)-len(want):], want) {
+		t.Fatalf("expected the job to be solved, proven and done, got %v", statuses)
+	}
+	if !phases[worker.PhaseProve] {
+		t.Fatalf("expected the prove phase of the job, got %v", phases)
+	}
+
+	// a finished job only has its final state
+	events, err = srv.Watch(context.Background(), job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var last []server.Event
+	for event := range events {
+		last = append(last, event)
+	}
+	if len(last) != 1 || last[0].Job.Status != server.StatusDone {
+		t.Fatalf("expected only the final state of a finished job, got %+v", last)
+	}
+}
+
+func TestServerStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := server.OpenStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prover, _ := newCubicProver(t)
+	prover.release = make(chan struct{})
+	ts, srv, inputs := newServer(t, prover, server.Options{Workers: 1, Store: store})
+
+	_, done := submit(t, ts, variant(t, inputs, 1))
+	_, cutOff := submit(t, ts, variant(t, inputs, 2))
+	prover.release <- struct{}{}
+	waitFor(t, ts, done.ID, server.StatusDone)
+	waitFor(t, ts, cutOff.ID, server.StatusSolving)
+
+	// a restart keeps the finished job and queues the cut off one again
+	srv.Close()
+	ts.Close()
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if store, err = server.OpenStore(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	// the jobs were proven on another curve than this server's
+	if _, err := server.New(prover, server.Options{Curve: ecc.BN254, Inputs: inputs, Store: store}); !errors.Is(err, worker.ErrFingerprintMismatch) {
+		t.Fatalf("expected a store of another circuit to be refused, got %v", err)
+	}
+
+	prover.release = nil
+	ts, _, _ = newServer(t, prover, server.Options{Store: store})
+
+	job := waitFor(t, ts, done.ID, server.StatusDone)
+	if len(job.PublicInputs) != 1 || job.Attempts != 1 {
+		t.Fatalf("unexpected stored job %+v", job)
+	}
+	if code, _ := get(t, ts.URL+"/proofs/"+done.ID+"/proof"); code != http.StatusOK {
+		t.Fatalf("expected the stored proof, got %d", code)
+	}
+
+	job = waitFor(t, ts, cutOff.ID, server.StatusDone)
+	if job.Attempts != 2 || len(job.PublicInputs) != 1 {
+		t.Fatalf("expected the cut off job to be proven again, got %+v", job)
+	}
+	for _, name := range []string{"proof_with_public_inputs.json", "proof", "public_witness"} {
+		if _, err := os.Stat(filepath.Join(dir, "jobs", cutOff.ID, name)); err != nil {
+			t.Fatalf("expected the job files in the store: %v", err)
+		}
+	}
+}
+
+func TestServerDuplicates(t *testing.T) {
+	prover, _ := newCubicProver(t)
+	prover.release = make(chan struct{})
+	ts, _, inputs := newServer(t, prover, server.Options{})
+
+	resp, job := submit(t, ts, inputs.ProofWithPublicInputs)
+	if resp.StatusCode != http.StatusAccepted || job.Hash == "" {
+		t.Fatalf("expected an accepted job with a hash, got %d %+v", resp.StatusCode, job)
+	}
+
+	// the same proof, formatted differently, attaches to the queued job
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, inputs.ProofWithPublicInputs, "", "  "); err != nil {
+		t.Fatal(err)
+	}
+	resp, duplicate := submit(t, ts, indented.Bytes())
+	if resp.StatusCode != http.StatusAccepted || duplicate.ID != job.ID {
+		t.Fatalf("expected the queued job for a duplicate, got %d %+v", resp.StatusCode, duplicate)
+	}
+	if _, other := submit(t, ts, variant(t, inputs, 1)); other.ID == job.ID {
+		t.Fatal("expected another proof to get its own job")
+	}
+
+	close(prover.release)
+	waitFor(t, ts, job.ID, server.StatusDone)
+	resp, duplicate = submit(t, ts, inputs.ProofWithPublicInputs)
+	if resp.StatusCode != http.StatusOK || duplicate.ID != job.ID || duplicate.Status != server.StatusDone {
+		t.Fatalf("expected the done job for a duplicate, got %d %+v", resp.StatusCode, duplicate)
+	}
+}
+
+func TestServerDuplicatesExpire(t *testing.T) {
+	dir := t.TempDir()
+	store, err := server.OpenStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	prover, _ := newCubicProver(t)
+	prover.release = make(chan struct{})
+	ts, srv, inputs := newServer(t, prover, server.Options{Store: store, ResultTTL: time.Nanosecond})
+
+	_, job := submit(t, ts, inputs.ProofWithPublicInputs)
+	events, err := srv.Watch(context.Background(), job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	close(prover.release)
+	var last server.Event
+	for last = range events {
+	}
+	if last.Job.Status != server.StatusDone {
+		t.Fatalf("expected the job to be done, got %+v", last.Job)
+	}
+
+	resp, again := submit(t, ts, inputs.ProofWithPublicInputs)
+	if resp.StatusCode != http.StatusAccepted || again.ID == job.ID {
+		t.Fatalf("expected an expired result to be proven again, got %d %+v", resp.StatusCode, again)
+	}
+
+	// the expired job is removed, from the store too
+	deadline := time.Now().Add(30 * time.Second)
+	for {
+		code, _ := get(t, ts.URL+"/proofs/"+job.ID)
+		_, statErr := os.Stat(filepath.Join(dir, "jobs", job.ID))
+		if code == http.StatusNotFound && errors.Is(statErr, os.ErrNotExist) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the expired job to be removed, got %d, %v", code, statErr)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestServerMaxFinishedJobs(t *testing.T) {
+	dir := t.TempDir()
+	store, err := server.OpenStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	prover, _ := newCubicProver(t)
+	ts, srv, inputs := newServer(t, prover, server.Options{Store: store, MaxFinishedJobs: 1})
+
+	_, first := submit(t, ts, variant(t, inputs, 1))
+	waitFor(t, ts, first.ID, server.StatusDone)
+	_, second := submit(t, ts, variant(t, inputs, 2))
+	waitFor(t, ts, second.ID, server.StatusDone)
+
+	// the first job is removed once the second one is stored as finished
+	deadline := time.Now().Add(30 * time.Second)
+	for {
+		_, ok := srv.Job(first.ID)
+		_, statErr := os.Stat(filepath.Join(dir, "jobs", first.ID))
+		if !ok && errors.Is(statErr, os.ErrNotExist) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the oldest finished job to be removed, got %v, %v", ok, statErr)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, ok := srv.Job(second.ID); !ok {
+		t.Fatal("expected the newest finished job to be kept")
+	}
+
+	// a restart only loads the job that is kept
+	srv.Close()
+	ts.Close()
+	ts, _, _ = newServer(t, prover, server.Options{Store: store, MaxFinishedJobs: 1})
+	if code, _ := get(t, ts.URL+"/proofs/"+first.ID); code != http.StatusNotFound {
+		t.Fatalf("expected the removed job to stay removed, got %d", code)
+	}
+	waitFor(t, ts, second.ID, server.StatusDone)
+}
+
+func TestServerFailedDuplicate(t *testing.T) {
+	prover, _ := newCubicProver(t)
+	prover.err = errors.New("out of memory")
+	ts, _, inputs := newServer(t, prover, server.Options{})
+
+	_, job := submit(t, ts, inputs.ProofWithPublicInputs)
+	waitFor(t, ts, job.ID, server.StatusFailed)
+
+	if _, retry := submit(t, ts, inputs.ProofWithPublicInputs); retry.ID == job.ID {
+		t.Fatal("expected a failed job to be retried by a duplicate")
+	}
+}
@@ -0,0 +1,192 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/zilong-dai/groth16-worker/artifact"
+	"github.com/zilong-dai/groth16-worker/worker"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	storeDB   = "jobs.db"
+	storeJobs = "jobs"
+
+	inputFile         = "proof_with_public_inputs.json"
+	proofFile         = "proof"
+	publicWitnessFile = "public_witness"
+)
+
+var (
+	jobsBucket    = []byte("jobs")
+	circuitBucket = []byte("circuit")
+
+	fingerprintKey = []byte("fingerprint")
+	curveKey       = []byte("curve")
+)
+
+// Store keeps jobs on disk so they survive restarts. Job records live in a
+// bolt database, jobs.db, and the files of a job in jobs/<id>: the submitted
+// proof_with_public_inputs.json, and the proof and public_witness once it is
+// done. The database also records the circuit the jobs are proven with, a
+// store can't be used for another one. Only one process can open a store at
+// a time.
+type Store struct {
+	dir string
+	db  *bolt.DB
+}
+
+// OpenStore opens the store in dir, creating it if needed.
+func OpenStore(dir string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Join(dir, storeJobs), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create job store: %w", err)
+	}
+
+	db, err := bolt.Open(filepath.Join(dir, storeDB), 0644, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open job store: %w", err)
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{jobsBucket, circuitBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open job store: %w", err)
+	}
+
+	return &Store{dir: dir, db: db}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+func (s *Store) jobDir(id string) string {
+	return filepath.Join(s.dir, storeJobs, id)
+}
+
+// bind records the circuit of a new store, or checks that the jobs of the
+// store were proven with it.
+func (s *Store) bind(fingerprint string, curve ecc.ID) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(circuitBucket)
+		storedFingerprint, storedCurve := bucket.Get(fingerprintKey), bucket.Get(curveKey)
+		if storedFingerprint == nil {
+			if err := bucket.Put(fingerprintKey, []byte(fingerprint)); err != nil {
+				return fmt.Errorf("failed to store circuit: %w", err)
+			}
+			if err := bucket.Put(curveKey, []byte(curve.String())); err != nil {
+				return fmt.Errorf("failed to store circuit: %w", err)
+			}
+			return nil
+		}
+
+		if string(storedFingerprint) != fingerprint || string(storedCurve) != curve.String() {
+			return fmt.Errorf("%w: the job store is for circuit %s on %s, not %s on %s", worker.ErrFingerprintMismatch, storedFingerprint, storedCurve, fingerprint, curve)
+		}
+		return nil
+	})
+}
+
+// save writes the record of job.
+func (s *Store) save(job *Job) error {
+	record, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	if err := s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(jobsBucket).Put([]byte(job.ID), record)
+	}); err != nil {
+		return fmt.Errorf("failed to store job %s: %w", job.ID, err)
+	}
+
+	return nil
+}
+
+// add writes the input of a new job, then its record.
+func (s *Store) add(job *Job) error {
+	if err := os.MkdirAll(s.jobDir(job.ID), 0755); err != nil {
+		return fmt.Errorf("failed to store job %s: %w", job.ID, err)
+	}
+	if err := artifact.WriteFileAtomic(filepath.Join(s.jobDir(job.ID), inputFile), job.input); err != nil {
+		return fmt.Errorf("failed to store job %s: %w", job.ID, err)
+	}
+
+	return s.save(job)
+}
+
+// remove deletes the record of a job, then its files.
+func (s *Store) remove(id string) error {
+	if err := s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(jobsBucket).Delete([]byte(id))
+	}); err != nil {
+		return fmt.Errorf("failed to remove job %s: %w", id, err)
+	}
+	if err := os.RemoveAll(s.jobDir(id)); err != nil {
+		return fmt.Errorf("failed to remove job %s: %w", id, err)
+	}
+
+	return nil
+}
+
+// saveResult writes the proof and public witness of a job. They are written
+// before the record says the job is done, so a job whose record is cut off
+// after them can be resumed from its files.
+func (s *Store) saveResult(id string, proof, publicWitness []byte) error {
+	for name, data := range map[string][]byte{proofFile: proof, publicWitnessFile: publicWitness} {
+		if err := artifact.WriteFileAtomic(filepath.Join(s.jobDir(id), name), data); err != nil {
+			return fmt.Errorf("failed to store job %s: %w", id, err)
+		}
+	}
+
+	return nil
+}
+
+// load returns the stored jobs, oldest first, with their results and, for
+// unfinished jobs, their input.
+func (s *Store) load() ([]*Job, error) {
+	var jobs []*Job
+	if err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(jobsBucket).ForEach(func(id, record []byte) error {
+			job := &Job{}
+			if err := json.Unmarshal(record, job); err != nil {
+				return fmt.Errorf("job %s: %w", id, err)
+			}
+			jobs = append(jobs, job)
+			return nil
+		})
+	}); err != nil {
+		return nil, fmt.Errorf("failed to load job store: %w", err)
+	}
+
+	for _, job := range jobs {
+		files := map[string]*[]byte{proofFile: &job.proof, publicWitnessFile: &job.publicWitness}
+		if !job.Done() {
+			files[inputFile] = &job.input
+		}
+		for name, data := range files {
+			var err error
+			if *data, err = os.ReadFile(filepath.Join(s.jobDir(job.ID), name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return nil, fmt.Errorf("failed to load job %s: %w", job.ID, err)
+			}
+		}
+	}
+
+	slices.SortFunc(jobs, func(a, b *Job) int {
+		return a.Created.Compare(b.Created)
+	})
+
+	return jobs, nil
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/zilong-dai/groth16-worker/worker"
+)
+
+// watchBuffer is the number of events a watcher can fall behind before phase
+// events are dropped for it.
+const watchBuffer = 64
+
+// Event is a change of a job: a new status, or a phase event of its proof
+// when Phase is set.
+type Event struct {
+	Job   Job
+	Phase *worker.Event
+}
+
+// Done reports whether the job has finished, successfully or not.
+func (j *Job) Done() bool {
+	return j.Status == StatusDone || j.Status == StatusFailed
+}
+
+// watcher queues the events of a job for a single Watch call. Its pump is
+// the only sender on events, so events stay in order and are closed once.
+type watcher struct {
+	events chan Event
+	wake   chan struct{}
+
+	mu      sync.Mutex
+	pending []Event
+	last    bool
+}
+
+// Watch returns the events of the job with id, starting with its current
+// state. The channel is closed after the event of the job finishing, or once
+// ctx is done. Phase events are dropped for watchers that fall behind, status
+// changes never are.
+func (s *Server) Watch(ctx context.Context, id string) (<-chan Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownJob, id)
+	}
+
+	w := &watcher{events: make(chan Event), wake: make(chan struct{}, 1)}
+	w.push(Event{Job: *job})
+	if !job.Done() {
+		s.watchers[id] = append(s.watchers[id], w)
+	}
+
+	go w.pump(ctx, func() { s.unwatch(id, w) })
+
+	return w.events, nil
+}
+
+func (w *watcher) push(event Event) {
+	w.mu.Lock()
+	if event.Phase == nil || len(w.pending) < watchBuffer {
+		w.pending = append(w.pending, event)
+		w.last = event.Job.Done()
+	}
+	w.mu.Unlock()
+
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (w *watcher) pump(ctx context.Context, unwatch func()) {
+	defer close(w.events)
+
+	for {
+		w.mu.Lock()
+		batch, last := w.pending, w.last
+		w.pending = nil
+		w.mu.Unlock()
+
+		for _, event := range batch {
+			select {
+			case w.events <- event:
+			case <-ctx.Done():
+				unwatch()
+				return
+			}
+		}
+		if last {
+			return
+		}
+
+		select {
+		case <-w.wake:
+		case <-ctx.Done():
+			unwatch()
+			return
+		}
+	}
+}
+
+func (s *Server) unwatch(id string, w *watcher) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	watchers := s.watchers[id]
+	for i := range watchers {
+		if watchers[i] == w {
+			s.watchers[id] = append(watchers[:i], watchers[i+1:]...)
+			break
+		}
+	}
+	if len(s.watchers[id]) == 0 {
+		delete(s.watchers, id)
+	}
+}
+
+// publish sends the current state of job, and phase if set, to its
+// watchers. s.mu must be held.
+func (s *Server) publish(job *Job, phase *worker.Event) {
+	event := Event{Job: *job, Phase: phase}
+	for _, w := range s.watchers[job.ID] {
+		w.push(event)
+	}
+
+	if job.Done() {
+		delete(s.watchers, job.ID)
+	}
+}
@@ -0,0 +1,167 @@
+package verifier
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"reflect"
+
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/zilong-dai/groth16-worker/artifact"
+)
+
+// goldilocksModulus is the order of the plonky2 base field, 2^64 - 2^32 + 1.
+const goldilocksModulus uint64 = 0xFFFFFFFF00000001
+
+// Failure says why a verification failed.
+type Failure string
+
+const (
+	// FailureEncoding is a key, proof or public witness that doesn't decode,
+	// is on another curve, or public inputs that aren't Goldilocks elements.
+	FailureEncoding Failure = "encoding"
+	// FailurePublicInputCount is a public witness with more or fewer inputs
+	// than the verifying key expects.
+	FailurePublicInputCount Failure = "public_input_count"
+	// FailurePairing is a well formed proof that doesn't verify.
+	FailurePairing Failure = "pairing"
+)
+
+// Classify returns the failure err stands for, or an empty Failure if err is
+// nil or isn't a verification failure, like a missing file.
+func Classify(err error) Failure {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrPublicInputCount):
+		return FailurePublicInputCount
+	case errors.Is(err, ErrInvalidEncoding), errors.Is(err, artifact.ErrCurveMismatch):
+		return FailureEncoding
+	case errors.Is(err, ErrInvalidProof):
+		return FailurePairing
+	default:
+		return ""
+	}
+}
+
+// Report describes a verification for machines. Hashes are the sha256 of the
+// compressed encodings, so they don't depend on how the key or proof was
+// stored. PublicInputs are in the order of proof_with_public_inputs.json.
+type Report struct {
+	Valid        bool     `json:"valid"`
+	Curve        string   `json:"curve"`
+	Fingerprint  string   `json:"fingerprint,omitempty"`
+	VerifyingKey string   `json:"verifying_key_sha256,omitempty"`
+	Proof        string   `json:"proof_sha256,omitempty"`
+	PublicInputs []uint64 `json:"public_inputs,omitempty"`
+	Failure      Failure  `json:"failure,omitempty"`
+	Error        string   `json:"error,omitempty"`
+}
+
+// Fail marks the report as invalid because of err.
+func (r *Report) Fail(err error) {
+	r.Valid = false
+	r.Failure = Classify(err)
+	r.Error = err.Error()
+}
+
+// Report verifies the loaded proof like Verify and describes the outcome. The
+// error Verify would return is returned too, and is also in the report.
+func (w *Groth16Verifier) Report() (*Report, error) {
+	report := &Report{Curve: w.curveId.String(), Fingerprint: w.fingerprint}
+	if w.Vk != nil {
+		report.VerifyingKey = hashOf(w.Vk)
+	}
+	if w.Proof != nil {
+		report.Proof = hashOf(w.Proof)
+	}
+
+	if w.PublicWitness != nil {
+		publicInputs, err := DecodePublicInputs(w.PublicWitness)
+		if err != nil {
+			report.Fail(err)
+			return report, err
+		}
+		report.PublicInputs = publicInputs
+	}
+
+	if err := w.Verify(); err != nil {
+		report.Fail(err)
+		return report, err
+	}
+	report.Valid = true
+
+	return report, nil
+}
+
+func hashOf(from io.WriterTo) string {
+	h := sha256.New()
+	if _, err := from.WriteTo(h); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// DecodePublicInputs returns the public inputs of a public witness of the
+// plonky2 verifier circuit as Goldilocks elements. Each input of the plonky2
+// proof is one public variable, so they come out in the order of
+// proof_with_public_inputs.json.
+func DecodePublicInputs(publicWitness witness.Witness) ([]uint64, error) {
+	vector := reflect.ValueOf(publicWitness.Vector())
+	if vector.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("%w: public witness is not a vector", ErrInvalidEncoding)
+	}
+
+	inputs := make([]uint64, vector.Len())
+	value := new(big.Int)
+	for i := range inputs {
+		element, ok := vector.Index(i).Addr().Interface().(interface{ BigInt(*big.Int) *big.Int })
+		if !ok {
+			return nil, fmt.Errorf("%w: public witness is not a vector of field elements", ErrInvalidEncoding)
+		}
+
+		element.BigInt(value)
+		if !value.IsUint64() || value.Uint64() >= goldilocksModulus {
+			return nil, fmt.Errorf("%w: public input %d is %s, not a goldilocks element", ErrInvalidEncoding, i, value)
+		}
+		inputs[i] = value.Uint64()
+	}
+
+	return inputs, nil
+}
+
+// SetPublicInputs sets the public witness from plonky2 public inputs, the
+// inverse of DecodePublicInputs.
+func (w *Groth16Verifier) SetPublicInputs(publicInputs []uint64) error {
+	values := make(chan any, len(publicInputs))
+	for i, input := range publicInputs {
+		if input >= goldilocksModulus {
+			return fmt.Errorf("%w: public input %d is %d, not a goldilocks element", ErrInvalidEncoding, i, input)
+		}
+		values <- input
+	}
+	close(values)
+
+	publicWitness, err := witness.New(w.curveId.ScalarField())
+	if err != nil {
+		return fmt.Errorf("error creating public witness: %w", err)
+	}
+	if err := publicWitness.Fill(len(publicInputs), 0, values); err != nil {
+		return fmt.Errorf("failed to set public inputs: %w", err)
+	}
+
+	w.PublicWitness = publicWitness
+
+	return nil
+}
+
+func publicWitnessLen(publicWitness witness.Witness) int {
+	vector := reflect.ValueOf(publicWitness.Vector())
+	if vector.Kind() != reflect.Slice {
+		return 0
+	}
+	return vector.Len()
+}
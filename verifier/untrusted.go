@@ -0,0 +1,148 @@
+package verifier
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	bls12377 "github.com/consensys/gnark-crypto/ecc/bls12-377"
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	bls24315 "github.com/consensys/gnark-crypto/ecc/bls24-315"
+	bls24317 "github.com/consensys/gnark-crypto/ecc/bls24-317"
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	bw6633 "github.com/consensys/gnark-crypto/ecc/bw6-633"
+	bw6761 "github.com/consensys/gnark-crypto/ecc/bw6-761"
+	"github.com/consensys/gnark/backend/groth16"
+)
+
+// pointSizes are the compressed sizes of G1 and G2 points of each curve. An
+// uncompressed point is twice as large.
+var pointSizes = map[ecc.ID][2]int{
+	ecc.BN254:     {bn254.SizeOfG1AffineCompressed, bn254.SizeOfG2AffineCompressed},
+	ecc.BLS12_377: {bls12377.SizeOfG1AffineCompressed, bls12377.SizeOfG2AffineCompressed},
+	ecc.BLS12_381: {bls12381.SizeOfG1AffineCompressed, bls12381.SizeOfG2AffineCompressed},
+	ecc.BLS24_315: {bls24315.SizeOfG1AffineCompressed, bls24315.SizeOfG2AffineCompressed},
+	ecc.BLS24_317: {bls24317.SizeOfG1AffineCompressed, bls24317.SizeOfG2AffineCompressed},
+	ecc.BW6_761:   {bw6761.SizeOfG1AffineCompressed, bw6761.SizeOfG2AffineCompressed},
+	ecc.BW6_633:   {bw6633.SizeOfG1AffineCompressed, bw6633.SizeOfG2AffineCompressed},
+}
+
+// NewGroth16VerifierFromUntrustedBytes decodes a verifying key sent by a
+// client. Unlike NewGroth16VerifierFromBytes it only accepts gnark's
+// encodings, never key dumps, and checks the lengths in the key against its
+// size before decoding, so a few bytes can't make the decoder allocate
+// gigabytes.
+func NewGroth16VerifierFromUntrustedBytes(key []byte, curveId ecc.ID) (*Groth16Verifier, error) {
+	w, err := NewGroth16Verifier(curveId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkVerifyingKeyLengths(key, curveId); err != nil {
+		return nil, fmt.Errorf("failed to read verifying key: %w", encodingError(err))
+	}
+
+	vk := groth16.NewVerifyingKey(curveId)
+	if _, err := vk.ReadFrom(bytes.NewReader(key)); err != nil {
+		return nil, fmt.Errorf("failed to read verifying key: %w", encodingError(err))
+	}
+	w.Vk = vk
+
+	return w, nil
+}
+
+// checkVerifyingKeyLengths walks a verifying key as gnark writes it, compressed
+// or raw: [α]₁, [β]₁, [β]₂, [γ]₂, [δ]₁, [δ]₂, the [K]₁ points and the
+// committed public inputs, each prefixed by a uint32 count, then the two [G]₂
+// points of the commitment key. Every count must fit in the rest of the key.
+func checkVerifyingKeyLengths(key []byte, curveId ecc.ID) error {
+	sizes, ok := pointSizes[curveId]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnsupportedCurve, curveId)
+	}
+	g1, g2 := sizes[0], sizes[1]
+
+	k := keyWalker{key: key, curveId: curveId}
+	for _, size := range []int{g1, g1, g2, g2, g1, g2} {
+		k.point(size)
+	}
+
+	n := k.count(g1)
+	for i := 0; i < n; i++ {
+		k.point(g1)
+	}
+
+	committed := k.count(4)
+	for i := 0; i < committed; i++ {
+		k.skip(k.count(8) * 8)
+	}
+
+	k.point(g2)
+	k.point(g2)
+
+	return k.err
+}
+
+// keyWalker reads through an encoded key, keeping the first error.
+type keyWalker struct {
+	key     []byte
+	curveId ecc.ID
+	err     error
+}
+
+func (k *keyWalker) skip(n int) {
+	if k.err != nil {
+		return
+	}
+	if n > len(k.key) {
+		k.err = fmt.Errorf("key is truncated")
+		return
+	}
+	k.key = k.key[n:]
+}
+
+// point skips a point of compressed size size, twice that if the flags of its
+// first byte say it is uncompressed.
+func (k *keyWalker) point(size int) {
+	if k.err != nil {
+		return
+	}
+	if len(k.key) == 0 {
+		k.err = fmt.Errorf("key is truncated")
+		return
+	}
+	if !isCompressed(k.key[0], k.curveId) {
+		size *= 2
+	}
+	k.skip(size)
+}
+
+// count reads a uint32 count of elements of at least minSize bytes each,
+// checking they fit in the rest of the key.
+func (k *keyWalker) count(minSize int) int {
+	if k.err != nil {
+		return 0
+	}
+	if len(k.key) < 4 {
+		k.err = fmt.Errorf("key is truncated")
+		return 0
+	}
+	n := int(binary.BigEndian.Uint32(k.key))
+	k.key = k.key[4:]
+	if n > len(k.key)/minSize {
+		k.err = fmt.Errorf("key has %d elements of at least %d bytes, only %d bytes are left", n, minSize, len(k.key))
+		return 0
+	}
+	return n
+}
+
+// isCompressed reads the encoding flags in the most significant bits of a
+// point, as gnark-crypto sets them: two bits on BN254, three on the others.
+func isCompressed(msb byte, curveId ecc.ID) bool {
+	if curveId == ecc.BN254 {
+		return msb&(0b11<<6) != 0b00<<6
+	}
+	flags := msb & (0b111 << 5)
+	return flags != 0b000<<5 && flags != 0b010<<5
+}
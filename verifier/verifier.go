@@ -0,0 +1,329 @@
+// Package verifier checks groth16 proofs of the plonky2 verifier circuit
+// against a verifying key. Unlike the worker package it never compiles the
+// circuit or runs a setup, and it does not depend on the constraint compiler,
+// so it can be linked into services that only verify.
+package verifier
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/consensys/gnark"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/zilong-dai/groth16-worker/artifact"
+)
+
+var (
+	ErrUnsupportedCurve = errors.New("unsupported curve")
+	ErrInvalidProof     = errors.New("invalid proof")
+	// ErrInvalidEncoding is wrapped by errors decoding a key, proof or public
+	// witness that was read in full.
+	ErrInvalidEncoding = errors.New("invalid encoding")
+	// ErrPublicInputCount is wrapped, along with ErrInvalidProof, when the
+	// public witness doesn't have as many inputs as the verifying key.
+	ErrPublicInputCount = errors.New("wrong number of public inputs")
+)
+
+type Groth16Verifier struct {
+	curveId       ecc.ID
+	Vk            groth16.VerifyingKey
+	Proof         groth16.Proof
+	PublicWitness witness.Witness
+	fingerprint   string
+}
+
+// NewGroth16Verifier returns a verifier on curveId with an empty verifying
+// key, to be filled by ReadVerifyingKey or ReadBundle.
+func NewGroth16Verifier(curveId ecc.ID) (*Groth16Verifier, error) {
+	if !slices.Contains(gnark.Curves(), curveId) {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedCurve, curveId)
+	}
+
+	vk := groth16.NewVerifyingKey(curveId)
+
+	proof := groth16.NewProof(curveId)
+
+	publicWitness, err := witness.New(curveId.ScalarField())
+	if err != nil {
+		return nil, fmt.Errorf("error creating public witness: %w", err)
+	}
+	return &Groth16Verifier{curveId: curveId, Vk: vk, Proof: proof, PublicWitness: publicWitness}, nil
+}
+
+// NewGroth16VerifierFromKey returns a verifier for an already decoded
+// verifying key.
+func NewGroth16VerifierFromKey(vk groth16.VerifyingKey) (*Groth16Verifier, error) {
+	w, err := NewGroth16Verifier(vk.CurveID())
+	if err != nil {
+		return nil, err
+	}
+	w.Vk = vk
+
+	return w, nil
+}
+
+// NewGroth16VerifierFromFile reads the verifying key at keyPath, as written by
+// the worker or prover.
+func NewGroth16VerifierFromFile(keyPath string, curveId ecc.ID) (*Groth16Verifier, error) {
+	w, err := NewGroth16Verifier(curveId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := w.ReadVerifyingKey(keyPath); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// NewGroth16VerifierFromBytes decodes a verifying key held in memory, for
+// example one embedded in the binary with go:embed.
+func NewGroth16VerifierFromBytes(key []byte, curveId ecc.ID) (*Groth16Verifier, error) {
+	w, err := NewGroth16Verifier(curveId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := w.readVerifyingKey(bytes.NewReader(key)); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// Clone returns a verifier sharing the verifying key and fingerprint of w,
+// with its own proof and public witness, so a key loaded once can check
+// proofs from several goroutines.
+func (w *Groth16Verifier) Clone() (*Groth16Verifier, error) {
+	clone, err := NewGroth16Verifier(w.curveId)
+	if err != nil {
+		return nil, err
+	}
+	clone.Vk, clone.fingerprint = w.Vk, w.fingerprint
+
+	return clone, nil
+}
+
+// Fingerprint returns the fingerprint of the plonky2 circuit the verifying key
+// was set up for, or an empty string if it is unknown.
+func (w *Groth16Verifier) Fingerprint() string {
+	return w.fingerprint
+}
+
+func (w *Groth16Verifier) Verify() error {
+	if w.Vk == nil || w.PublicWitness == nil || w.Proof == nil {
+		return fmt.Errorf("Verifier keys, public witness, or proof are not set")
+	}
+	if err := checkCurveOf("verifying key", w.Vk.CurveID(), w.curveId); err != nil {
+		return err
+	}
+	if err := checkCurveOf("proof", w.Proof.CurveID(), w.curveId); err != nil {
+		return err
+	}
+	if got, want := publicWitnessLen(w.PublicWitness), w.Vk.NbPublicWitness(); got != want {
+		return fmt.Errorf("%w: %w: got %d, the verifying key expects %d", ErrInvalidProof, ErrPublicInputCount, got, want)
+	}
+	if err := groth16.Verify(w.Proof, w.Vk, w.PublicWitness); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidProof, err)
+	}
+	return nil
+}
+
+func checkCurveOf(name string, got, want ecc.ID) error {
+	if got != want {
+		return fmt.Errorf("%w: %s is on %s, expected %s", artifact.ErrCurveMismatch, name, got, want)
+	}
+
+	return nil
+}
+
+// encodingError marks decoding errors as ErrInvalidEncoding.
+func encodingError(err error) error {
+	return fmt.Errorf("%w: %w", ErrInvalidEncoding, err)
+}
+
+func (w *Groth16Verifier) readVerifyingKey(r io.Reader) error {
+	vk := groth16.NewVerifyingKey(w.curveId)
+	if _, err := artifact.ReadVerifyingKey(r, vk); err != nil {
+		return fmt.Errorf("failed to read verifying key: %w", encodingError(err))
+	}
+
+	w.Vk = vk
+
+	return nil
+}
+
+func (w *Groth16Verifier) ReadVerifyingKey(keyPath string) error {
+	fingerprint, err := artifact.ReadFingerprint(keyPath)
+	if err != nil {
+		return err
+	}
+
+	verifyingKeyFile, err := os.Open(keyPath)
+	if err != nil {
+		return fmt.Errorf("failed to open verifying key file: %w", err)
+	}
+	defer verifyingKeyFile.Close()
+
+	if err := w.readVerifyingKey(verifyingKeyFile); err != nil {
+		return err
+	}
+
+	w.fingerprint = fingerprint
+
+	return nil
+}
+
+func (w *Groth16Verifier) WriteVerifyingKey(keyPath string) error {
+	if w.Vk == nil {
+		return fmt.Errorf("verifying key is not initialized")
+	}
+
+	fVK, err := artifact.CreateAtomic(keyPath)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer fVK.Close()
+
+	if _, err := w.Vk.WriteTo(fVK); err != nil {
+		return fmt.Errorf("failed to write verifying key to file: %w", err)
+	}
+
+	if err := fVK.CommitWithFingerprint(w.fingerprint); err != nil {
+		return fmt.Errorf("failed to write verifying key to file: %w", err)
+	}
+
+	return nil
+}
+
+func (w *Groth16Verifier) ReadProof(keyPath string) error {
+	if w.Proof == nil {
+		return fmt.Errorf("proof is not initialized")
+	}
+
+	proofFile, err := os.Open(keyPath)
+	if err != nil {
+		return fmt.Errorf("failed to open proof file: %w", err)
+	}
+	defer proofFile.Close()
+
+	return w.ReadProofFrom(proofFile)
+}
+
+// ReadProofFrom reads a proof as written by WriteProof, or with uncompressed
+// points, from r.
+func (w *Groth16Verifier) ReadProofFrom(r io.Reader) error {
+	proof := groth16.NewProof(w.curveId)
+	if _, err := proof.ReadFrom(r); err != nil {
+		return fmt.Errorf("failed to read proof: %w", encodingError(err))
+	}
+
+	w.Proof = proof
+
+	return nil
+}
+
+func (w *Groth16Verifier) WriteProof(keyPath string) error {
+	if w.Proof == nil {
+		return fmt.Errorf("verifier or proof is not initialized")
+	}
+
+	fVK, err := artifact.CreateAtomic(keyPath)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer fVK.Close()
+
+	if _, err := w.Proof.WriteTo(fVK); err != nil {
+		return fmt.Errorf("failed to write proof to file: %w", err)
+	}
+
+	if err := fVK.Commit(); err != nil {
+		return fmt.Errorf("failed to write proof to file: %w", err)
+	}
+
+	return nil
+}
+
+func (w *Groth16Verifier) ReadPublicInputs(inputPath string) error {
+	if w.PublicWitness == nil {
+		return fmt.Errorf("public inputs is not initialized")
+	}
+
+	publicinputsFile, err := os.Open(inputPath)
+	if err != nil {
+		return fmt.Errorf("failed to open public inputs file: %w", err)
+	}
+	defer publicinputsFile.Close()
+
+	return w.ReadPublicInputsFrom(publicinputsFile)
+}
+
+// ReadPublicInputsFrom reads a public witness as written by
+// WritePublicInputs from r.
+func (w *Groth16Verifier) ReadPublicInputsFrom(r io.Reader) error {
+	publicWitness, err := witness.New(w.curveId.ScalarField())
+	if err != nil {
+		return fmt.Errorf("error creating public witness: %w", err)
+	}
+	if _, err := publicWitness.ReadFrom(r); err != nil {
+		return fmt.Errorf("failed to read public inputs: %w", encodingError(err))
+	}
+
+	w.PublicWitness = publicWitness
+
+	return nil
+}
+
+func (w *Groth16Verifier) WritePublicInputs(inputPath string) error {
+	if w.PublicWitness == nil {
+		return fmt.Errorf("public inputs is not initialized")
+	}
+
+	publicinputsFile, err := artifact.CreateAtomic(inputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer publicinputsFile.Close()
+
+	if _, err := w.PublicWitness.WriteTo(publicinputsFile); err != nil {
+		return fmt.Errorf("failed to write proof to file: %w", err)
+	}
+
+	if err := publicinputsFile.Commit(); err != nil {
+		return fmt.Errorf("failed to write public inputs to file: %w", err)
+	}
+
+	return nil
+}
+
+// ReadBundle loads only the verifying key from a bundle written by
+// Groth16Worker.WriteBundle; the circuit and proving key are skipped.
+func (w *Groth16Verifier) ReadBundle(bundlePath string) error {
+	bundle, err := artifact.OpenBundle(bundlePath)
+	if err != nil {
+		return err
+	}
+	defer bundle.Close()
+
+	if err := bundle.Header.Check(w.curveId); err != nil {
+		return err
+	}
+
+	vk := groth16.NewVerifyingKey(w.curveId)
+	if err := bundle.ReadSection(artifact.SectionVerifyingKey, vk); err != nil {
+		return err
+	}
+
+	w.Vk = vk
+	w.fingerprint = bundle.Header.Fingerprint
+
+	return nil
+}
@@ -0,0 +1,234 @@
+// Package verifyserver verifies groth16 proofs over HTTP against verifying
+// keys loaded once and pinned by ID. It only depends on package verifier: it
+// never reads a circuit or proving key, so it runs on small instances.
+//
+//	POST /verify/{key}  verify a proof against the key, returns a Result
+//	GET  /keys          the loaded keys
+//	GET  /healthz       the number of loaded keys
+//
+// A proof to verify is sent either as multipart/form-data, with the files
+// proof and public_witness in the encodings of Groth16Verifier.ReadProof and
+// ReadPublicInputs, or as a JSON Request.
+package verifyserver
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"sort"
+
+	"github.com/zilong-dai/groth16-worker/verifier"
+)
+
+// maxBodySize bounds a request, far above a proof and public witness.
+const maxBodySize = 1 << 20
+
+// keyID is what a key ID can be made of, so it fits in a path segment.
+var keyID = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// Request is the JSON form of a proof to verify. Byte fields are base64, as
+// encoding/json writes them. The public witness can be given as the plonky2
+// public inputs instead.
+type Request struct {
+	Proof         []byte   `json:"proof"`
+	PublicWitness []byte   `json:"public_witness,omitempty"`
+	PublicInputs  []uint64 `json:"public_inputs,omitempty"`
+}
+
+// Result is the outcome of a verification: the verifier report, along with
+// the ID of the key it was checked against. Proofs that don't verify,
+// including ones that don't decode, are a Result with Valid unset.
+type Result struct {
+	Key string `json:"key"`
+	verifier.Report
+}
+
+// Key describes a loaded verifying key.
+type Key struct {
+	ID           string `json:"id"`
+	Curve        string `json:"curve"`
+	Fingerprint  string `json:"fingerprint,omitempty"`
+	VerifyingKey string `json:"verifying_key_sha256"`
+	PublicInputs int    `json:"public_inputs"`
+}
+
+// Server is an http.Handler verifying proofs against pinned keys. The keys
+// are only read, so it is safe for concurrent use.
+type Server struct {
+	keys map[string]*verifier.Groth16Verifier
+	info []Key
+	mux  *http.ServeMux
+}
+
+// New returns a server verifying against keys, by ID. Each verifier must have
+// its verifying key loaded.
+func New(keys map[string]*verifier.Groth16Verifier) (*Server, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no verifying keys")
+	}
+
+	s := &Server{keys: keys, mux: http.NewServeMux()}
+	for id, groth16Verifier := range keys {
+		if !keyID.MatchString(id) {
+			return nil, fmt.Errorf("invalid key id %q, expected letters, digits, '.', '_' or '-'", id)
+		}
+		if groth16Verifier.Vk == nil {
+			return nil, fmt.Errorf("key %s: verifying key is not set", id)
+		}
+		h := sha256.New()
+		if _, err := groth16Verifier.Vk.WriteTo(h); err != nil {
+			return nil, fmt.Errorf("key %s: %w", id, err)
+		}
+		s.info = append(s.info, Key{
+			ID:           id,
+			Curve:        groth16Verifier.Vk.CurveID().String(),
+			Fingerprint:  groth16Verifier.Fingerprint(),
+			VerifyingKey: hex.EncodeToString(h.Sum(nil)),
+			PublicInputs: groth16Verifier.Vk.NbPublicWitness(),
+		})
+	}
+	sort.Slice(s.info, func(i, j int) bool { return s.info[i].ID < s.info[j].ID })
+
+	s.mux.HandleFunc("POST /verify/{key}", s.handleVerify)
+	s.mux.HandleFunc("GET /keys", s.handleKeys)
+	s.mux.HandleFunc("GET /healthz", s.handleHealth)
+
+	return s, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) handleVerify(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("key")
+	pinned, ok := s.keys[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no key %s", id))
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodySize)
+	req, err := readRequest(r)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, err)
+			return
+		}
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	groth16Verifier, err := pinned.Clone()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	err = groth16Verifier.ReadProofFrom(bytes.NewReader(req.Proof))
+	if err == nil && req.PublicInputs != nil {
+		err = groth16Verifier.SetPublicInputs(req.PublicInputs)
+	} else if err == nil {
+		err = groth16Verifier.ReadPublicInputsFrom(bytes.NewReader(req.PublicWitness))
+	}
+	if err != nil {
+		if verifier.Classify(err) == "" {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		result := Result{Key: id, Report: verifier.Report{Curve: pinned.Vk.CurveID().String(), Fingerprint: pinned.Fingerprint()}}
+		result.Fail(err)
+		writeJSON(w, http.StatusOK, result)
+		return
+	}
+
+	report, err := groth16Verifier.Report()
+	if err != nil && report.Failure == "" {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, Result{Key: id, Report: *report})
+}
+
+// readRequest reads a multipart or JSON request, after checking it has a
+// proof and exactly one of a public witness or public inputs.
+func readRequest(r *http.Request) (*Request, error) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid content type: %w", err)
+	}
+
+	req := &Request{}
+	switch mediaType {
+	case "application/json":
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(req); err != nil {
+			return nil, fmt.Errorf("invalid request: %w", err)
+		}
+	case "multipart/form-data":
+		if err := r.ParseMultipartForm(maxBodySize); err != nil {
+			return nil, fmt.Errorf("invalid request: %w", err)
+		}
+		for name, to := range map[string]*[]byte{"proof": &req.Proof, "public_witness": &req.PublicWitness} {
+			if *to, err = formFile(r, name); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported content type %s, expected application/json or multipart/form-data", mediaType)
+	}
+
+	switch {
+	case len(req.Proof) == 0:
+		return nil, fmt.Errorf("proof is missing")
+	case (len(req.PublicWitness) == 0) == (req.PublicInputs == nil):
+		return nil, fmt.Errorf("set exactly one of public_witness or public_inputs")
+	}
+
+	return req, nil
+}
+
+func formFile(r *http.Request, name string) ([]byte, error) {
+	file, _, err := r.FormFile(name)
+	if errors.Is(err, http.ErrMissingFile) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	defer file.Close()
+
+	return io.ReadAll(file)
+}
+
+func (s *Server) handleKeys(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.info)
+}
+
+type health struct {
+	Keys int `json:"keys"`
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, health{Keys: len(s.keys)})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, struct {
+		Error string `json:"error"`
+	}{err.Error()})
+}
@@ -0,0 +1,205 @@
+package verifyserver_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/zilong-dai/groth16-worker/verifier"
+	"github.com/zilong-dai/groth16-worker/verifyserver"
+)
+
+type cubicCircuit struct {
+	X frontend.Variable `gnark:"x"`
+	Y frontend.Variable `gnark:",public"`
+}
+
+func (c *cubicCircuit) Define(api frontend.API) error {
+	x3 := api.Mul(c.X, c.X, c.X)
+	api.AssertIsEqual(c.Y, api.Add(x3, c.X, 5))
+	return nil
+}
+
+// proveCubic sets up the cubic circuit and returns its verifying key, and a
+// proof and public witness in the encodings of the worker.
+func proveCubic(t *testing.T) (vk groth16.VerifyingKey, proof, publicWitness []byte) {
+	t.Helper()
+
+	ccs, err := frontend.Compile(ecc.BLS12_381.ScalarField(), r1cs.NewBuilder, &cubicCircuit{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk, vk, err := groth16.Setup(ccs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	full, err := frontend.NewWitness(&cubicCircuit{X: 3, Y: 35}, ecc.BLS12_381.ScalarField())
+	if err != nil {
+		t.Fatal(err)
+	}
+	groth16Proof, err := groth16.Prove(ccs, pk, full)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, err := full.Public()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var proofBuf, publicWitnessBuf bytes.Buffer
+	if _, err := groth16Proof.WriteTo(&proofBuf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := public.WriteTo(&publicWitnessBuf); err != nil {
+		t.Fatal(err)
+	}
+
+	return vk, proofBuf.Bytes(), publicWitnessBuf.Bytes()
+}
+
+func newServer(t *testing.T, vk groth16.VerifyingKey) *httptest.Server {
+	t.Helper()
+
+	cubic, err := verifier.NewGroth16VerifierFromKey(vk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv, err := verifyserver.New(map[string]*verifier.Groth16Verifier{"cubic": cubic})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+
+	return ts
+}
+
+func postJSON(t *testing.T, url string, req verifyserver.Request) (int, verifyserver.Result) {
+	t.Helper()
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return post(t, url, "application/json", body)
+}
+
+func postMultipart(t *testing.T, url string, files map[string][]byte) (int, verifyserver.Result) {
+	t.Helper()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for name, data := range files {
+		part, err := form.CreateFormFile(name, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write(data)
+	}
+	form.Close()
+
+	return post(t, url, form.FormDataContentType(), body.Bytes())
+}
+
+func post(t *testing.T, url, contentType string, body []byte) (int, verifyserver.Result) {
+	t.Helper()
+
+	resp, err := http.Post(url, contentType, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var result verifyserver.Result
+	if resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.StatusCode, result
+}
+
+func TestVerifyServer(t *testing.T) {
+	vk, proof, publicWitness := proveCubic(t)
+	ts := newServer(t, vk)
+	url := ts.URL + "/verify/cubic"
+
+	code, result := postMultipart(t, url, map[string][]byte{"proof": proof, "public_witness": publicWitness})
+	if code != http.StatusOK || !result.Valid || result.Key != "cubic" || len(result.PublicInputs) != 1 || result.PublicInputs[0] != 35 {
+		t.Fatalf("expected a multipart proof to verify, got %d %+v", code, result)
+	}
+
+	code, result = postJSON(t, url, verifyserver.Request{Proof: proof, PublicWitness: publicWitness})
+	if code != http.StatusOK || !result.Valid || result.VerifyingKey == "" || result.Proof == "" {
+		t.Fatalf("expected a json proof to verify, got %d %+v", code, result)
+	}
+
+	code, result = postJSON(t, url, verifyserver.Request{Proof: proof, PublicInputs: []uint64{35}})
+	if code != http.StatusOK || !result.Valid {
+		t.Fatalf("expected a proof with public inputs to verify, got %d %+v", code, result)
+	}
+
+	for _, test := range []struct {
+		name    string
+		req     verifyserver.Request
+		failure verifier.Failure
+	}{
+		{"wrong public input", verifyserver.Request{Proof: proof, PublicInputs: []uint64{36}}, verifier.FailurePairing},
+		{"extra public input", verifyserver.Request{Proof: proof, PublicInputs: []uint64{35, 1}}, verifier.FailurePublicInputCount},
+		{"garbage proof", verifyserver.Request{Proof: []byte("garbage"), PublicWitness: publicWitness}, verifier.FailureEncoding},
+		{"not a goldilocks element", verifyserver.Request{Proof: proof, PublicInputs: []uint64{1 << 63, 1<<64 - 1}}, verifier.FailureEncoding},
+	} {
+		code, result := postJSON(t, url, test.req)
+		if code != http.StatusOK || result.Valid || result.Failure != test.failure {
+			t.Errorf("%s: expected a %s failure, got %d %+v", test.name, test.failure, code, result)
+		}
+	}
+}
+
+func TestVerifyServerErrors(t *testing.T) {
+	vk, proof, publicWitness := proveCubic(t)
+	ts := newServer(t, vk)
+	url := ts.URL + "/verify/cubic"
+
+	if code, _ := postJSON(t, ts.URL+"/verify/other", verifyserver.Request{Proof: proof, PublicWitness: publicWitness}); code != http.StatusNotFound {
+		t.Errorf("expected an unknown key to be not found, got %d", code)
+	}
+	if code, _ := postMultipart(t, url, map[string][]byte{"proof": proof}); code != http.StatusBadRequest {
+		t.Errorf("expected a missing public witness to be a bad request, got %d", code)
+	}
+	if code, _ := postJSON(t, url, verifyserver.Request{Proof: proof, PublicWitness: publicWitness, PublicInputs: []uint64{35}}); code != http.StatusBadRequest {
+		t.Errorf("expected both a public witness and public inputs to be a bad request, got %d", code)
+	}
+	if code, _ := post(t, url, "text/plain", proof); code != http.StatusBadRequest {
+		t.Errorf("expected an unsupported content type to be a bad request, got %d", code)
+	}
+	if code, _ := post(t, url, "application/json", bytes.Repeat([]byte(" "), 2<<20)); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected a large body to be refused, got %d", code)
+	}
+
+	resp, err := http.Get(ts.URL + "/keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []verifyserver.Key
+	if err := json.Unmarshal(body, &keys); err != nil || len(keys) != 1 || keys[0].ID != "cubic" || keys[0].PublicInputs != 1 {
+		t.Fatalf("unexpected keys %s", body)
+	}
+
+	if _, err := verifyserver.New(map[string]*verifier.Groth16Verifier{"a/b": nil}); err == nil {
+		t.Fatal("expected an id that isn't a path segment to be refused")
+	}
+}
@@ -0,0 +1,45 @@
+package worker
+
+import "github.com/zilong-dai/groth16-worker/artifact"
+
+// The bundle and fingerprint files moved to the artifact package, shared with
+// the verifier. These forward to it.
+
+// Deprecated: use the artifact package.
+const (
+	BundleVersion       = artifact.BundleVersion
+	SectionCircuit      = artifact.SectionCircuit
+	SectionProvingKey   = artifact.SectionProvingKey
+	SectionVerifyingKey = artifact.SectionVerifyingKey
+	SectionMetadata     = artifact.SectionMetadata
+	FingerprintSuffix   = artifact.FingerprintSuffix
+)
+
+// Deprecated: use the artifact package.
+var (
+	ErrInvalidBundle    = artifact.ErrInvalidBundle
+	ErrChecksumMismatch = artifact.ErrChecksumMismatch
+	ErrMissingSection   = artifact.ErrMissingSection
+)
+
+// Deprecated: use the artifact package.
+type (
+	Bundle         = artifact.Bundle
+	BundleHeader   = artifact.BundleHeader
+	BundleSection  = artifact.BundleSection
+	BundleMetadata = artifact.BundleMetadata
+)
+
+// OpenBundle opens a bundle and reads its header.
+//
+// Deprecated: use artifact.OpenBundle.
+func OpenBundle(path string) (*Bundle, error) {
+	return artifact.OpenBundle(path)
+}
+
+// ReadFingerprint returns the fingerprint stored next to an artifact.
+//
+// Deprecated: use artifact.ReadFingerprint.
+func ReadFingerprint(artifactPath string) (string, error) {
+	return artifact.ReadFingerprint(artifactPath)
+}
@@ -0,0 +1,188 @@
+package worker
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/constraint"
+	"github.com/zilong-dai/groth16-worker/artifact"
+)
+
+// EnvCacheDir names the environment variable overriding the directory of the
+// default compile cache. Setting it to an empty string disables the cache.
+const EnvCacheDir = "GROTH16_WORKER_CACHE_DIR"
+
+// compileBuilder names the constraint system builder, it is part of the cache
+// key in case another one is ever used.
+const compileBuilder = "r1cs"
+
+// compileCircuitVersion is the version of Plonky2VerifierCircuit in the cache
+// key, so circuits compiled before it took the proof as a witness, or while
+// it took the verifier-only data as one, are never loaded.
+const compileCircuitVersion = "plonky2-verifier/3"
+
+// CompileCache keeps compiled constraint systems on disk, so the verifier
+// circuit is only compiled again when the plonky2 circuit changes.
+type CompileCache struct {
+	Dir string
+}
+
+// DefaultCompileCache returns the cache in $GROTH16_WORKER_CACHE_DIR, or in
+// groth16-worker under the user cache directory. It returns nil, no cache, if
+// the variable is set but empty or there is no user cache directory.
+func DefaultCompileCache() *CompileCache {
+	if dir, ok := os.LookupEnv(EnvCacheDir); ok {
+		if dir == "" {
+			return nil
+		}
+		return &CompileCache{Dir: dir}
+	}
+
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return nil
+	}
+	return &CompileCache{Dir: filepath.Join(dir, "groth16-worker")}
+}
+
+// Key identifies the constraint system compiled from inputs on curveId: a
+// sha256 of the circuit fingerprint, which covers the canonical common and
+// verifier-only circuit data, the curve, the builder, the circuit version and
+// the gnark version.
+func (c *CompileCache) Key(inputs *Plonky2Inputs, curveId ecc.ID) (string, error) {
+	fingerprint, err := inputs.Fingerprint()
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%s\n%s\n%s\n", curveId, compileBuilder, compileCircuitVersion, artifact.GnarkVersion(), fingerprint)
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (c *CompileCache) path(key string) string {
+	return filepath.Join(c.Dir, key+".r1cs")
+}
+
+// Load returns the constraint system stored under key, or nil if there is
+// none.
+func (c *CompileCache) Load(key string, curveId ecc.ID, observer Observer) (constraint.ConstraintSystem, error) {
+	return c.load(context.Background(), key, curveId, observer)
+}
+
+func (c *CompileCache) load(ctx context.Context, key string, curveId ecc.ID, observer Observer) (constraint.ConstraintSystem, error) {
+	file, err := os.Open(c.path(key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open cached circuit: %w", err)
+	}
+	defer file.Close()
+
+	r1cs := groth16.NewCS(curveId)
+	phase := startPhase(observer, PhaseReadCircuit)
+	progress := newProgressReader(newContextReader(ctx, file), phase)
+	_, err = r1cs.ReadFrom(progress)
+	progress.end(err)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, fmt.Errorf("failed to read cached circuit: %w", ctxErr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cached circuit %s: %w", c.path(key), err)
+	}
+
+	return r1cs, nil
+}
+
+// Store saves r1cs under key.
+func (c *CompileCache) Store(key string, r1cs constraint.ConstraintSystem) error {
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	file, err := artifact.CreateAtomic(c.path(key))
+	if err != nil {
+		return fmt.Errorf("failed to create cached circuit: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := r1cs.WriteTo(file); err != nil {
+		return fmt.Errorf("failed to write cached circuit: %w", err)
+	}
+	if err := file.Commit(); err != nil {
+		return fmt.Errorf("failed to write cached circuit: %w", err)
+	}
+
+	return nil
+}
+
+// compileInputs compiles the circuit of inputs, or loads it from cache if it
+// is there, and stores what it compiled. loaded is the constraint system
+// already held, stored under loadedKey, and is returned as is if the inputs
+// have the same key. The cache is best effort: an entry that can't be read
+// is compiled again and a failed store only costs the next compile. A nil
+// cache always compiles.
+func compileInputs(cache *CompileCache, curveId ecc.ID, inputs *Plonky2Inputs, observer Observer, loaded constraint.ConstraintSystem, loadedKey string) (constraint.ConstraintSystem, string, error) {
+	// parsed even on a hit, so bad inputs fail the same with or without cache
+	circuit, err := inputs.Circuit()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to load circuit: %w", err)
+	}
+
+	var key string
+	if cache != nil {
+		if key, err = cache.Key(inputs, curveId); err != nil {
+			return nil, "", err
+		}
+		if key == loadedKey && loaded != nil {
+			return loaded, key, nil
+		}
+		if r1cs, err := cache.Load(key, curveId, observer); err == nil && r1cs != nil {
+			return r1cs, key, nil
+		}
+	}
+
+	r1cs, err := compileCircuit(curveId, circuit, observer)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if cache != nil {
+		cache.Store(key, r1cs)
+	}
+
+	return r1cs, key, nil
+}
+
+// cachedCircuit loads the constraint system of circuitData, the common and
+// verifier-only circuit data, from cache, for steps that need a circuit when
+// none was compiled or read. A miss returns a nil constraint system; an entry
+// that can't be read is a miss too, as in compileInputs.
+func cachedCircuit(ctx context.Context, cache *CompileCache, circuitData *Plonky2Inputs, curveId ecc.ID, observer Observer) (constraint.ConstraintSystem, string, error) {
+	if cache == nil {
+		return nil, "", nil
+	}
+
+	key, err := cache.Key(circuitData, curveId)
+	if err != nil {
+		return nil, "", err
+	}
+
+	r1cs, err := cache.load(ctx, key, curveId, observer)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, "", fmt.Errorf("failed to read cached circuit: %w", ctxErr)
+	}
+	if err != nil || r1cs == nil {
+		return nil, "", nil
+	}
+
+	return r1cs, key, nil
+}
@@ -0,0 +1,34 @@
+package worker
+
+import (
+	gl "github.com/cf/gnark-plonky2-verifier/goldilocks"
+	"github.com/cf/gnark-plonky2-verifier/types"
+	"github.com/cf/gnark-plonky2-verifier/variables"
+	"github.com/cf/gnark-plonky2-verifier/verifier"
+	"github.com/consensys/gnark/frontend"
+)
+
+// Plonky2VerifierCircuit verifies a plonky2 proof. verifier.ExampleVerifierCircuit
+// leaves the proof out of the witness, so it is compiled in as a constant and
+// its keys only ever prove the proof they were set up with. Here the proof is
+// a secret witness value, so the keys prove any proof of the circuit.
+//
+// The common and verifier-only circuit data stay constants: the verifier-only
+// data holds the circuit digest, so compiling it in pins the verifying key to
+// the plonky2 circuit it was set up for.
+type Plonky2VerifierCircuit struct {
+	PublicInputs []gl.Variable `gnark:",public"`
+	Proof        variables.Proof
+
+	// CommonCircuitData shapes the circuit and VerifierOnlyCircuitData
+	// identifies it, they are constants not variables
+	VerifierOnlyCircuitData variables.VerifierOnlyCircuitData `gnark:"-"`
+	CommonCircuitData       types.CommonCircuitData           `gnark:"-"`
+}
+
+func (c *Plonky2VerifierCircuit) Define(api frontend.API) error {
+	verifierChip := verifier.NewVerifierChip(api, c.CommonCircuitData)
+	verifierChip.Verify(c.Proof, c.PublicInputs, c.VerifierOnlyCircuitData)
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package worker
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync"
+)
+
+// contextReader fails reads once ctx is done, so a key or circuit read that
+// takes minutes stops at the next chunk instead of running to the end.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func newContextReader(ctx context.Context, r io.Reader) io.Reader {
+	return &contextReader{ctx: ctx, r: r}
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// withFileSize bounds r, which reads file from its start, by the size of the
+// file, so artifact.ReadProvingKey and ReadVerifyingKey can check the lengths
+// in a key dump against it.
+func withFileSize(r io.Reader, file *os.File) io.Reader {
+	info, err := file.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		return r
+	}
+	return &io.LimitedReader{R: r, N: info.Size()}
+}
+
+// running counts the calls of runWithContext whose fn hasn't returned,
+// including the ones that returned early. idle is closed whenever the count
+// drops to zero.
+var running struct {
+	sync.Mutex
+	n    int
+	idle chan struct{}
+}
+
+func startRunning() {
+	running.Lock()
+	defer running.Unlock()
+
+	if running.n == 0 {
+		running.idle = make(chan struct{})
+	}
+	running.n++
+}
+
+func stopRunning() {
+	running.Lock()
+	defer running.Unlock()
+
+	running.n--
+	if running.n == 0 {
+		close(running.idle)
+	}
+}
+
+// Wait blocks until the gnark calls of cancelled operations have returned, or
+// ctx is done. A cancelled operation returns right away while its gnark call
+// keeps running in the background, holding its memory and cpus, until it
+// reaches a point it can stop at:
+//
+//   - a prove stops solving at its next hint, but its MSMs run to the end once
+//     the solve is over
+//   - compiles, setups and witness generation can't be interrupted and run
+//     to the end
+//
+// Call Wait on shutdown to let those calls finish first. It waits until no
+// operation of the package is running, cancelled or not.
+func Wait(ctx context.Context) error {
+	running.Lock()
+	if running.n == 0 {
+		running.Unlock()
+		return nil
+	}
+	idle := running.idle
+	running.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// runWithContext runs fn and returns early with ctx.Err() if ctx is done first.
+// fn keeps running in the background after a cancellation until it notices
+// ctx itself, if it can, or returns, see Wait; callers must only publish its
+// results when runWithContext returns nil, so nothing keeps them alive once
+// fn finishes.
+func runWithContext(ctx context.Context, fn func() error) error {
+	return runWithContextThen(ctx, fn, func() {})
+}
+
+// runWithContextThen is runWithContext calling then once fn has returned,
+// even if runWithContext returned early, or right away if fn isn't run
+// because ctx is already done. It frees what fn holds only once fn is done
+// with it.
+func runWithContextThen(ctx context.Context, fn func() error, then func()) error {
+	if err := ctx.Err(); err != nil {
+		then()
+		return err
+	}
+
+	done := make(chan error, 1)
+	startRunning()
+	go func() {
+		defer stopRunning()
+		defer then()
+		done <- fn()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,195 @@
+package worker
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/zilong-dai/groth16-worker/artifact"
+)
+
+// KeyConversion reports what ConvertKey did and how long each step took, so
+// the formats of a key can be compared on the machine that will load it.
+// Durations are in nanoseconds in JSON.
+type KeyConversion struct {
+	Type       ArtifactType `json:"type"`
+	From       string       `json:"from"`
+	To         string       `json:"to"`
+	InputSize  int64        `json:"input_size"`
+	OutputSize int64        `json:"output_size"`
+
+	// ReadTime decodes the input and WriteTime encodes the output. LoadTime
+	// decodes the output again and is only measured when it is verified.
+	ReadTime  time.Duration `json:"read_time"`
+	WriteTime time.Duration `json:"write_time"`
+	LoadTime  time.Duration `json:"load_time,omitempty"`
+
+	// SHA256 is the hash of the output file, also stored next to it.
+	SHA256 string `json:"sha256"`
+	// KeyDigest is the sha256 of the compressed encoding of the key. It does
+	// not depend on the format, so two files hold the same key if and only if
+	// their digests match.
+	KeyDigest string `json:"key_digest"`
+	Verified  bool   `json:"verified"`
+}
+
+// convertibleKey is implemented by both proving and verifying keys.
+type convertibleKey interface {
+	io.WriterTo
+	WriteRawTo(io.Writer) (int64, error)
+}
+
+// ConvertKey re-encodes the proving or verifying key at inPath in format,
+// one of FormatCompressed, FormatRaw and FormatDump, and writes it to outPath
+// with its sha256 next to it. The fingerprint of the input, if any, is
+// carried over. If verify is set, the output is read back and must decode to
+// the same key.
+func ConvertKey(inPath, outPath, format string, curveId ecc.ID, verify bool) (*KeyConversion, error) {
+	switch format {
+	case FormatCompressed, FormatRaw, FormatDump:
+	default:
+		return nil, fmt.Errorf("unknown key format %q, expected %s, %s or %s", format, FormatCompressed, FormatRaw, FormatDump)
+	}
+
+	info, err := InspectArtifact(inPath, curveId)
+	if err != nil {
+		return nil, err
+	}
+	if info.Type != ArtifactProvingKey && info.Type != ArtifactVerifyingKey {
+		return nil, fmt.Errorf("%w: %s is a %s, not a key", ErrUnknownArtifact, inPath, info.Type)
+	}
+
+	conversion := &KeyConversion{Type: info.Type, From: info.Format, To: format, InputSize: info.Size}
+
+	start := time.Now()
+	key, err := readKey(inPath, info.Type, curveId)
+	if err != nil {
+		return nil, err
+	}
+	conversion.ReadTime = time.Since(start)
+
+	if conversion.KeyDigest, err = keyDigest(key); err != nil {
+		return nil, err
+	}
+
+	fingerprint, err := artifact.ReadFingerprint(inPath)
+	if err != nil {
+		return nil, err
+	}
+
+	start = time.Now()
+	conversion.SHA256, conversion.OutputSize, err = writeKey(outPath, key, info.Type, format, fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	conversion.WriteTime = time.Since(start)
+
+	if !verify {
+		return conversion, nil
+	}
+
+	if _, err := artifact.VerifyChecksum(outPath); err != nil {
+		return nil, err
+	}
+
+	start = time.Now()
+	loaded, err := readKey(outPath, info.Type, curveId)
+	if err != nil {
+		return nil, err
+	}
+	conversion.LoadTime = time.Since(start)
+
+	digest, err := keyDigest(loaded)
+	if err != nil {
+		return nil, err
+	}
+	if digest != conversion.KeyDigest {
+		return nil, fmt.Errorf("%w: %s decodes to key %s, expected %s", artifact.ErrChecksumMismatch, outPath, digest, conversion.KeyDigest)
+	}
+	conversion.Verified = true
+
+	return conversion, nil
+}
+
+func readKey(path string, typ ArtifactType, curveId ecc.ID) (convertibleKey, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open key file: %w", err)
+	}
+	defer file.Close()
+
+	if typ == ArtifactProvingKey {
+		pk := groth16.NewProvingKey(curveId)
+		if _, err := artifact.ReadProvingKey(file, pk); err != nil {
+			return nil, fmt.Errorf("failed to read proving key: %w", err)
+		}
+		return pk, nil
+	}
+
+	vk := groth16.NewVerifyingKey(curveId)
+	if _, err := artifact.ReadVerifyingKey(file, vk); err != nil {
+		return nil, fmt.Errorf("failed to read verifying key: %w", err)
+	}
+	return vk, nil
+}
+
+// writeKey writes key, of type typ, to path in format with its sha256 and
+// fingerprint next to it, and returns the sha256 and size of what was
+// written. A failed write leaves the key it replaces, with its own sha256
+// and fingerprint.
+func writeKey(path string, key convertibleKey, typ ArtifactType, format string, fingerprint string) (string, int64, error) {
+	file, err := artifact.CreateAtomic(path)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create file: %w", err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	buf := bufio.NewWriterSize(io.MultiWriter(file, hash), 1<<20)
+
+	var n int64
+	switch format {
+	case FormatCompressed:
+		n, err = key.WriteTo(buf)
+	case FormatRaw:
+		n, err = key.WriteRawTo(buf)
+	case FormatDump:
+		// verifying keys also implement groth16.ProvingKey, so the type
+		// can't be switched on
+		if typ == ArtifactProvingKey {
+			n, err = artifact.WriteProvingKeyDump(buf, key.(groth16.ProvingKey))
+		} else {
+			n, err = artifact.WriteVerifyingKeyDump(buf, key.(groth16.VerifyingKey))
+		}
+	default:
+		err = fmt.Errorf("unknown key format %q", format)
+	}
+	if err == nil {
+		err = buf.Flush()
+	}
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to write key: %w", err)
+	}
+
+	sum := hex.EncodeToString(hash.Sum(nil))
+	if err := file.CommitWithChecksum(sum, fingerprint); err != nil {
+		return "", 0, fmt.Errorf("failed to write key: %w", err)
+	}
+
+	return sum, n, nil
+}
+
+func keyDigest(key convertibleKey) (string, error) {
+	hash := sha256.New()
+	if _, err := key.WriteTo(hash); err != nil {
+		return "", fmt.Errorf("failed to hash key: %w", err)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,89 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/zilong-dai/groth16-worker/artifact"
+)
+
+var (
+	ErrUnsupportedCurve = errors.New("unsupported curve")
+	ErrCurveMismatch    = artifact.ErrCurveMismatch
+)
+
+// poseidonVariants maps each outer curve to the Poseidon variant the plonky2
+// verifier circuit hashes with. The circuit has to be compiled over the same
+// scalar field as its Poseidon variant, and gnark-plonky2-verifier only ships
+// the BLS12-381 one: its challenger, FRI verifier and circuit digest are
+// written against it. BN254 needs a BN254 Poseidon in gnark-plonky2-verifier,
+// and plonky2 proofs hashed with it, so until then it is rejected with the
+// other curves, before anything is compiled.
+var poseidonVariants = map[ecc.ID]string{
+	ecc.BLS12_381: "poseidon-bls12-381",
+}
+
+// plonky2HashCurve is the curve whose scalar field holds the hashes in the
+// plonky2 proofs the circuit verifies, that of its only Poseidon variant, and
+// plonky2HashField that field.
+var (
+	plonky2HashCurve = ecc.BLS12_381
+	plonky2HashField = plonky2HashCurve.ScalarField()
+)
+
+// CheckCurve returns ErrUnsupportedCurve if the plonky2 verifier circuit
+// can't be built on curveId.
+func CheckCurve(curveId ecc.ID) error {
+	if _, ok := poseidonVariants[curveId]; !ok {
+		return fmt.Errorf("%w: %s, the plonky2 verifier circuit needs a Poseidon over its scalar field and is only available on %s", ErrUnsupportedCurve, curveId, supportedCurves())
+	}
+
+	return nil
+}
+
+func supportedCurves() string {
+	names := make([]string, 0, len(poseidonVariants))
+	for curveId := range poseidonVariants {
+		names = append(names, curveId.String())
+	}
+	sort.Strings(names)
+
+	return strings.Join(names, ", ")
+}
+
+// checkCurveOf makes sure an object read from disk or passed in by the caller
+// belongs to the curve the worker was created for.
+func checkCurveOf(name string, got, want ecc.ID) error {
+	if got != want {
+		return fmt.Errorf("%w: %s is on %s, expected %s", ErrCurveMismatch, name, got, want)
+	}
+
+	return nil
+}
+
+// compileCircuit compiles the plonky2 verifier circuit over the scalar field of curveId.
+func compileCircuit(curveId ecc.ID, circuit *Plonky2VerifierCircuit, observer Observer) (constraint.ConstraintSystem, error) {
+	if err := CheckCurve(curveId); err != nil {
+		return nil, err
+	}
+
+	phase := startPhase(observer, PhaseCompile)
+
+	builder := r1cs.NewBuilder
+
+	r1cs, err := frontend.Compile(curveId.ScalarField(), builder, circuit)
+	if err != nil {
+		phase.end(err)
+		return nil, fmt.Errorf("failed to compile circuit: %w", err)
+	}
+
+	phase.emit(Event{Kind: PhaseFinished, Constraints: r1cs.GetNbConstraints()})
+
+	return r1cs, nil
+}
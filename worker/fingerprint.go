@@ -0,0 +1,76 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/zilong-dai/groth16-worker/artifact"
+)
+
+var ErrFingerprintMismatch = errors.New("circuit fingerprint mismatch")
+
+// FingerprintFromDir fingerprints the common and verifier-only circuit data in
+// dir. It returns an empty string if either file is missing.
+func FingerprintFromDir(dir string) (string, error) {
+	circuitData, err := circuitDataFromDir(dir)
+	if err != nil || circuitData == nil {
+		return "", err
+	}
+
+	return circuitData.Fingerprint()
+}
+
+// circuitDataFromDir reads the common and verifier-only circuit data in dir,
+// without a proof. It returns nil if either file is missing.
+func circuitDataFromDir(dir string) (*Plonky2Inputs, error) {
+	common, err := os.ReadFile(filepath.Join(dir, CommonCircuitDataFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", CommonCircuitDataFile, err)
+	}
+
+	verifierOnly, err := os.ReadFile(filepath.Join(dir, VerifierOnlyCircuitDataFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", VerifierOnlyCircuitDataFile, err)
+	}
+
+	return NewPlonky2InputsFromBytes(common, nil, verifierOnly), nil
+}
+
+// checkArtifactFingerprint reads the fingerprint stored next to an artifact
+// and checks it against the fingerprint of the plonky2 inputs.
+func checkArtifactFingerprint(name string, artifactPath string, inputs func() (string, error)) (string, error) {
+	stored, err := artifact.ReadFingerprint(artifactPath)
+	if err != nil {
+		return "", err
+	}
+
+	return stored, checkFingerprint(name, stored, inputs)
+}
+
+// checkFingerprint compares the fingerprint an artifact was stored with to
+// the one of the plonky2 inputs. Either side may be unknown, in which case
+// there is nothing to check.
+func checkFingerprint(name string, stored string, inputs func() (string, error)) error {
+	if stored == "" {
+		return nil
+	}
+
+	expected, err := inputs()
+	if err != nil {
+		return fmt.Errorf("failed to fingerprint inputs: %w", err)
+	}
+
+	if expected == "" || expected == stored {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s was set up for circuit %s, inputs are circuit %s", ErrFingerprintMismatch, name, stored, expected)
+}
@@ -0,0 +1,286 @@
+package worker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/cf/gnark-plonky2-verifier/plonk/gates"
+	"github.com/cf/gnark-plonky2-verifier/types"
+	"github.com/cf/gnark-plonky2-verifier/variables"
+)
+
+const (
+	CommonCircuitDataFile       = "common_circuit_data.json"
+	ProofWithPublicInputsFile   = "proof_with_public_inputs.json"
+	VerifierOnlyCircuitDataFile = "verifier_only_circuit_data.json"
+)
+
+// Plonky2Inputs holds the three plonky2 JSON documents the verifier circuit is
+// built from. It can be filled from a directory, from readers or from bytes,
+// so callers holding the JSON in memory don't have to write it to disk.
+type Plonky2Inputs struct {
+	CommonCircuitData       []byte
+	ProofWithPublicInputs   []byte
+	VerifierOnlyCircuitData []byte
+}
+
+func NewPlonky2InputsFromBytes(commonCircuitData, proofWithPublicInputs, verifierOnlyCircuitData []byte) *Plonky2Inputs {
+	return &Plonky2Inputs{
+		CommonCircuitData:       commonCircuitData,
+		ProofWithPublicInputs:   proofWithPublicInputs,
+		VerifierOnlyCircuitData: verifierOnlyCircuitData,
+	}
+}
+
+func NewPlonky2InputsFromReaders(commonCircuitData, proofWithPublicInputs, verifierOnlyCircuitData io.Reader) (*Plonky2Inputs, error) {
+	common, err := io.ReadAll(commonCircuitData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read common circuit data: %w", err)
+	}
+
+	proof, err := io.ReadAll(proofWithPublicInputs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read proof with public inputs: %w", err)
+	}
+
+	verifierOnly, err := io.ReadAll(verifierOnlyCircuitData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read verifier only circuit data: %w", err)
+	}
+
+	return NewPlonky2InputsFromBytes(common, proof, verifierOnly), nil
+}
+
+func NewPlonky2InputsFromDir(dir string) (*Plonky2Inputs, error) {
+	files := []string{CommonCircuitDataFile, ProofWithPublicInputsFile, VerifierOnlyCircuitDataFile}
+
+	contents := make([][]byte, len(files))
+	for i, file := range files {
+		data, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		contents[i] = data
+	}
+
+	return NewPlonky2InputsFromBytes(contents[0], contents[1], contents[2]), nil
+}
+
+// WithProof returns a copy of the inputs with proofWithPublicInputs in place
+// of the current proof.
+func (in *Plonky2Inputs) WithProof(proofWithPublicInputs []byte) *Plonky2Inputs {
+	return NewPlonky2InputsFromBytes(in.CommonCircuitData, proofWithPublicInputs, in.VerifierOnlyCircuitData)
+}
+
+func (in *Plonky2Inputs) readCommonCircuitDataRaw() (types.CommonCircuitDataRaw, error) {
+	var raw types.CommonCircuitDataRaw
+	if err := unmarshalInput(CommonCircuitDataFile, in.CommonCircuitData, &raw); err != nil {
+		return raw, err
+	}
+
+	return raw, validateCommonCircuitData(raw)
+}
+
+func (in *Plonky2Inputs) ReadCommonCircuitData() (commonCircuitData types.CommonCircuitData, err error) {
+	raw, err := in.readCommonCircuitDataRaw()
+	if err != nil {
+		return types.CommonCircuitData{}, err
+	}
+
+	defer recoverInputError(CommonCircuitDataFile, "", &err)
+
+	// mirrors types.ReadCommonCircuitData, which only reads from a path
+	commonCircuitData.Config.NumWires = raw.Config.NumWires
+	commonCircuitData.Config.NumRoutedWires = raw.Config.NumRoutedWires
+	commonCircuitData.Config.NumConstants = raw.Config.NumConstants
+	commonCircuitData.Config.UseBaseArithmeticGate = raw.Config.UseBaseArithmeticGate
+	commonCircuitData.Config.SecurityBits = raw.Config.SecurityBits
+	commonCircuitData.Config.NumChallenges = raw.Config.NumChallenges
+	commonCircuitData.Config.ZeroKnowledge = raw.Config.ZeroKnowledge
+	commonCircuitData.Config.MaxQuotientDegreeFactor = raw.Config.MaxQuotientDegreeFactor
+
+	commonCircuitData.Config.FriConfig.RateBits = raw.Config.FriConfig.RateBits
+	commonCircuitData.Config.FriConfig.CapHeight = raw.Config.FriConfig.CapHeight
+	commonCircuitData.Config.FriConfig.ProofOfWorkBits = raw.Config.FriConfig.ProofOfWorkBits
+	commonCircuitData.Config.FriConfig.NumQueryRounds = raw.Config.FriConfig.NumQueryRounds
+
+	commonCircuitData.FriParams.DegreeBits = raw.FriParams.DegreeBits
+	commonCircuitData.DegreeBits = raw.FriParams.DegreeBits
+	commonCircuitData.FriParams.Config.RateBits = raw.FriParams.Config.RateBits
+	commonCircuitData.FriParams.Config.CapHeight = raw.FriParams.Config.CapHeight
+	commonCircuitData.FriParams.Config.ProofOfWorkBits = raw.FriParams.Config.ProofOfWorkBits
+	commonCircuitData.FriParams.Config.NumQueryRounds = raw.FriParams.Config.NumQueryRounds
+	commonCircuitData.FriParams.ReductionArityBits = raw.FriParams.ReductionArityBits
+
+	commonCircuitData.GateIds = raw.Gates
+
+	selectorGroupStart := []uint64{}
+	selectorGroupEnd := []uint64{}
+	for _, group := range raw.SelectorsInfo.Groups {
+		selectorGroupStart = append(selectorGroupStart, group.Start)
+		selectorGroupEnd = append(selectorGroupEnd, group.End)
+	}
+
+	commonCircuitData.SelectorsInfo = *gates.NewSelectorsInfo(
+		raw.SelectorsInfo.SelectorIndices,
+		selectorGroupStart,
+		selectorGroupEnd,
+	)
+
+	commonCircuitData.QuotientDegreeFactor = raw.QuotientDegreeFactor
+	commonCircuitData.NumGateConstraints = raw.NumGateConstraints
+	commonCircuitData.NumConstants = raw.NumConstants
+	commonCircuitData.NumPublicInputs = raw.NumPublicInputs
+	commonCircuitData.KIs = raw.KIs
+	commonCircuitData.NumPartialProducts = raw.NumPartialProducts
+
+	return commonCircuitData, nil
+}
+
+func (in *Plonky2Inputs) ReadProofWithPublicInputs() (proofWithPis variables.ProofWithPublicInputs, err error) {
+	common, err := in.readCommonCircuitDataRaw()
+	if err != nil {
+		return variables.ProofWithPublicInputs{}, err
+	}
+
+	var raw types.ProofWithPublicInputsRaw
+	if err := unmarshalInput(ProofWithPublicInputsFile, in.ProofWithPublicInputs, &raw); err != nil {
+		return variables.ProofWithPublicInputs{}, err
+	}
+
+	if err := validateProofWithPublicInputs(raw, common); err != nil {
+		return variables.ProofWithPublicInputs{}, err
+	}
+
+	defer recoverInputError(ProofWithPublicInputsFile, "", &err)
+
+	return variables.DeserializeProofWithPublicInputs(raw), nil
+}
+
+func (in *Plonky2Inputs) ReadVerifierOnlyCircuitData() (verifierOnlyCircuitData variables.VerifierOnlyCircuitData, err error) {
+	common, err := in.readCommonCircuitDataRaw()
+	if err != nil {
+		return variables.VerifierOnlyCircuitData{}, err
+	}
+
+	var raw types.VerifierOnlyCircuitDataRaw
+	if err := unmarshalInput(VerifierOnlyCircuitDataFile, in.VerifierOnlyCircuitData, &raw); err != nil {
+		return variables.VerifierOnlyCircuitData{}, err
+	}
+
+	if err := validateVerifierOnlyCircuitData(raw, common); err != nil {
+		return variables.VerifierOnlyCircuitData{}, err
+	}
+
+	defer recoverInputError(VerifierOnlyCircuitDataFile, "", &err)
+
+	return variables.DeserializeVerifierOnlyCircuitData(raw), nil
+}
+
+// Fingerprint identifies the plonky2 circuit the inputs describe. It is a
+// sha256 over the canonical encoding of the common and verifier-only circuit
+// data; the proof is left out, so every proof of a circuit shares it.
+func (in *Plonky2Inputs) Fingerprint() (string, error) {
+	common, err := in.readCommonCircuitDataRaw()
+	if err != nil {
+		return "", err
+	}
+
+	var verifierOnly types.VerifierOnlyCircuitDataRaw
+	if err := unmarshalInput(VerifierOnlyCircuitDataFile, in.VerifierOnlyCircuitData, &verifierOnly); err != nil {
+		return "", err
+	}
+	if err := validateVerifierOnlyCircuitData(verifierOnly, common); err != nil {
+		return "", err
+	}
+
+	// re-encoding the parsed documents drops whitespace, key order and
+	// unknown fields, so equivalent JSON gives the same fingerprint
+	h := sha256.New()
+	encoder := json.NewEncoder(h)
+	if err := encoder.Encode(common); err != nil {
+		return "", fmt.Errorf("failed to encode common circuit data: %w", err)
+	}
+	if err := encoder.Encode(verifierOnly); err != nil {
+		return "", fmt.Errorf("failed to encode verifier only circuit data: %w", err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ProofHash identifies the proof of the inputs for its circuit: a sha256 over
+// the circuit fingerprint and the canonical encoding of the proof, so the
+// same proof sent with other whitespace or key order hashes the same.
+func (in *Plonky2Inputs) ProofHash() (string, error) {
+	fingerprint, err := in.Fingerprint()
+	if err != nil {
+		return "", err
+	}
+	common, err := in.readCommonCircuitDataRaw()
+	if err != nil {
+		return "", err
+	}
+
+	var proof types.ProofWithPublicInputsRaw
+	if err := unmarshalInput(ProofWithPublicInputsFile, in.ProofWithPublicInputs, &proof); err != nil {
+		return "", err
+	}
+	if err := validateProofWithPublicInputs(proof, common); err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n", fingerprint)
+	if err := json.NewEncoder(h).Encode(proof); err != nil {
+		return "", fmt.Errorf("failed to encode proof with public inputs: %w", err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Validate parses all three inputs and checks them against each other
+// without compiling the circuit.
+func (in *Plonky2Inputs) Validate() error {
+	_, err := in.Circuit()
+	return err
+}
+
+// Circuit returns the plonky2 verifier circuit used to compile the constraint system.
+func (in *Plonky2Inputs) Circuit() (*Plonky2VerifierCircuit, error) {
+	commonCircuitData, err := in.ReadCommonCircuitData()
+	if err != nil {
+		return nil, err
+	}
+
+	verifierOnlyCircuitData, err := in.ReadVerifierOnlyCircuitData()
+	if err != nil {
+		return nil, err
+	}
+
+	assignment, err := in.Assignment()
+	if err != nil {
+		return nil, err
+	}
+	assignment.CommonCircuitData = commonCircuitData
+	assignment.VerifierOnlyCircuitData = verifierOnlyCircuitData
+
+	return assignment, nil
+}
+
+// Assignment returns the witness assignment of the plonky2 verifier circuit.
+func (in *Plonky2Inputs) Assignment() (*Plonky2VerifierCircuit, error) {
+	proofWithPis, err := in.ReadProofWithPublicInputs()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Plonky2VerifierCircuit{
+		Proof:        proofWithPis.Proof,
+		PublicInputs: proofWithPis.PublicInputs,
+	}, nil
+}
@@ -0,0 +1,521 @@
+package worker
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"unsafe"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	curve "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
+	"github.com/consensys/gnark/backend/groth16"
+	groth16_bls12381 "github.com/consensys/gnark/backend/groth16/bls12-381"
+	"github.com/zilong-dai/groth16-worker/artifact"
+)
+
+var ErrUnknownArtifact = errors.New("unknown artifact")
+
+type ArtifactType string
+
+const (
+	ArtifactCircuit       ArtifactType = "circuit"
+	ArtifactProvingKey    ArtifactType = "proving_key"
+	ArtifactVerifyingKey  ArtifactType = "verifying_key"
+	ArtifactProof         ArtifactType = "proof"
+	ArtifactWitness       ArtifactType = "witness"
+	ArtifactPublicWitness ArtifactType = "public_witness"
+	ArtifactBundle        ArtifactType = "bundle"
+)
+
+// Encodings of keys and proofs. gnark decodes both point encodings with the
+// same reader, raw points just skip the square root on load.
+const (
+	FormatCompressed = "compressed"
+	FormatRaw        = "raw"
+	FormatDump       = "dump"
+)
+
+// ArtifactInfo summarizes a file written by the worker, prover or verifier.
+// Counts that don't apply to the artifact type are left at zero.
+type ArtifactInfo struct {
+	Path        string       `json:"path"`
+	Type        ArtifactType `json:"type"`
+	Curve       string       `json:"curve"`
+	Format      string       `json:"format,omitempty"`
+	Size        int64        `json:"size"`
+	Fingerprint string       `json:"fingerprint,omitempty"`
+
+	Constraints       int `json:"constraints,omitempty"`
+	InternalVariables int `json:"internal_variables,omitempty"`
+	SecretVariables   int `json:"secret_variables,omitempty"`
+	PublicVariables   int `json:"public_variables,omitempty"`
+
+	// PublicInputs and SecretInputs don't count the constant one wire.
+	PublicInputs int `json:"public_inputs,omitempty"`
+	SecretInputs int `json:"secret_inputs,omitempty"`
+
+	DomainSize  uint64 `json:"domain_size,omitempty"`
+	G1Points    int    `json:"g1_points,omitempty"`
+	G2Points    int    `json:"g2_points,omitempty"`
+	Commitments int    `json:"commitments,omitempty"`
+
+	Points   []Point                  `json:"points,omitempty"`
+	Sections []artifact.BundleSection `json:"sections,omitempty"`
+}
+
+// Point is a curve point of a proof or verifying key, hex encoded in
+// compressed form.
+type Point struct {
+	Name  string `json:"name"`
+	Group string `json:"group"`
+	Value string `json:"value"`
+}
+
+func (p Point) String() string {
+	return fmt.Sprintf("%s %s %s", p.Name, p.Group, p.Value)
+}
+
+// InspectArtifact detects the type of the artifact at path and summarizes it.
+// Proving keys are only walked through, not decoded, so inspecting one takes
+// about as long as reading its length prefixes.
+func InspectArtifact(path string, curveId ecc.ID) (*ArtifactInfo, error) {
+	if err := CheckCurve(curveId); err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open artifact: %w", err)
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat artifact: %w", err)
+	}
+
+	fingerprint, err := artifact.ReadFingerprint(path)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &ArtifactInfo{
+		Path:        path,
+		Curve:       curveId.String(),
+		Size:        stat.Size(),
+		Fingerprint: fingerprint,
+	}
+
+	if bundle, err := artifact.OpenBundle(path); err == nil {
+		defer bundle.Close()
+		return info, inspectBundle(info, bundle)
+	} else if !errors.Is(err, artifact.ErrInvalidBundle) {
+		return nil, err
+	}
+
+	if ok, err := inspectDump(info, file); ok || err != nil {
+		return info, err
+	}
+
+	// the layouts are checked from the cheapest to the most expensive, each
+	// one only matches if it accounts for every byte of the file
+	if inspectWitness(info, file) {
+		return info, nil
+	}
+	if ok, err := inspectProof(info, file); ok || err != nil {
+		return info, err
+	}
+	if ok, err := inspectVerifyingKey(info, file); ok || err != nil {
+		return info, err
+	}
+	if inspectProvingKey(info, file) {
+		return info, nil
+	}
+	if inspectCircuit(info, file, curveId) {
+		return info, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s is not a %s circuit, key, proof, witness or bundle", ErrUnknownArtifact, path, curveId)
+}
+
+func inspectBundle(info *ArtifactInfo, bundle *artifact.Bundle) error {
+	info.Type = ArtifactBundle
+	info.Curve = bundle.Header.Curve
+	info.Fingerprint = bundle.Header.Fingerprint
+	info.Sections = bundle.Header.Sections
+
+	if _, ok := bundle.Header.Section(artifact.SectionMetadata); !ok {
+		return nil
+	}
+
+	metadata, err := bundle.ReadMetadata()
+	if err != nil {
+		return err
+	}
+	info.Constraints = metadata.NumConstraints
+	info.PublicInputs = metadata.NumPublicInputs
+	info.SecretInputs = metadata.NumSecretInputs
+
+	return nil
+}
+
+// layout walks a gnark encoding without decoding it. Reads past the end of
+// the file set err, so callers only check it once at the end.
+type layout struct {
+	r    io.ReaderAt
+	size int64
+	off  int64
+	err  error
+}
+
+func newLayout(r io.ReaderAt, size int64) *layout {
+	return &layout{r: r, size: size}
+}
+
+func (l *layout) read(n int64) []byte {
+	if l.err != nil {
+		return nil
+	}
+	if n < 0 || l.off+n > l.size {
+		l.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	buf := make([]byte, n)
+	if _, err := l.r.ReadAt(buf, l.off); err != nil {
+		l.err = err
+		return nil
+	}
+	l.off += n
+	return buf
+}
+
+func (l *layout) skip(n int64) {
+	if l.err != nil {
+		return
+	}
+	if n < 0 || l.off+n > l.size {
+		l.err = io.ErrUnexpectedEOF
+		return
+	}
+	l.off += n
+}
+
+func (l *layout) uint32() uint32 {
+	if buf := l.read(4); buf != nil {
+		return binary.BigEndian.Uint32(buf)
+	}
+	return 0
+}
+
+func (l *layout) uint64() uint64 {
+	if buf := l.read(8); buf != nil {
+		return binary.BigEndian.Uint64(buf)
+	}
+	return 0
+}
+
+// slice64 skips a slice of a key dump, which has a uint64 length prefix, and
+// returns its length.
+func (l *layout) slice64(elementSize int64) int {
+	n := l.uint64()
+	if n > uint64(l.size) {
+		l.err = io.ErrUnexpectedEOF
+		return 0
+	}
+	l.skip(int64(n) * elementSize)
+	return int(n)
+}
+
+// slice skips a length prefixed slice of elements of elementSize bytes and
+// returns its length.
+func (l *layout) slice(elementSize int64) int {
+	n := l.uint32()
+	l.skip(int64(n) * elementSize)
+	return int(n)
+}
+
+// pointSizes returns the encoded size of G1 and G2 points from the flag bits
+// of the first point at the current offset.
+func (l *layout) pointSizes() (format string, g1, g2 int64) {
+	buf := l.read(1)
+	if buf == nil {
+		return "", 0, 0
+	}
+	l.off--
+
+	if buf[0]&0x80 != 0 {
+		return FormatCompressed, curve.SizeOfG1AffineCompressed, curve.SizeOfG2AffineCompressed
+	}
+	return FormatRaw, curve.SizeOfG1AffineUncompressed, curve.SizeOfG2AffineUncompressed
+}
+
+func (l *layout) complete() bool {
+	return l.err == nil && l.off == l.size
+}
+
+// witness: nbPublic, nbSecret, then a length prefixed vector of field elements
+func inspectWitness(info *ArtifactInfo, r io.ReaderAt) bool {
+	l := newLayout(r, info.Size)
+	nbPublic, nbSecret := l.uint32(), l.uint32()
+	n := l.slice(fr.Bytes)
+	if !l.complete() || uint64(n) != uint64(nbPublic)+uint64(nbSecret) {
+		return false
+	}
+
+	info.Type = ArtifactWitness
+	if nbSecret == 0 {
+		info.Type = ArtifactPublicWitness
+	}
+	info.PublicInputs = int(nbPublic)
+	info.SecretInputs = int(nbSecret)
+
+	return true
+}
+
+// proof: Ar, Bs, Krs, commitments, commitment proof of knowledge
+func inspectProof(info *ArtifactInfo, r io.ReaderAt) (bool, error) {
+	l := newLayout(r, info.Size)
+	format, g1, g2 := l.pointSizes()
+	l.skip(g1 + g2 + g1)
+	l.slice(g1)
+	l.skip(g1)
+	if !l.complete() {
+		return false, nil
+	}
+
+	var proof groth16_bls12381.Proof
+	if _, err := proof.ReadFrom(io.NewSectionReader(r, 0, info.Size)); err != nil {
+		return true, fmt.Errorf("failed to read proof: %w", err)
+	}
+
+	info.Type = ArtifactProof
+	info.Format = format
+	info.Commitments = len(proof.Commitments)
+	info.Points = []Point{g1Point("ar", &proof.Ar), g2Point("bs", &proof.Bs), g1Point("krs", &proof.Krs)}
+	for i := range proof.Commitments {
+		info.Points = append(info.Points, g1Point(fmt.Sprintf("commitments[%d]", i), &proof.Commitments[i]))
+	}
+	if len(proof.Commitments) > 0 {
+		info.Points = append(info.Points, g1Point("commitment_pok", &proof.CommitmentPok))
+	}
+
+	return true, nil
+}
+
+// verifying key: α₁, β₁, β₂, γ₂, δ₁, δ₂, K, public and commitment committed
+// indexes, then the commitment key
+func inspectVerifyingKey(info *ArtifactInfo, r io.ReaderAt) (bool, error) {
+	l := newLayout(r, info.Size)
+	format, g1, g2 := l.pointSizes()
+	l.skip(3*g1 + 3*g2)
+	l.slice(g1)
+	committed := l.uint32()
+	for i := uint32(0); i < committed && l.err == nil; i++ {
+		l.slice(8)
+	}
+	l.skip(2 * g2)
+	if !l.complete() {
+		return false, nil
+	}
+
+	vk := groth16.NewVerifyingKey(ecc.BLS12_381)
+	if _, err := vk.UnsafeReadFrom(io.NewSectionReader(r, 0, info.Size)); err != nil {
+		return true, fmt.Errorf("failed to read verifying key: %w", err)
+	}
+	key := vk.(*groth16_bls12381.VerifyingKey)
+
+	info.Type = ArtifactVerifyingKey
+	info.Format = format
+	info.PublicInputs = vk.NbPublicWitness()
+	info.G1Points = vk.NbG1()
+	info.G2Points = vk.NbG2()
+	info.Commitments = len(key.PublicAndCommitmentCommitted)
+	info.Points = []Point{
+		g1Point("alpha", &key.G1.Alpha),
+		g2Point("beta", &key.G2.Beta),
+		g2Point("gamma", &key.G2.Gamma),
+		g2Point("delta", &key.G2.Delta),
+	}
+	for i := range key.G1.K {
+		info.Points = append(info.Points, g1Point(fmt.Sprintf("k[%d]", i), &key.G1.K[i]))
+	}
+
+	return true, nil
+}
+
+// proving key: domain, α₁, β₁, δ₁, A, B, Z, K, β₂, δ₂, B₂, wire and infinity
+// counts, infinity flags, then the commitment keys
+func inspectProvingKey(info *ArtifactInfo, r io.ReaderAt) bool {
+	l := newLayout(r, info.Size)
+	domainSize := l.uint64()
+	if domainSize == 0 || domainSize&(domainSize-1) != 0 {
+		return false
+	}
+	l.skip(5 * fr.Bytes)
+
+	format, g1, g2 := l.pointSizes()
+	alpha := l.read(g1)
+	l.skip(2 * g1)
+	nbG1 := 3
+	for i := 0; i < 4; i++ {
+		nbG1 += l.slice(g1)
+	}
+	l.skip(2 * g2)
+	nbG2 := 2 + l.slice(g2)
+
+	nbWires := l.uint64()
+	l.skip(16)
+	if nbWires > uint64(info.Size) {
+		return false
+	}
+	l.skip(2 * int64(nbWires))
+
+	commitments := l.uint32()
+	for i := uint32(0); i < commitments && l.err == nil; i++ {
+		// basis and basis^σ of each pedersen key
+		l.slice(g1)
+		l.slice(g1)
+	}
+	if !l.complete() {
+		return false
+	}
+
+	// α₁ is shared with the verifying key, so it tells which one belongs to
+	// the proving key
+	var alphaPoint curve.G1Affine
+	if _, err := alphaPoint.SetBytes(alpha); err != nil {
+		return false
+	}
+
+	info.Type = ArtifactProvingKey
+	info.Format = format
+	info.DomainSize = domainSize
+	info.Points = []Point{g1Point("alpha", &alphaPoint)}
+	info.G1Points = nbG1
+	info.G2Points = nbG2
+	info.Commitments = int(commitments)
+
+	return true
+}
+
+// key dumps, see artifact.WriteProvingKeyDump: points are stored as laid out
+// in memory and the commitment keys are raw encoded
+func inspectDump(info *ArtifactInfo, r io.ReaderAt) (bool, error) {
+	l := newLayout(r, info.Size)
+	kind := artifact.DumpKind(l.read(int64(artifact.DumpHeaderSize)))
+	if kind == 0 {
+		return false, nil
+	}
+
+	g1 := int64(unsafe.Sizeof(curve.G1Affine{}))
+	g2 := int64(unsafe.Sizeof(curve.G2Affine{}))
+
+	switch kind {
+	case artifact.DumpProvingKey:
+		info.Type = ArtifactProvingKey
+		info.DomainSize = l.uint64()
+		l.skip(5 * fr.Bytes)
+		info.Points = dumpAlpha(l, g1)
+		info.G1Points = 3
+		for i := 0; i < 4; i++ {
+			info.G1Points += l.slice64(g1)
+		}
+		for i := 0; i < 2; i++ {
+			info.G2Points += l.slice64(g2)
+		}
+		l.slice64(1)
+		l.slice64(1)
+		l.skip(16)
+		commitments := l.uint64()
+		for i := uint64(0); i < commitments && l.err == nil; i++ {
+			l.slice(curve.SizeOfG1AffineUncompressed)
+			l.slice(curve.SizeOfG1AffineUncompressed)
+		}
+		info.Commitments = int(commitments)
+	case artifact.DumpVerifyingKey:
+		info.Type = ArtifactVerifyingKey
+		info.Points = dumpAlpha(l, g1)
+		k := l.slice64(g1)
+		info.G1Points = 3 + k
+		info.G2Points = l.slice64(g2)
+		committed := l.uint64()
+		for i := uint64(0); i < committed && l.err == nil; i++ {
+			l.slice64(8)
+		}
+		l.skip(2 * curve.SizeOfG2AffineUncompressed)
+		info.Commitments = int(committed)
+		info.PublicInputs = k - 1
+	default:
+		return true, fmt.Errorf("%w: unknown key type %d", artifact.ErrInvalidDump, kind)
+	}
+	if !l.complete() {
+		return true, fmt.Errorf("%w: truncated or corrupted %s", artifact.ErrInvalidDump, info.Type)
+	}
+
+	info.Format = FormatDump
+
+	return true, nil
+}
+
+// dumpAlpha reads the α₁, β₁, δ₁ slice a dump starts its points with and
+// returns α₁.
+func dumpAlpha(l *layout, g1 int64) []Point {
+	if n := l.uint64(); n != 3 {
+		l.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	buf := l.read(g1)
+	l.skip(2 * g1)
+	if buf == nil {
+		return nil
+	}
+
+	var alpha curve.G1Affine
+	copy(unsafe.Slice((*byte)(unsafe.Pointer(&alpha)), g1), buf)
+	return []Point{g1Point("alpha", &alpha)}
+}
+
+// circuits are cbor encoded, anything that decodes as one is taken for one
+func inspectCircuit(info *ArtifactInfo, r io.ReaderAt, curveId ecc.ID) bool {
+	head := make([]byte, 1)
+	if _, err := r.ReadAt(head, 0); err != nil {
+		return false
+	}
+	// cbor maps and tags
+	if head[0]>>5 != 5 && head[0]>>5 != 6 {
+		return false
+	}
+
+	ccs := groth16.NewCS(curveId)
+	if _, err := ccs.ReadFrom(io.NewSectionReader(r, 0, info.Size)); err != nil {
+		return false
+	}
+
+	info.Type = ArtifactCircuit
+	info.Constraints = ccs.GetNbConstraints()
+	info.InternalVariables = ccs.GetNbInternalVariables()
+	info.SecretVariables = ccs.GetNbSecretVariables()
+	info.PublicVariables = ccs.GetNbPublicVariables()
+	// the first public variable is the constant one wire
+	info.PublicInputs = info.PublicVariables - 1
+	info.SecretInputs = info.SecretVariables
+	if commitments := ccs.GetCommitments(); commitments != nil {
+		info.Commitments = len(commitments.CommitmentIndexes())
+	}
+
+	return true
+}
+
+func g1Point(name string, p *curve.G1Affine) Point {
+	b := p.Bytes()
+	return Point{Name: name, Group: "G1", Value: hex.EncodeToString(b[:])}
+}
+
+func g2Point(name string, p *curve.G2Affine) Point {
+	b := p.Bytes()
+	return Point{Name: name, Group: "G2", Value: hex.EncodeToString(b[:])}
+}
@@ -0,0 +1,50 @@
+package worker
+
+import (
+	"context"
+	"runtime"
+)
+
+// ProverLimits bounds how many proofs a Groth16Prover runs at the same time.
+type ProverLimits struct {
+	// MaxConcurrentProofs caps the number of proofs in flight. Zero means one.
+	MaxConcurrentProofs int
+	// CPUsPerProof is the CPU budget of a single proof. gnark spreads every
+	// proof over all cores, so the budget is enforced at admission: at most
+	// GOMAXPROCS/CPUsPerProof proofs run at once. Zero disables the budget.
+	CPUsPerProof int
+}
+
+// Slots returns the number of proofs that may run at the same time.
+func (l ProverLimits) Slots() int {
+	slots := l.MaxConcurrentProofs
+	if slots <= 0 {
+		slots = 1
+	}
+
+	if l.CPUsPerProof > 0 {
+		slots = min(slots, max(1, runtime.GOMAXPROCS(0)/l.CPUsPerProof))
+	}
+
+	return slots
+}
+
+// proofSlots is a counting semaphore for proofs in flight.
+type proofSlots chan struct{}
+
+func newProofSlots(limits ProverLimits) proofSlots {
+	return make(proofSlots, limits.Slots())
+}
+
+func (s proofSlots) acquire(ctx context.Context) error {
+	select {
+	case s <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s proofSlots) release() {
+	<-s
+}
@@ -0,0 +1,122 @@
+package worker
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+// PhaseStats are the resources used by one run of a phase. Durations are in
+// nanoseconds in JSON.
+type PhaseStats struct {
+	Phase Phase `json:"phase"`
+	// Label tells apart runs of the same phase, like reads of a key in
+	// different formats. It is set by the caller, not by the Meter.
+	Label string `json:"label,omitempty"`
+
+	Wall time.Duration `json:"wall_time"`
+	// CPU is the user and system time of the whole process, and PeakRSS its
+	// peak resident set while the phase ran. Both are zero on platforms the
+	// meter can't read them on, and PeakRSS is only reset between phases on
+	// linux; elsewhere it is the peak of the process so far.
+	CPU     time.Duration `json:"cpu_time"`
+	PeakRSS int64         `json:"peak_rss"`
+	// Allocs and AllocBytes count heap allocations of the whole process.
+	Allocs     uint64 `json:"allocs"`
+	AllocBytes uint64 `json:"alloc_bytes"`
+
+	Constraints int    `json:"constraints,omitempty"`
+	BytesRead   int64  `json:"bytes_read,omitempty"`
+	Estimated   bool   `json:"estimated,omitempty"`
+	Err         string `json:"error,omitempty"`
+}
+
+// usageSnapshot is the process usage at the start of a phase.
+type usageSnapshot struct {
+	cpu        time.Duration
+	allocs     uint64
+	allocBytes uint64
+}
+
+func takeUsageSnapshot() usageSnapshot {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	return usageSnapshot{cpu: processCPUTime(), allocs: mem.Mallocs, allocBytes: mem.TotalAlloc}
+}
+
+// Meter is an Observer that measures the resources used by each phase it is
+// told about. CPU time, memory and allocations are process wide, so phases
+// measured by the same Meter must not overlap. Estimated phases, like solve
+// and msm, are reported after they ran and only get their wall time.
+type Meter struct {
+	// Next, if set, also receives every event.
+	Next Observer
+
+	mu      sync.Mutex
+	started map[Phase]usageSnapshot
+	stats   []PhaseStats
+}
+
+func (m *Meter) Observe(e Event) {
+	if m.Next != nil {
+		m.Next.Observe(e)
+	}
+
+	switch e.Kind {
+	case PhaseStarted:
+		if e.Estimated {
+			return
+		}
+		// reset the peak before the snapshot, the snapshot allocates
+		resetPeakRSS()
+		snapshot := takeUsageSnapshot()
+
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if m.started == nil {
+			m.started = make(map[Phase]usageSnapshot)
+		}
+		m.started[e.Phase] = snapshot
+	case PhaseFinished:
+		end := takeUsageSnapshot()
+		stats := PhaseStats{
+			Phase:       e.Phase,
+			Wall:        e.Duration,
+			Constraints: e.Constraints,
+			BytesRead:   e.BytesRead,
+			Estimated:   e.Estimated,
+		}
+		if e.Err != nil {
+			stats.Err = e.Err.Error()
+		}
+
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if start, ok := m.started[e.Phase]; ok {
+			delete(m.started, e.Phase)
+			stats.CPU = end.cpu - start.cpu
+			stats.PeakRSS = peakRSS()
+			stats.Allocs = end.allocs - start.allocs
+			stats.AllocBytes = end.allocBytes - start.allocBytes
+		}
+		m.stats = append(m.stats, stats)
+	}
+}
+
+// Measure runs fn as phase, for steps that don't report events themselves.
+func (m *Meter) Measure(phase Phase, fn func() error) error {
+	timer := startPhase(m, phase)
+	err := fn()
+	timer.end(err)
+	return err
+}
+
+// Take returns the stats of the phases finished since the last call.
+func (m *Meter) Take() []PhaseStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := m.stats
+	m.stats = nil
+	return stats
+}
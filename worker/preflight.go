@@ -0,0 +1,432 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/zilong-dai/groth16-worker/artifact"
+)
+
+var ErrPreflightFailed = errors.New("preflight failed")
+
+// Rough needs of a groth16 setup or prove on BLS12-381, per constraint. They
+// err on the high side: they are meant to catch machines that are clearly too
+// small before a run of several minutes, not to predict usage.
+const (
+	setupMemoryPerConstraint = 3 << 10
+	proveMemoryPerConstraint = 2 << 10
+	// raw proving key, r1cs and verifying key
+	setupDiskPerConstraint = 1 << 10
+	// proofs and public witnesses are a few hundred bytes
+	proveDiskNeeded = 1 << 20
+)
+
+type CheckStatus string
+
+const (
+	CheckOK      CheckStatus = "ok"
+	CheckFail    CheckStatus = "fail"
+	CheckSkipped CheckStatus = "skipped"
+)
+
+// PreflightCheck is the outcome of one check of a preflight.
+type PreflightCheck struct {
+	Name    string      `json:"name"`
+	Status  CheckStatus `json:"status"`
+	Message string      `json:"message,omitempty"`
+}
+
+func (c PreflightCheck) String() string {
+	return fmt.Sprintf("%-7s %s: %s", c.Status, c.Name, c.Message)
+}
+
+// PreflightOptions describes a setup or prove about to run. Empty paths are
+// not checked.
+type PreflightOptions struct {
+	// Phase is PhaseSetup or PhaseProve, and picks the memory and disk
+	// estimates.
+	Phase Phase
+	Curve ecc.ID
+	// Dir holds the plonky2 inputs.
+	Dir string
+
+	// Circuit, ProvingKey, VerifyingKey and Bundle are existing artifacts
+	// the run will read.
+	Circuit      string
+	ProvingKey   string
+	VerifyingKey string
+	Bundle       string
+
+	// Outputs are the files the run will write.
+	Outputs []string
+
+	// Constraints is the expected constraint count, used for the estimates
+	// when no circuit, proving key or bundle gives it.
+	Constraints int
+}
+
+// PreflightReport lists the checks of a preflight, in the order they ran.
+// Memory and disk sizes are in bytes.
+type PreflightReport struct {
+	OK              bool             `json:"ok"`
+	Phase           Phase            `json:"phase"`
+	Constraints     int              `json:"constraints,omitempty"`
+	MemoryNeeded    int64            `json:"memory_needed,omitempty"`
+	MemoryAvailable int64            `json:"memory_available,omitempty"`
+	DiskNeeded      int64            `json:"disk_needed,omitempty"`
+	Checks          []PreflightCheck `json:"checks"`
+}
+
+func (r *PreflightReport) add(name string, status CheckStatus, format string, args ...any) {
+	r.Checks = append(r.Checks, PreflightCheck{Name: name, Status: status, Message: fmt.Sprintf(format, args...)})
+	if status == CheckFail {
+		r.OK = false
+	}
+}
+
+// Err returns an error naming the failed checks, or nil if none failed.
+func (r *PreflightReport) Err() error {
+	var failed []string
+	for _, check := range r.Checks {
+		if check.Status == CheckFail {
+			failed = append(failed, check.Name)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s", ErrPreflightFailed, strings.Join(failed, ", "))
+}
+
+// Preflight checks, in seconds, what would otherwise make a setup or prove
+// fail after minutes: unparseable inputs, artifacts that don't belong
+// together, too little memory or disk, and outputs that can't be written.
+// Failures are reported in the returned report, see Err.
+func Preflight(opts PreflightOptions) *PreflightReport {
+	report := &PreflightReport{OK: true, Phase: opts.Phase}
+
+	if opts.Phase != PhaseSetup && opts.Phase != PhaseProve {
+		report.add("phase", CheckFail, "unknown phase %q, expected %s or %s", opts.Phase, PhaseSetup, PhaseProve)
+		return report
+	}
+	if err := CheckCurve(opts.Curve); err != nil {
+		report.add("curve", CheckFail, "%v", err)
+		return report
+	}
+	report.add("curve", CheckOK, "%s", opts.Curve)
+
+	// fingerprints of everything that has one, by source
+	fingerprints := make(map[string]string)
+
+	if opts.Dir == "" {
+		report.add("inputs", CheckSkipped, "no plonky2 input directory")
+	} else if fingerprint, err := preflightInputs(opts.Dir); err != nil {
+		report.add("inputs", CheckFail, "%v", err)
+	} else {
+		report.add("inputs", CheckOK, "parsed the plonky2 inputs in %s", opts.Dir)
+		fingerprints["inputs"] = fingerprint
+	}
+
+	artifacts := []struct {
+		name string
+		path string
+		typ  ArtifactType
+	}{
+		{"circuit", opts.Circuit, ArtifactCircuit},
+		{"proving_key", opts.ProvingKey, ArtifactProvingKey},
+		{"verifying_key", opts.VerifyingKey, ArtifactVerifyingKey},
+		{"bundle", opts.Bundle, ArtifactBundle},
+	}
+	infos := make(map[ArtifactType]*ArtifactInfo)
+	for _, a := range artifacts {
+		if a.path == "" {
+			continue
+		}
+		info, err := preflightArtifact(a.path, a.typ, opts.Curve)
+		if err != nil {
+			report.add(a.name, CheckFail, "%v", err)
+			continue
+		}
+		report.add(a.name, CheckOK, "%s %s, %d bytes", a.path, describeFormat(info), info.Size)
+		infos[a.typ] = info
+		if info.Fingerprint != "" {
+			fingerprints[a.name] = info.Fingerprint
+		}
+	}
+
+	preflightFingerprints(report, fingerprints)
+	preflightConsistency(report, infos)
+
+	report.Constraints = opts.Constraints
+	switch {
+	case infos[ArtifactCircuit] != nil:
+		report.Constraints = infos[ArtifactCircuit].Constraints
+	case infos[ArtifactBundle] != nil && infos[ArtifactBundle].Constraints > 0:
+		report.Constraints = infos[ArtifactBundle].Constraints
+	case infos[ArtifactProvingKey] != nil:
+		// an upper bound, the domain is rounded up to a power of two
+		report.Constraints = int(infos[ArtifactProvingKey].DomainSize)
+	}
+
+	preflightResources(report, opts)
+	preflightOutputs(report, opts.Outputs)
+
+	return report
+}
+
+func preflightInputs(dir string) (string, error) {
+	inputs, err := NewPlonky2InputsFromDir(dir)
+	if err != nil {
+		return "", err
+	}
+	if err := inputs.Validate(); err != nil {
+		return "", err
+	}
+
+	return inputs.Fingerprint()
+}
+
+// preflightArtifact inspects an artifact and checks it is of type typ. The
+// sections of bundles are checked against their checksums.
+func preflightArtifact(path string, typ ArtifactType, curveId ecc.ID) (*ArtifactInfo, error) {
+	info, err := InspectArtifact(path, curveId)
+	if err != nil {
+		return nil, err
+	}
+	if info.Type != typ {
+		return nil, fmt.Errorf("%w: %s is a %s, expected a %s", ErrUnknownArtifact, path, info.Type, typ)
+	}
+
+	if typ == ArtifactBundle {
+		bundle, err := artifact.OpenBundle(path)
+		if err != nil {
+			return nil, err
+		}
+		defer bundle.Close()
+
+		if err := bundle.Header.Check(curveId); err != nil {
+			return nil, err
+		}
+		if err := bundle.Verify(); err != nil {
+			return nil, err
+		}
+	}
+
+	return info, nil
+}
+
+func describeFormat(info *ArtifactInfo) string {
+	if info.Format == "" {
+		return string(info.Type)
+	}
+	return fmt.Sprintf("%s %s", info.Format, info.Type)
+}
+
+func preflightFingerprints(report *PreflightReport, fingerprints map[string]string) {
+	if len(fingerprints) == 0 {
+		report.add("fingerprint", CheckSkipped, "no fingerprints to compare")
+		return
+	}
+
+	sources := make([]string, 0, len(fingerprints))
+	distinct := make(map[string]bool)
+	for source, fingerprint := range fingerprints {
+		sources = append(sources, source)
+		distinct[fingerprint] = true
+	}
+	sort.Strings(sources)
+
+	if len(sources) == 1 {
+		report.add("fingerprint", CheckOK, "only %s has a fingerprint, circuit %s", sources[0], fingerprints[sources[0]])
+		return
+	}
+	if len(distinct) == 1 {
+		report.add("fingerprint", CheckOK, "%s agree on circuit %s", strings.Join(sources, ", "), fingerprints[sources[0]])
+		return
+	}
+
+	parts := make([]string, len(sources))
+	for i, source := range sources {
+		parts[i] = fmt.Sprintf("%s is circuit %s", source, fingerprints[source])
+	}
+	report.add("fingerprint", CheckFail, "%v: %s", ErrFingerprintMismatch, strings.Join(parts, ", "))
+}
+
+// preflightConsistency checks that the circuit and keys were set up
+// together, as far as their headers tell.
+func preflightConsistency(report *PreflightReport, infos map[ArtifactType]*ArtifactInfo) {
+	circuit, pk, vk := infos[ArtifactCircuit], infos[ArtifactProvingKey], infos[ArtifactVerifyingKey]
+
+	var problems []string
+	checked := 0
+	if circuit != nil && pk != nil {
+		checked++
+		if want := domainSize(circuit.Constraints); pk.DomainSize != want {
+			problems = append(problems, fmt.Sprintf("proving key has a domain of %d, the circuit needs %d", pk.DomainSize, want))
+		}
+		if pk.Commitments != circuit.Commitments {
+			problems = append(problems, fmt.Sprintf("proving key has %d commitments, the circuit %d", pk.Commitments, circuit.Commitments))
+		}
+	}
+	if circuit != nil && vk != nil {
+		checked++
+		if vk.PublicInputs != circuit.PublicInputs {
+			problems = append(problems, fmt.Sprintf("verifying key has %d public inputs, the circuit %d", vk.PublicInputs, circuit.PublicInputs))
+		}
+	}
+	if pk != nil && vk != nil {
+		checked++
+		if alpha(pk) != alpha(vk) {
+			problems = append(problems, "proving and verifying keys come from different setups")
+		}
+	}
+
+	switch {
+	case checked == 0:
+		report.add("consistency", CheckSkipped, "need at least two of a circuit, proving key and verifying key")
+	case len(problems) > 0:
+		report.add("consistency", CheckFail, "%s", strings.Join(problems, "; "))
+	default:
+		report.add("consistency", CheckOK, "circuit and keys belong together")
+	}
+}
+
+// domainSize is the size of the fft domain groth16.Setup uses for a circuit
+// of n constraints.
+func domainSize(n int) uint64 {
+	if n <= 1 {
+		return 1
+	}
+	return 1 << bits.Len64(uint64(n-1))
+}
+
+func alpha(info *ArtifactInfo) string {
+	for _, p := range info.Points {
+		if p.Name == "alpha" {
+			return p.Value
+		}
+	}
+	return ""
+}
+
+func preflightResources(report *PreflightReport, opts PreflightOptions) {
+	if report.Constraints <= 0 {
+		report.add("memory", CheckSkipped, "unknown constraint count, give a circuit, proving key or constraint count")
+		report.add("disk", CheckSkipped, "unknown constraint count")
+		return
+	}
+
+	n := int64(report.Constraints)
+	report.MemoryNeeded = n * proveMemoryPerConstraint
+	report.DiskNeeded = proveDiskNeeded
+	if opts.Phase == PhaseSetup {
+		report.MemoryNeeded = n * setupMemoryPerConstraint
+		report.DiskNeeded = n * setupDiskPerConstraint
+	}
+
+	report.MemoryAvailable = availableMemory()
+	switch {
+	case report.MemoryAvailable == 0:
+		report.add("memory", CheckSkipped, "available memory is unknown on this platform")
+	case report.MemoryAvailable < report.MemoryNeeded:
+		report.add("memory", CheckFail, "%s needs about %s for %d constraints, %s available", opts.Phase, formatBytes(report.MemoryNeeded), n, formatBytes(report.MemoryAvailable))
+	default:
+		report.add("memory", CheckOK, "%s needs about %s for %d constraints, %s available", opts.Phase, formatBytes(report.MemoryNeeded), n, formatBytes(report.MemoryAvailable))
+	}
+
+	if len(opts.Outputs) == 0 {
+		report.add("disk", CheckSkipped, "no outputs")
+		return
+	}
+
+	// every output directory must fit everything, they are usually the same
+	dirs := make(map[string]bool)
+	for _, output := range opts.Outputs {
+		dirs[filepath.Dir(output)] = true
+	}
+	var low []string
+	var unknown int
+	for dir := range dirs {
+		free := freeDiskSpace(dir)
+		switch {
+		case free == 0:
+			unknown++
+		case free < report.DiskNeeded:
+			low = append(low, fmt.Sprintf("%s has %s free", dir, formatBytes(free)))
+		}
+	}
+	sort.Strings(low)
+
+	switch {
+	case len(low) > 0:
+		report.add("disk", CheckFail, "outputs need about %s, %s", formatBytes(report.DiskNeeded), strings.Join(low, ", "))
+	case unknown == len(dirs):
+		report.add("disk", CheckSkipped, "free disk space is unknown")
+	default:
+		report.add("disk", CheckOK, "outputs need about %s", formatBytes(report.DiskNeeded))
+	}
+}
+
+// preflightOutputs makes sure every output can be created or overwritten,
+// without touching existing files.
+func preflightOutputs(report *PreflightReport, outputs []string) {
+	if len(outputs) == 0 {
+		report.add("outputs", CheckSkipped, "no outputs")
+		return
+	}
+
+	var problems []string
+	for _, output := range outputs {
+		if err := checkWritable(output); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if len(problems) > 0 {
+		report.add("outputs", CheckFail, "%s", strings.Join(problems, "; "))
+		return
+	}
+	report.add("outputs", CheckOK, "%d outputs are writable", len(outputs))
+}
+
+func checkWritable(path string) error {
+	if stat, err := os.Stat(path); err == nil {
+		if !stat.Mode().IsRegular() {
+			return fmt.Errorf("%s exists and is not a regular file", path)
+		}
+		file, err := os.OpenFile(path, os.O_WRONLY, 0)
+		if err != nil {
+			return err
+		}
+		return file.Close()
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), ".preflight-*")
+	if err != nil {
+		return fmt.Errorf("can't create %s: %w", path, err)
+	}
+	file.Close()
+	return os.Remove(file.Name())
+}
+
+func formatBytes(n int64) string {
+	const unit = 1 << 10
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
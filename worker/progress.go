@@ -0,0 +1,219 @@
+package worker
+
+import (
+	"context"
+	"io"
+	"math/big"
+	"sync/atomic"
+	"time"
+
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/constraint/solver"
+)
+
+type Phase string
+
+const (
+	PhaseCompile          Phase = "compile"
+	PhaseSetup            Phase = "setup"
+	PhaseReadProvingKey   Phase = "read_proving_key"
+	PhaseReadVerifyingKey Phase = "read_verifying_key"
+	PhaseReadCircuit      Phase = "read_circuit"
+	PhaseWitness          Phase = "witness"
+	PhaseProve            Phase = "prove"
+	// PhaseSolve and PhaseMSM split a prove in two. gnark doesn't report
+	// when it is done solving, so the split is estimated and both are only
+	// reported, with Estimated set, once the prove is over.
+	PhaseSolve  Phase = "solve"
+	PhaseMSM    Phase = "msm"
+	PhaseVerify Phase = "verify"
+)
+
+type EventKind int
+
+const (
+	PhaseStarted EventKind = iota
+	PhaseProgress
+	PhaseFinished
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case PhaseStarted:
+		return "started"
+	case PhaseProgress:
+		return "progress"
+	case PhaseFinished:
+		return "finished"
+	default:
+		return "unknown"
+	}
+}
+
+// Event describes a step of a long running phase. Duration is the time since
+// the phase started, Constraints is set when a compile finishes and BytesRead
+// while keys and circuits are being read. Estimated is set on events whose
+// times are guessed after the fact rather than measured.
+type Event struct {
+	Phase       Phase
+	Kind        EventKind
+	Time        time.Time
+	Duration    time.Duration
+	Constraints int
+	BytesRead   int64
+	Estimated   bool
+	Err         error
+}
+
+// Observer receives progress events. Phases may run on background
+// goroutines, so implementations must be safe for concurrent use.
+type Observer interface {
+	Observe(Event)
+}
+
+type ObserverFunc func(Event)
+
+func (f ObserverFunc) Observe(e Event) {
+	f(e)
+}
+
+type observerKey struct{}
+
+// WithObserver returns a context whose calls to a prover also send their
+// events to observer, besides the prover's Observer. It tells the events of
+// concurrent ProvePlonky2 and ProveWitness calls apart.
+func WithObserver(ctx context.Context, observer Observer) context.Context {
+	return context.WithValue(ctx, observerKey{}, observer)
+}
+
+// contextObserver returns observer joined with the observer of ctx, if any.
+func contextObserver(ctx context.Context, observer Observer) Observer {
+	extra, _ := ctx.Value(observerKey{}).(Observer)
+	switch {
+	case extra == nil:
+		return observer
+	case observer == nil:
+		return extra
+	default:
+		return ObserverFunc(func(e Event) {
+			observer.Observe(e)
+			extra.Observe(e)
+		})
+	}
+}
+
+// phaseTimer emits the events of a single phase to an optional observer.
+type phaseTimer struct {
+	observer Observer
+	phase    Phase
+	start    time.Time
+}
+
+func startPhase(observer Observer, phase Phase) *phaseTimer {
+	p := &phaseTimer{observer: observer, phase: phase, start: time.Now()}
+	p.emit(Event{Kind: PhaseStarted})
+	return p
+}
+
+func (p *phaseTimer) emit(e Event) {
+	if p.observer == nil {
+		return
+	}
+
+	e.Phase = p.phase
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.Duration = e.Time.Sub(p.start)
+	p.observer.Observe(e)
+}
+
+func (p *phaseTimer) end(err error) {
+	p.emit(Event{Kind: PhaseFinished, Err: err})
+}
+
+// progressInterval is how many bytes are read between two progress events.
+const progressInterval = 64 << 20
+
+// progressReader counts the bytes read through it and reports them every
+// progressInterval bytes.
+type progressReader struct {
+	r        io.Reader
+	phase    *phaseTimer
+	read     int64
+	reported int64
+}
+
+func newProgressReader(r io.Reader, phase *phaseTimer) *progressReader {
+	return &progressReader{r: r, phase: phase}
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.read += int64(n)
+	if r.read-r.reported >= progressInterval {
+		r.reported = r.read
+		r.phase.emit(Event{Kind: PhaseProgress, BytesRead: r.read})
+	}
+	return n, err
+}
+
+func (r *progressReader) end(err error) {
+	r.phase.emit(Event{Kind: PhaseFinished, BytesRead: r.read, Err: err})
+}
+
+// solveTimer wraps every hint of a prove. It records when the solver last
+// called a hint: groth16.Prove solves the witness before running the MSMs and
+// hints are only called while solving, so the last call is an estimate of the
+// end of the solve. It misses the constraints solved after the last hint.
+//
+// Once ctx is done, the hints fail instead of running, so a cancelled prove
+// stops solving at the next hint and frees its solution.
+type solveTimer struct {
+	ctx  context.Context
+	last atomic.Int64
+}
+
+func newSolveTimer(ctx context.Context) *solveTimer {
+	return &solveTimer{ctx: ctx}
+}
+
+func (t *solveTimer) proverOption() backend.ProverOption {
+	hints := solver.GetRegisteredHints()
+	opts := make([]solver.Option, 0, len(hints))
+	for _, hint := range hints {
+		opts = append(opts, solver.OverrideHint(solver.GetHintID(hint), t.wrap(hint)))
+	}
+
+	return backend.WithSolverOptions(opts...)
+}
+
+func (t *solveTimer) wrap(hint solver.Hint) solver.Hint {
+	return func(field *big.Int, inputs []*big.Int, outputs []*big.Int) error {
+		if err := t.ctx.Err(); err != nil {
+			return err
+		}
+		err := hint(field, inputs, outputs)
+		t.last.Store(time.Now().UnixNano())
+		return err
+	}
+}
+
+// report emits the estimated solve and MSM phases of a prove that started
+// with phase and finished at end, each as a started and a finished event. A
+// prove that called no hint can't be split and reports neither.
+func (t *solveTimer) report(phase *phaseTimer, end time.Time) {
+	last := t.last.Load()
+	if phase.observer == nil || last == 0 {
+		return
+	}
+	solved := time.Unix(0, last)
+
+	solve := &phaseTimer{observer: phase.observer, phase: PhaseSolve, start: phase.start}
+	solve.emit(Event{Kind: PhaseStarted, Time: phase.start, Estimated: true})
+	solve.emit(Event{Kind: PhaseFinished, Time: solved, Estimated: true})
+
+	msm := &phaseTimer{observer: phase.observer, phase: PhaseMSM, start: solved}
+	msm.emit(Event{Kind: PhaseStarted, Time: solved, Estimated: true})
+	msm.emit(Event{Kind: PhaseFinished, Time: end, Estimated: true})
+}
@@ -1,40 +1,58 @@
 package worker
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
-	"github.com/cf/gnark-plonky2-verifier/types"
-	"github.com/cf/gnark-plonky2-verifier/variables"
-	"github.com/cf/gnark-plonky2-verifier/verifier"
 	"github.com/consensys/gnark-crypto/ecc"
 	"github.com/consensys/gnark/backend/groth16"
 	"github.com/consensys/gnark/backend/witness"
-	"github.com/consensys/gnark/frontend"
-	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/constraint"
+	"github.com/zilong-dai/groth16-worker/artifact"
 )
 
+// NewGroth16Prover returns a prover for the plonky2 inputs in Path. Nothing
+// is read until a step needs it; Prove loads the circuit of the inputs from
+// the compile cache if none was compiled or read, so only the proving key is
+// left to read.
 func NewGroth16Prover(Path string, curveId ecc.ID) (*Groth16Prover, error) {
+	if err := CheckCurve(curveId); err != nil {
+		return nil, err
+	}
+
 	pk := groth16.NewProvingKey(curveId)
 	vk := groth16.NewVerifyingKey(curveId)
 
 	proof := groth16.NewProof(curveId)
 
 	witness, err := witness.New(curveId.ScalarField())
+	if err != nil {
+		return nil, fmt.Errorf("error creating witness: %w", err)
+	}
+	publicWitness, err := witness.Public()
 	if err != nil {
 		return nil, fmt.Errorf("error creating public witness: %w", err)
 	}
 	r1cs := groth16.NewCS(curveId)
-	// PublicWitness: publicWitness
-	return &Groth16Prover{Path: Path, pk: pk, vk: vk, Proof: proof, Witness: witness, curveId: curveId, r1cs: r1cs}, nil
+
+	return &Groth16Prover{Path: Path, pk: pk, vk: vk, Proof: proof, Witness: witness, PublicWitness: publicWitness, r1cs: r1cs, curveId: curveId, Cache: DefaultCompileCache(), slots: newProofSlots(ProverLimits{})}, nil
+}
+
+// SetLimits sets how many proofs ProvePlonky2 and ProveWitness run at the
+// same time. Calls beyond the limit wait for a free slot. It must not be
+// called while proofs are running.
+func (w *Groth16Prover) SetLimits(limits ProverLimits) {
+	w.slots = newProofSlots(limits)
 }
 
 func (w *Groth16Prover) CheckPath() error {
 	files := []string{
-		"common_circuit_data.json",
-		"proof_with_public_inputs.json",
-		"verifier_only_circuit_data.json",
+		CommonCircuitDataFile,
+		ProofWithPublicInputsFile,
+		VerifierOnlyCircuitDataFile,
 	}
 
 	for _, file := range files {
@@ -51,114 +69,312 @@ func (w *Groth16Prover) CheckPath() error {
 	return nil
 }
 
+// inputsFingerprint fingerprints the circuit data set with SetCircuitData, or
+// the plonky2 inputs in Path.
+func (w *Groth16Prover) inputsFingerprint() (string, error) {
+	if w.circuitData != nil {
+		return w.circuitData.Fingerprint()
+	}
+	return FingerprintFromDir(w.Path)
+}
+
+// loadCachedCircuit loads the circuit of the circuit data set with
+// SetCircuitData, or of the plonky2 inputs in Path, from the compile cache. It
+// leaves the prover alone on a miss, or if there are no inputs.
+func (w *Groth16Prover) loadCachedCircuit(ctx context.Context) error {
+	circuitData := w.circuitData
+	if circuitData == nil {
+		var err error
+		if circuitData, err = circuitDataFromDir(w.Path); err != nil || circuitData == nil {
+			return err
+		}
+	}
+
+	r1cs, key, err := cachedCircuit(ctx, w.Cache, circuitData, w.curveId, w.Observer)
+	if err != nil || r1cs == nil {
+		return err
+	}
+
+	fingerprint, err := w.inputsFingerprint()
+	if err != nil {
+		return fmt.Errorf("failed to fingerprint circuit: %w", err)
+	}
+
+	w.r1cs, w.cacheKey = r1cs, key
+	w.fingerprint = fingerprint
+
+	return nil
+}
+
 func (w *Groth16Prover) Setup() error {
+	return w.SetupContext(context.Background())
+}
+
+func (w *Groth16Prover) SetupContext(ctx context.Context) error {
 	if err := w.CheckPath(); err != nil {
 		return fmt.Errorf("failed to check path: %w", err)
 	}
 
-	commonCircuitData := types.ReadCommonCircuitData(filepath.Join(w.Path, "common_circuit_data.json"))
-	proofWithPis := variables.DeserializeProofWithPublicInputs(types.ReadProofWithPublicInputs(filepath.Join(w.Path, "proof_with_public_inputs.json")))
-	verifierOnlyCircuitData := variables.DeserializeVerifierOnlyCircuitData(types.ReadVerifierOnlyCircuitData(filepath.Join(w.Path, "verifier_only_circuit_data.json")))
-
-	circuit := verifier.ExampleVerifierCircuit{
-		Proof:                   proofWithPis.Proof,
-		PublicInputs:            proofWithPis.PublicInputs,
-		VerifierOnlyCircuitData: verifierOnlyCircuitData,
-		CommonCircuitData:       commonCircuitData,
+	inputs, err := NewPlonky2InputsFromDir(w.Path)
+	if err != nil {
+		return fmt.Errorf("failed to load inputs: %w", err)
 	}
 
-	builder := r1cs.NewBuilder
+	return w.SetupWithInputsContext(ctx, inputs)
+}
 
-	r1cs, err := frontend.Compile(w.curveId.ScalarField(), builder, &circuit)
+func (w *Groth16Prover) SetupWithInputs(inputs *Plonky2Inputs) error {
+	return w.SetupWithInputsContext(context.Background(), inputs)
+}
+
+func (w *Groth16Prover) SetupWithInputsContext(ctx context.Context, inputs *Plonky2Inputs) error {
+	fingerprint, err := inputs.Fingerprint()
 	if err != nil {
-		return fmt.Errorf("failed to compile circuit: %w", err)
+		return fmt.Errorf("failed to fingerprint circuit: %w", err)
 	}
-	w.r1cs = r1cs
-
-	var errSetup error
 
-	w.pk, w.vk, errSetup = groth16.Setup(r1cs)
+	var r1cs constraint.ConstraintSystem
+	var key string
+	if err := runWithContext(ctx, func() (err error) {
+		r1cs, key, err = compileInputs(w.Cache, w.curveId, inputs, w.Observer, w.r1cs, w.cacheKey)
+		return err
+	}); err != nil {
+		return err
+	}
 
-	if errSetup != nil {
-		return fmt.Errorf("error in setting up circuit: %w", errSetup)
+	var pk groth16.ProvingKey
+	var vk groth16.VerifyingKey
+	phase := startPhase(w.Observer, PhaseSetup)
+	if err := runWithContext(ctx, func() (err error) {
+		pk, vk, err = groth16.Setup(r1cs)
+		return err
+	}); err != nil {
+		phase.end(err)
+		return fmt.Errorf("error in setting up circuit: %w", err)
 	}
+	phase.end(nil)
+
+	w.r1cs, w.pk, w.vk = r1cs, pk, vk
+	w.cacheKey = key
+	w.fingerprint = fingerprint
 
 	return nil
 }
 
 func (w *Groth16Prover) GenerateWitness() error {
-	// https://github.com/Consensys/gnark/issues/1038
-	// error in generating witness: can't set fr.Element from type expr.LinearExpression
+	return w.GenerateWitnessContext(context.Background())
+}
+
+func (w *Groth16Prover) GenerateWitnessContext(ctx context.Context) error {
+	inputs, err := NewPlonky2InputsFromDir(w.Path)
+	if err != nil {
+		return fmt.Errorf("failed to load inputs: %w", err)
+	}
 
-	proofWithPis := variables.DeserializeProofWithPublicInputs(types.ReadProofWithPublicInputs(filepath.Join(w.Path, "proof_with_public_inputs.json")))
-	verifierOnlyCircuitData := variables.DeserializeVerifierOnlyCircuitData(types.ReadVerifierOnlyCircuitData(filepath.Join(w.Path, "verifier_only_circuit_data.json")))
+	return w.GenerateWitnessWithInputsContext(ctx, inputs)
+}
 
-	assignment := verifier.ExampleVerifierCircuit{
-		Proof:                   proofWithPis.Proof,
-		PublicInputs:            proofWithPis.PublicInputs,
-		VerifierOnlyCircuitData: verifierOnlyCircuitData,
+func (w *Groth16Prover) GenerateWitnessWithInputs(inputs *Plonky2Inputs) error {
+	return w.GenerateWitnessWithInputsContext(context.Background(), inputs)
+}
+
+func (w *Groth16Prover) GenerateWitnessWithInputsContext(ctx context.Context, inputs *Plonky2Inputs) error {
+	witness, err := w.generateWitness(ctx, inputs)
+	if err != nil {
+		return err
 	}
 
-	witness, err := frontend.NewWitness(&assignment, w.curveId.ScalarField())
+	fingerprint, err := inputs.Fingerprint()
 	if err != nil {
-		return fmt.Errorf("error in generating witness: %s", err)
+		return fmt.Errorf("failed to fingerprint circuit: %w", err)
 	}
 
 	w.Witness = witness
+	w.witnessFingerprint = fingerprint
 
 	return nil
 }
 
-func (w *Groth16Prover) GenerateR1CS() error {
-	commonCircuitData := types.ReadCommonCircuitData(filepath.Join(w.Path, "common_circuit_data.json"))
-	proofWithPis := variables.DeserializeProofWithPublicInputs(types.ReadProofWithPublicInputs(filepath.Join(w.Path, "proof_with_public_inputs.json")))
-	verifierOnlyCircuitData := variables.DeserializeVerifierOnlyCircuitData(types.ReadVerifierOnlyCircuitData(filepath.Join(w.Path, "verifier_only_circuit_data.json")))
+// generateWitness builds the full witness of inputs without touching the prover's state.
+func (w *Groth16Prover) generateWitness(ctx context.Context, inputs *Plonky2Inputs) (witness.Witness, error) {
+	return GenerateWitness(ctx, inputs, w.curveId, w.Observer)
+}
 
-	circuit := verifier.ExampleVerifierCircuit{
-		Proof:                   proofWithPis.Proof,
-		PublicInputs:            proofWithPis.PublicInputs,
-		VerifierOnlyCircuitData: verifierOnlyCircuitData,
-		CommonCircuitData:       commonCircuitData,
+func (w *Groth16Prover) GenerateR1CS() error {
+	inputs, err := NewPlonky2InputsFromDir(w.Path)
+	if err != nil {
+		return fmt.Errorf("failed to load inputs: %w", err)
 	}
 
-	builder := r1cs.NewBuilder
+	return w.GenerateR1CSWithInputs(inputs)
+}
 
-	r1cs, err := frontend.Compile(ecc.BLS12_381.ScalarField(), builder, &circuit)
+func (w *Groth16Prover) GenerateR1CSWithInputs(inputs *Plonky2Inputs) error {
+	fingerprint, err := inputs.Fingerprint()
+	if err != nil {
+		return fmt.Errorf("failed to fingerprint circuit: %w", err)
+	}
 
+	r1cs, key, err := compileInputs(w.Cache, w.curveId, inputs, w.Observer, w.r1cs, w.cacheKey)
 	if err != nil {
-		return fmt.Errorf("failed to compile circuit: %w", err)
+		return err
 	}
 
-	w.r1cs = r1cs
+	w.r1cs, w.cacheKey = r1cs, key
+	w.fingerprint = fingerprint
 	return nil
 }
 
 func (w *Groth16Prover) Prove() error {
+	return w.ProveContext(context.Background())
+}
+
+// ProveContext proves Witness. Without a circuit, it looks up the one of the
+// plonky2 inputs in the compile cache.
+func (w *Groth16Prover) ProveContext(ctx context.Context) error {
+	if err := checkPublicWitness(w.Witness, w.PublicWitness); err != nil {
+		return err
+	}
+	if w.r1cs.GetNbConstraints() == 0 {
+		if err := w.loadCachedCircuit(ctx); err != nil {
+			return err
+		}
+	}
 
-	proof, err := groth16.Prove(w.r1cs, w.pk, w.Witness)
+	proof, err := w.prove(ctx, w.Witness)
+	if ctx.Err() != nil {
+		// drop the witness of the cancelled proof, the solve stops using it
+		// at its next hint
+		w.Witness, w.Proof = nil, nil
+	}
 	if err != nil {
-		return fmt.Errorf("error in creating proof: %s", err)
+		return err
 	}
 
 	w.Proof = proof
 	return nil
 }
 
+// prove proves a full witness against the loaded circuit and proving key
+// without touching the prover's state.
+func (w *Groth16Prover) prove(ctx context.Context, witness witness.Witness) (groth16.Proof, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("error in creating proof: %w", err)
+	}
+	if w.r1cs.GetNbConstraints() == 0 {
+		return nil, fmt.Errorf("circuit is not compiled or read")
+	}
+	if err := w.slots.acquire(ctx); err != nil {
+		return nil, fmt.Errorf("error in creating proof: %w", err)
+	}
+
+	phase := startPhase(contextObserver(ctx, w.Observer), PhaseProve)
+	timer := newSolveTimer(ctx)
+	var proof groth16.Proof
+	// a cancelled proof keeps its slot until gnark returns, it still uses the
+	// cpus and memory the limits are for
+	if err := runWithContextThen(ctx, func() (err error) {
+		proof, err = groth16.Prove(w.r1cs, w.pk, witness, timer.proverOption())
+		return err
+	}, w.slots.release); err != nil {
+		phase.end(err)
+		return nil, fmt.Errorf("error in creating proof: %w", err)
+	}
+	timer.report(phase, time.Now())
+	phase.end(nil)
+
+	return proof, nil
+}
+
+// SetCircuitData sets the common and verifier-only circuit data used by
+// ProvePlonky2. The proof in inputs is ignored. Without it, ProvePlonky2
+// reads both files from Path.
+func (w *Groth16Prover) SetCircuitData(inputs *Plonky2Inputs) error {
+	circuitData := inputs.WithProof(nil)
+	if _, err := circuitData.ReadVerifierOnlyCircuitData(); err != nil {
+		return fmt.Errorf("failed to load circuit data: %w", err)
+	}
+
+	w.circuitData = circuitData
+	return nil
+}
+
+// ProvePlonky2 generates the witness for a plonky2 proof_with_public_inputs
+// JSON document and proves it with the loaded circuit and proving key. It
+// doesn't change Witness or Proof and is safe for concurrent use, so the
+// prover can be kept resident and called for any number of proofs.
+func (w *Groth16Prover) ProvePlonky2(ctx context.Context, proofWithPublicInputs []byte) (*Groth16Proof, error) {
+	circuitData := w.circuitData
+	if circuitData == nil {
+		var err error
+		if circuitData, err = NewPlonky2InputsFromDir(w.Path); err != nil {
+			return nil, fmt.Errorf("failed to load inputs: %w", err)
+		}
+	}
+
+	witness, err := w.generateWitness(ctx, circuitData.WithProof(proofWithPublicInputs))
+	if err != nil {
+		return nil, err
+	}
+
+	return w.ProveWitness(ctx, witness)
+}
+
+// ProveWitness proves a full witness with the loaded circuit and proving key.
+// Like ProvePlonky2 it leaves the prover's state alone and is safe to call
+// from several goroutines; SetLimits bounds how many proofs run at once.
+func (w *Groth16Prover) ProveWitness(ctx context.Context, witness witness.Witness) (*Groth16Proof, error) {
+	publicWitness, err := witness.Public()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create publicWitness: %w", err)
+	}
+
+	proof, err := w.prove(ctx, witness)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Groth16Proof{Proof: proof, PublicWitness: publicWitness}, nil
+}
+
 func (w *Groth16Prover) ReadProvingKey(keyPath string) error {
+	return w.ReadProvingKeyContext(context.Background(), keyPath)
+}
+
+func (w *Groth16Prover) ReadProvingKeyContext(ctx context.Context, keyPath string) error {
 	if w.pk == nil {
 		return fmt.Errorf("proving key is not initialized")
 	}
 
+	fingerprint, err := checkArtifactFingerprint("proving key", keyPath, w.inputsFingerprint)
+	if err != nil {
+		return err
+	}
+
 	provingKeyFile, err := os.Open(keyPath)
 	if err != nil {
 		return fmt.Errorf("failed to open proving key file: %w", err)
 	}
 	defer provingKeyFile.Close()
 
-	_, err = w.pk.ReadFrom(provingKeyFile)
+	phase := startPhase(w.Observer, PhaseReadProvingKey)
+	progress := newProgressReader(newContextReader(ctx, provingKeyFile), phase)
+
+	_, err = artifact.ReadProvingKey(withFileSize(progress, provingKeyFile), w.pk)
+	progress.end(err)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		// drop whatever was read so far
+		w.pk = groth16.NewProvingKey(w.curveId)
+		return fmt.Errorf("failed to read proving key: %w", ctxErr)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to read proving key: %w", err)
 	}
+
+	if fingerprint != "" {
+		w.fingerprint = fingerprint
+	}
+
 	return nil
 }
 
@@ -167,7 +383,7 @@ func (w *Groth16Prover) WriteProvingKey(keyPath string) error {
 		return fmt.Errorf("proving key is not initialized")
 	}
 
-	fPK, err := os.Create(keyPath)
+	fPK, err := artifact.CreateAtomic(keyPath)
 	if err != nil {
 		return fmt.Errorf("failed to create file: %w", err)
 	}
@@ -177,6 +393,10 @@ func (w *Groth16Prover) WriteProvingKey(keyPath string) error {
 		return fmt.Errorf("failed to write verifying key to file: %w", err)
 	}
 
+	if err := fPK.CommitWithFingerprint(w.fingerprint); err != nil {
+		return fmt.Errorf("failed to write proving key to file: %w", err)
+	}
+
 	return nil
 }
 
@@ -204,7 +424,7 @@ func (w *Groth16Prover) WriteProof(keyPath string) error {
 		return fmt.Errorf("verifier or proof is not initialized")
 	}
 
-	proofFile, err := os.Create(keyPath)
+	proofFile, err := artifact.CreateAtomic(keyPath)
 	if err != nil {
 		return fmt.Errorf("failed to create file: %w", err)
 	}
@@ -214,69 +434,83 @@ func (w *Groth16Prover) WriteProof(keyPath string) error {
 		return fmt.Errorf("failed to write proof to file: %w", err)
 	}
 
+	if err := proofFile.Commit(); err != nil {
+		return fmt.Errorf("failed to write proof to file: %w", err)
+	}
+
 	return nil
 }
 
-// func (w *Groth16Prover) ReadPublicInputs(inputPath string) error {
-// 	if w.Witness == nil {
-// 		return fmt.Errorf("public inputs is not initialized")
-// 	}
-
-// 	publicinputsFile, err := os.Open(inputPath)
-// 	if err != nil {
-// 		return fmt.Errorf("failed to open public inputs file: %w", err)
-// 	}
-// 	defer publicinputsFile.Close()
-
-// 	publicwitness, err := w.Witness.Public()
-
-// 	_, err = publicwitness.ReadFrom(publicinputsFile)
-// 	if err != nil {
-// 		return fmt.Errorf("failed to read public inputs: %w", err)
-// 	}
-
-// 	return nil
-// }
-
-func (w *Groth16Prover) WritePublicInputs(inputPath string) error {
-	if w.Witness == nil {
-		return fmt.Errorf("public inputs is not initialized")
+// WriteRawProof writes the proof with uncompressed points. It is twice the
+// size of WriteProof's but is read without computing square roots.
+func (w *Groth16Prover) WriteRawProof(keyPath string) error {
+	if w.Proof == nil {
+		return fmt.Errorf("verifier or proof is not initialized")
 	}
 
-	publicinputsFile, err := os.Create(inputPath)
+	proofFile, err := artifact.CreateAtomic(keyPath)
 	if err != nil {
 		return fmt.Errorf("failed to create file: %w", err)
 	}
-	defer publicinputsFile.Close()
+	defer proofFile.Close()
 
-	publicWitness, err := w.Witness.Public()
-	if err != nil {
-		return fmt.Errorf("failed to create publicWitness: %w", err)
+	if _, err := w.Proof.WriteRawTo(proofFile); err != nil {
+		return fmt.Errorf("failed to write proof to file: %w", err)
 	}
 
-	if _, err := publicWitness.WriteTo(publicinputsFile); err != nil {
+	if err := proofFile.Commit(); err != nil {
 		return fmt.Errorf("failed to write proof to file: %w", err)
 	}
 
 	return nil
 }
 
+func (w *Groth16Prover) WritePublicInputs(inputPath string) error {
+	if w.Witness == nil {
+		return fmt.Errorf("public inputs is not initialized")
+	}
+
+	return WritePublicInputs(inputPath, w.Witness)
+}
+
 func (w *Groth16Prover) ReadVerifyingKey(keyPath string) error {
+	return w.ReadVerifyingKeyContext(context.Background(), keyPath)
+}
+
+func (w *Groth16Prover) ReadVerifyingKeyContext(ctx context.Context, keyPath string) error {
 	if w.vk == nil {
 		return fmt.Errorf("verifying key is not initialized")
 	}
 
+	fingerprint, err := checkArtifactFingerprint("verifying key", keyPath, w.inputsFingerprint)
+	if err != nil {
+		return err
+	}
+
 	verifyingKeyFile, err := os.Open(keyPath)
 	if err != nil {
 		return fmt.Errorf("failed to open verifying key file: %w", err)
 	}
 	defer verifyingKeyFile.Close()
 
-	_, err = w.vk.ReadFrom(verifyingKeyFile)
+	phase := startPhase(w.Observer, PhaseReadVerifyingKey)
+	progress := newProgressReader(newContextReader(ctx, verifyingKeyFile), phase)
+
+	_, err = artifact.ReadVerifyingKey(withFileSize(progress, verifyingKeyFile), w.vk)
+	progress.end(err)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		// drop whatever was read so far
+		w.vk = groth16.NewVerifyingKey(w.curveId)
+		return fmt.Errorf("failed to read verifying key: %w", ctxErr)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to read verifying key: %w", err)
 	}
 
+	if fingerprint != "" {
+		w.fingerprint = fingerprint
+	}
+
 	return nil
 }
 
@@ -285,7 +519,7 @@ func (w *Groth16Prover) WriteVerifyingKey(keyPath string) error {
 		return fmt.Errorf("verifying key is not initialized")
 	}
 
-	fVK, err := os.Create(keyPath)
+	fVK, err := artifact.CreateAtomic(keyPath)
 	if err != nil {
 		return fmt.Errorf("failed to create file: %w", err)
 	}
@@ -295,25 +529,52 @@ func (w *Groth16Prover) WriteVerifyingKey(keyPath string) error {
 		return fmt.Errorf("failed to write verifying key to file: %w", err)
 	}
 
+	if err := fVK.CommitWithFingerprint(w.fingerprint); err != nil {
+		return fmt.Errorf("failed to write verifying key to file: %w", err)
+	}
+
 	return nil
 }
 
 func (w *Groth16Prover) ReadCircuit(keyPath string) error {
+	return w.ReadCircuitContext(context.Background(), keyPath)
+}
+
+func (w *Groth16Prover) ReadCircuitContext(ctx context.Context, keyPath string) error {
 	if w.r1cs == nil {
 		return fmt.Errorf("r1cs is not initialized")
 	}
 
+	fingerprint, err := checkArtifactFingerprint("circuit", keyPath, w.inputsFingerprint)
+	if err != nil {
+		return err
+	}
+
 	circuitFile, err := os.Open(keyPath)
 	if err != nil {
 		return fmt.Errorf("failed to open circuit file: %w", err)
 	}
 	defer circuitFile.Close()
 
-	_, err = w.r1cs.ReadFrom(circuitFile)
+	phase := startPhase(w.Observer, PhaseReadCircuit)
+	progress := newProgressReader(newContextReader(ctx, circuitFile), phase)
+
+	w.cacheKey = ""
+	_, err = w.r1cs.ReadFrom(progress)
+	progress.end(err)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		// drop whatever was read so far
+		w.r1cs = groth16.NewCS(w.curveId)
+		return fmt.Errorf("failed to read circuit: %w", ctxErr)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to read circuit: %w", err)
 	}
 
+	if fingerprint != "" {
+		w.fingerprint = fingerprint
+	}
+
 	return nil
 }
 
@@ -322,7 +583,7 @@ func (w *Groth16Prover) WriteCircuit(keyPath string) error {
 		return fmt.Errorf("r1cs is not initialized")
 	}
 
-	circuitFile, err := os.Create(keyPath)
+	circuitFile, err := artifact.CreateAtomic(keyPath)
 	if err != nil {
 		return fmt.Errorf("failed to circuit file: %w", err)
 	}
@@ -332,5 +593,47 @@ func (w *Groth16Prover) WriteCircuit(keyPath string) error {
 		return fmt.Errorf("failed to write circuit to file: %w", err)
 	}
 
+	if err := circuitFile.CommitWithFingerprint(w.fingerprint); err != nil {
+		return fmt.Errorf("failed to write circuit to file: %w", err)
+	}
+
+	return nil
+}
+
+// ReadBundle loads the circuit, proving key and verifying key from a bundle
+// written by Groth16Worker.WriteBundle.
+func (w *Groth16Prover) ReadBundle(bundlePath string) error {
+	bundle, err := artifact.OpenBundle(bundlePath)
+	if err != nil {
+		return err
+	}
+	defer bundle.Close()
+
+	if err := bundle.Header.Check(w.curveId); err != nil {
+		return err
+	}
+
+	if err := checkFingerprint("bundle", bundle.Header.Fingerprint, w.inputsFingerprint); err != nil {
+		return err
+	}
+
+	r1cs := groth16.NewCS(w.curveId)
+	if err := bundle.ReadSection(artifact.SectionCircuit, r1cs); err != nil {
+		return err
+	}
+
+	pk := groth16.NewProvingKey(w.curveId)
+	if err := bundle.ReadSection(artifact.SectionProvingKey, pk); err != nil {
+		return err
+	}
+
+	vk := groth16.NewVerifyingKey(w.curveId)
+	if err := bundle.ReadSection(artifact.SectionVerifyingKey, vk); err != nil {
+		return err
+	}
+
+	w.r1cs, w.pk, w.vk = r1cs, pk, vk
+	w.fingerprint = bundle.Header.Fingerprint
+
 	return nil
 }
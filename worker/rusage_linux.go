@@ -0,0 +1,52 @@
+package worker
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+func processCPUTime() time.Duration {
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		return 0
+	}
+	return time.Duration(usage.Utime.Nano() + usage.Stime.Nano())
+}
+
+// resetPeakRSS resets VmHWM, see proc(5). It needs linux 4.0 or later and
+// fails silently before.
+func resetPeakRSS() {
+	os.WriteFile("/proc/self/clear_refs", []byte("5"), 0)
+}
+
+// peakRSS returns VmHWM in bytes, falling back to the peak of the process
+// reported by getrusage.
+func peakRSS() int64 {
+	status, err := os.ReadFile("/proc/self/status")
+	if err == nil {
+		for _, line := range bytes.Split(status, []byte("\n")) {
+			value, ok := bytes.CutPrefix(line, []byte("VmHWM:"))
+			if !ok {
+				continue
+			}
+			// "VmHWM:     1234 kB"
+			fields := bytes.Fields(value)
+			if len(fields) == 0 {
+				break
+			}
+			if kb, err := strconv.ParseInt(string(fields[0]), 10, 64); err == nil {
+				return kb << 10
+			}
+		}
+	}
+
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		return 0
+	}
+	// kilobytes on linux
+	return usage.Maxrss << 10
+}
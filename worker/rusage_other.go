@@ -0,0 +1,15 @@
+//go:build !linux
+
+package worker
+
+import "time"
+
+func processCPUTime() time.Duration {
+	return 0
+}
+
+func resetPeakRSS() {}
+
+func peakRSS() int64 {
+	return 0
+}
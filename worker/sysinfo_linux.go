@@ -0,0 +1,43 @@
+package worker
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// availableMemory returns MemAvailable from /proc/meminfo in bytes, or 0 if
+// it can't be read.
+func availableMemory() int64 {
+	file, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// "MemAvailable:   1234 kB"
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "MemAvailable:" {
+			continue
+		}
+		if kb, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+			return kb << 10
+		}
+	}
+
+	return 0
+}
+
+// freeDiskSpace returns the bytes available to unprivileged users on the
+// filesystem holding dir, or 0 if it can't be read.
+func freeDiskSpace(dir string) int64 {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return 0
+	}
+	return int64(stat.Bavail) * int64(stat.Bsize)
+}
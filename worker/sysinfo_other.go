@@ -0,0 +1,11 @@
+//go:build !linux
+
+package worker
+
+func availableMemory() int64 {
+	return 0
+}
+
+func freeDiskSpace(dir string) int64 {
+	return 0
+}
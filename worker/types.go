@@ -8,26 +8,49 @@ import (
 )
 
 type Groth16Prover struct {
-	Path    string
-	curveId ecc.ID
-	pk      groth16.ProvingKey
-	vk      groth16.VerifyingKey
-	Proof   groth16.Proof
-	Witness witness.Witness
-	r1cs    constraint.ConstraintSystem
+	Path     string
+	curveId  ecc.ID
+	pk       groth16.ProvingKey
+	vk       groth16.VerifyingKey
+	Proof    groth16.Proof
+	Witness  witness.Witness
+	r1cs     constraint.ConstraintSystem
+	Observer Observer
+	// Cache holds compiled circuits, see CompileCache. Nil disables it.
+	Cache *CompileCache
+	// PublicWitness is the public witness read by ReadPublicInputs, the
+	// public part of Witness must match it.
+	PublicWitness witness.Witness
+
+	circuitData *Plonky2Inputs
+	slots       proofSlots
+	fingerprint string
+	// cacheKey is the cache key of r1cs, if it was compiled or loaded from
+	// cache.
+	cacheKey string
+	// witnessFingerprint is the fingerprint of the circuit Witness was
+	// generated for.
+	witnessFingerprint string
 }
 
-type Groth16Verifier struct {
-	Path          string
-	Vk            groth16.VerifyingKey
+// Groth16Proof is the result of a single ProvePlonky2 call.
+type Groth16Proof struct {
 	Proof         groth16.Proof
 	PublicWitness witness.Witness
 }
 
 type Groth16Worker struct {
-	Path    string
-	curveId ecc.ID
-	Pk      groth16.ProvingKey
-	Vk      groth16.VerifyingKey
-	r1cs    constraint.ConstraintSystem
+	Path     string
+	curveId  ecc.ID
+	Pk       groth16.ProvingKey
+	Vk       groth16.VerifyingKey
+	r1cs     constraint.ConstraintSystem
+	Observer Observer
+	// Cache holds compiled circuits, see CompileCache. Nil disables it.
+	Cache *CompileCache
+
+	fingerprint string
+	// cacheKey is the cache key of r1cs, if it was compiled or loaded from
+	// cache.
+	cacheKey string
 }
@@ -0,0 +1,246 @@
+package worker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/cf/gnark-plonky2-verifier/plonk/gates"
+	"github.com/cf/gnark-plonky2-verifier/types"
+)
+
+var (
+	ErrInvalidJSON          = errors.New("invalid json")
+	ErrInvalidValue         = errors.New("invalid value")
+	ErrWrongLength          = errors.New("wrong length")
+	ErrUnsupportedGate      = errors.New("unsupported gate")
+	ErrUnsupportedCircuit   = errors.New("unsupported circuit")
+	ErrMissingPublicInputs  = errors.New("missing public inputs")
+	ErrMalformedPlonky2Data = errors.New("malformed plonky2 data")
+)
+
+// goldilocksModulus is the order of the plonky2 base field, 2^64 - 2^32 + 1.
+const goldilocksModulus uint64 = 0xFFFFFFFF00000001
+
+// InputError reports a problem in one of the plonky2 JSON inputs. Path is the
+// JSON path of the offending value, e.g. proof.openings.wires[3].
+type InputError struct {
+	File   string
+	Path   string
+	Err    error
+	Detail string
+}
+
+func (e *InputError) Error() string {
+	msg := e.File
+	if e.Path != "" {
+		msg += ": " + e.Path
+	}
+	msg += ": " + e.Err.Error()
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+	return msg
+}
+
+func (e *InputError) Unwrap() error {
+	return e.Err
+}
+
+// unmarshalInput decodes data into v. The plonky2 raw types panic on some
+// malformed documents, so panics are turned into an InputError as well.
+func unmarshalInput(file string, data []byte, v any) (err error) {
+	defer recoverInputError(file, "", &err)
+
+	if err := json.Unmarshal(data, v); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		var syntaxErr *json.SyntaxError
+		switch {
+		case errors.As(err, &typeErr):
+			return &InputError{File: file, Path: typeErr.Field, Err: ErrInvalidJSON, Detail: fmt.Sprintf("cannot decode %s into %s", typeErr.Value, typeErr.Type)}
+		case errors.As(err, &syntaxErr):
+			return &InputError{File: file, Err: ErrInvalidJSON, Detail: fmt.Sprintf("%s at offset %d", syntaxErr, syntaxErr.Offset)}
+		default:
+			return &InputError{File: file, Err: ErrInvalidJSON, Detail: err.Error()}
+		}
+	}
+
+	return nil
+}
+
+func recoverInputError(file string, path string, err *error) {
+	if r := recover(); r != nil {
+		*err = &InputError{File: file, Path: path, Err: ErrMalformedPlonky2Data, Detail: fmt.Sprint(r)}
+	}
+}
+
+// inputValidator records the first problem found in a plonky2 input file.
+type inputValidator struct {
+	file string
+	err  error
+}
+
+func (v *inputValidator) fail(path string, err error, format string, args ...any) {
+	if v.err == nil {
+		v.err = &InputError{File: v.file, Path: path, Err: err, Detail: fmt.Sprintf(format, args...)}
+	}
+}
+
+func (v *inputValidator) length(path string, got int, want uint64) bool {
+	if uint64(got) != want {
+		v.fail(path, ErrWrongLength, "expected %d entries, got %d", want, got)
+		return false
+	}
+	return true
+}
+
+func (v *inputValidator) hash(path string, hash string) {
+	value, ok := new(big.Int).SetString(hash, 10)
+	if !ok || value.Sign() < 0 || value.Cmp(plonky2HashField) >= 0 {
+		v.fail(path, ErrInvalidValue, "%q is not a %s scalar field element", hash, plonky2HashCurve)
+	}
+}
+
+func (v *inputValidator) hashes(path string, hashes []string, want uint64) {
+	if !v.length(path, len(hashes), want) {
+		return
+	}
+
+	for i, hash := range hashes {
+		v.hash(fmt.Sprintf("%s[%d]", path, i), hash)
+	}
+}
+
+func (v *inputValidator) goldilocks(path string, values []uint64, want uint64) {
+	if !v.length(path, len(values), want) {
+		return
+	}
+
+	for i, value := range values {
+		if value >= goldilocksModulus {
+			v.fail(fmt.Sprintf("%s[%d]", path, i), ErrInvalidValue, "%d is not a goldilocks field element", value)
+			return
+		}
+	}
+}
+
+func (v *inputValidator) extensions(path string, values [][]uint64, want uint64) {
+	if !v.length(path, len(values), want) {
+		return
+	}
+
+	for i, value := range values {
+		v.goldilocks(fmt.Sprintf("%s[%d]", path, i), value, 2)
+	}
+}
+
+func validateCommonCircuitData(raw types.CommonCircuitDataRaw) error {
+	v := &inputValidator{file: CommonCircuitDataFile}
+
+	if raw.FriParams.Hiding {
+		v.fail("fri_params.hiding", ErrUnsupportedCircuit, "circuits with hiding enabled are not supported")
+	}
+
+	if raw.Config.FriConfig.CapHeight >= 32 {
+		v.fail("config.fri_config.cap_height", ErrInvalidValue, "cap height %d is too large", raw.Config.FriConfig.CapHeight)
+	}
+
+	totalArities := uint64(0)
+	for _, bits := range raw.FriParams.ReductionArityBits {
+		totalArities += bits
+	}
+	if totalArities > raw.FriParams.DegreeBits {
+		v.fail("fri_params.reduction_arity_bits", ErrInvalidValue, "reduction arities (%d bits) exceed degree bits %d", totalArities, raw.FriParams.DegreeBits)
+	}
+
+	if len(raw.Gates) == 0 {
+		v.fail("gates", ErrWrongLength, "circuit has no gates")
+	}
+	for i, gateId := range raw.Gates {
+		if err := checkGate(gateId); err != nil {
+			v.fail(fmt.Sprintf("gates[%d]", i), ErrUnsupportedGate, "%s", err)
+		}
+	}
+
+	v.length("selectors_info.selector_indices", len(raw.SelectorsInfo.SelectorIndices), uint64(len(raw.Gates)))
+	for i, index := range raw.SelectorsInfo.SelectorIndices {
+		if index >= uint64(len(raw.SelectorsInfo.Groups)) {
+			v.fail(fmt.Sprintf("selectors_info.selector_indices[%d]", i), ErrInvalidValue, "selector group %d does not exist", index)
+		}
+	}
+
+	return v.err
+}
+
+func checkGate(gateId string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	gates.GateInstanceFromId(gateId)
+	return nil
+}
+
+func validateProofWithPublicInputs(raw types.ProofWithPublicInputsRaw, common types.CommonCircuitDataRaw) error {
+	v := &inputValidator{file: ProofWithPublicInputsFile}
+
+	capLength := uint64(1) << common.Config.FriConfig.CapHeight
+	numChallenges := common.Config.NumChallenges
+
+	v.hashes("proof.wires_cap", raw.Proof.WiresCap, capLength)
+	v.hashes("proof.plonk_zs_partial_products_cap", raw.Proof.PlonkZsPartialProductsCap, capLength)
+	v.hashes("proof.quotient_polys_cap", raw.Proof.QuotientPolysCap, capLength)
+
+	openings := raw.Proof.Openings
+	v.extensions("proof.openings.constants", openings.Constants, common.NumConstants)
+	v.extensions("proof.openings.plonk_sigmas", openings.PlonkSigmas, common.Config.NumRoutedWires)
+	v.extensions("proof.openings.wires", openings.Wires, common.Config.NumWires)
+	v.extensions("proof.openings.plonk_zs", openings.PlonkZs, numChallenges)
+	v.extensions("proof.openings.plonk_zs_next", openings.PlonkZsNext, numChallenges)
+	v.extensions("proof.openings.partial_products", openings.PartialProducts, numChallenges*common.NumPartialProducts)
+	v.extensions("proof.openings.quotient_polys", openings.QuotientPolys, numChallenges*common.QuotientDegreeFactor)
+
+	openingProof := raw.Proof.OpeningProof
+	numReductions := uint64(len(common.FriParams.ReductionArityBits))
+	if v.length("proof.opening_proof.commit_phase_merkle_caps", len(openingProof.CommitPhaseMerkleCaps), numReductions) {
+		for i, merkleCap := range openingProof.CommitPhaseMerkleCaps {
+			v.hashes(fmt.Sprintf("proof.opening_proof.commit_phase_merkle_caps[%d]", i), merkleCap, capLength)
+		}
+	}
+
+	if v.length("proof.opening_proof.query_round_proofs", len(openingProof.QueryRoundProofs), common.Config.FriConfig.NumQueryRounds) {
+		for i, round := range openingProof.QueryRoundProofs {
+			path := fmt.Sprintf("proof.opening_proof.query_round_proofs[%d]", i)
+			if len(round.InitialTreesProof.EvalsProofs) == 0 {
+				v.fail(path+".initial_trees_proof.evals_proofs", ErrWrongLength, "no evaluation proofs")
+			}
+			v.length(path+".steps", len(round.Steps), numReductions)
+		}
+	}
+
+	totalArities := uint64(0)
+	for _, bits := range common.FriParams.ReductionArityBits {
+		totalArities += bits
+	}
+	finalPolyLength := uint64(1) << (common.FriParams.DegreeBits - totalArities)
+	v.extensions("proof.opening_proof.final_poly.coeffs", openingProof.FinalPoly.Coeffs, finalPolyLength)
+
+	if uint64(len(raw.PublicInputs)) < common.NumPublicInputs {
+		v.fail("public_inputs", ErrMissingPublicInputs, "expected %d public inputs, got %d", common.NumPublicInputs, len(raw.PublicInputs))
+	}
+	v.goldilocks("public_inputs", raw.PublicInputs, common.NumPublicInputs)
+
+	return v.err
+}
+
+func validateVerifierOnlyCircuitData(raw types.VerifierOnlyCircuitDataRaw, common types.CommonCircuitDataRaw) error {
+	v := &inputValidator{file: VerifierOnlyCircuitDataFile}
+
+	v.hashes("constants_sigmas_cap", raw.ConstantsSigmasCap, uint64(1)<<common.Config.FriConfig.CapHeight)
+	v.hash("circuit_digest", raw.CircuitDigest)
+
+	return v.err
+}
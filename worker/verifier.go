@@ -1,112 +1,113 @@
 package worker
 
 import (
+	"context"
 	"fmt"
-	"os"
-	"path/filepath"
 
-	"github.com/cf/gnark-plonky2-verifier/types"
-	"github.com/cf/gnark-plonky2-verifier/variables"
-	"github.com/cf/gnark-plonky2-verifier/verifier"
 	"github.com/consensys/gnark-crypto/ecc"
-	"github.com/consensys/gnark/backend/groth16"
-	"github.com/consensys/gnark/backend/witness"
-	"github.com/consensys/gnark/frontend"
-	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/zilong-dai/groth16-worker/artifact"
+	"github.com/zilong-dai/groth16-worker/verifier"
 )
 
-func NewGroth16Verifier(Path string, curveId ecc.ID) (*Groth16Verifier, error) {
-	vk := groth16.NewVerifyingKey(curveId)
+// Groth16Verifier is the verifier this package used to provide, which can set
+// up its own verifying key from the plonky2 inputs in Path.
+//
+// Deprecated: use verifier.Groth16Verifier with a verifying key written by
+// Groth16Worker. Groth16Verifier will be removed in the next release.
+type Groth16Verifier struct {
+	*verifier.Groth16Verifier
+	Path     string
+	Observer Observer
+
+	// fingerprint is the fingerprint of the circuit set up by Setup.
+	fingerprint string
+}
 
-	proof := groth16.NewProof(curveId)
+// NewGroth16Verifier returns a verifier on curveId with an empty verifying key.
+//
+// Deprecated: use verifier.NewGroth16Verifier or
+// verifier.NewGroth16VerifierFromFile.
+func NewGroth16Verifier(Path string, curveId ecc.ID) (*Groth16Verifier, error) {
+	if err := CheckCurve(curveId); err != nil {
+		return nil, err
+	}
 
-	publicWitness, err := witness.New(curveId.ScalarField())
+	v, err := verifier.NewGroth16Verifier(curveId)
 	if err != nil {
-		return nil, fmt.Errorf("error creating public witness: %w", err)
+		return nil, err
 	}
-	return &Groth16Verifier{Path: Path, Vk: vk, Proof: proof, PublicWitness: publicWitness}, nil
+
+	return &Groth16Verifier{Groth16Verifier: v, Path: Path}, nil
 }
 
+// CheckPath checks that the plonky2 inputs are in Path.
+//
+// Deprecated: use Groth16Worker.CheckPath.
 func (w *Groth16Verifier) CheckPath() error {
-	files := []string{
-		"common_circuit_data.json",
-		"proof_with_public_inputs.json",
-		"verifier_only_circuit_data.json",
-	}
-
-	for _, file := range files {
-		path := filepath.Join(w.Path, file)
-		if _, err := os.Stat(path); err != nil {
-			if os.IsNotExist(err) {
-				return fmt.Errorf("path %s does not exist", path)
-			} else {
-				return fmt.Errorf("error checking path %s: %s", path, err)
-			}
-		}
-	}
-
-	return nil
+	return (&Groth16Worker{Path: w.Path}).CheckPath()
 }
 
+// Setup compiles the circuit of the plonky2 inputs in Path and runs a setup
+// to get its verifying key.
+//
+// Deprecated: run Groth16Worker.Setup once, write the verifying key and load
+// it with verifier.NewGroth16VerifierFromFile.
 func (w *Groth16Verifier) Setup() error {
-	if err := w.CheckPath(); err != nil {
-		return fmt.Errorf("failed to check path: %w", err)
-	}
+	return w.SetupContext(context.Background())
+}
 
-	commonCircuitData := types.ReadCommonCircuitData(filepath.Join(w.Path, "common_circuit_data.json"))
-	proofWithPis := variables.DeserializeProofWithPublicInputs(types.ReadProofWithPublicInputs(filepath.Join(w.Path, "proof_with_public_inputs.json")))
-	verifierOnlyCircuitData := variables.DeserializeVerifierOnlyCircuitData(types.ReadVerifierOnlyCircuitData(filepath.Join(w.Path, "verifier_only_circuit_data.json")))
+// Deprecated: see Setup.
+func (w *Groth16Verifier) SetupContext(ctx context.Context) error {
+	return w.setup(func(setup *Groth16Worker) error {
+		return setup.SetupContext(ctx)
+	})
+}
 
-	circuit := verifier.ExampleVerifierCircuit{
-		Proof:                   proofWithPis.Proof,
-		PublicInputs:            proofWithPis.PublicInputs,
-		VerifierOnlyCircuitData: verifierOnlyCircuitData,
-		CommonCircuitData:       commonCircuitData,
-	}
+// Deprecated: see Setup.
+func (w *Groth16Verifier) SetupWithInputs(inputs *Plonky2Inputs) error {
+	return w.SetupWithInputsContext(context.Background(), inputs)
+}
 
-	builder := r1cs.NewBuilder
+// Deprecated: see Setup.
+func (w *Groth16Verifier) SetupWithInputsContext(ctx context.Context, inputs *Plonky2Inputs) error {
+	return w.setup(func(setup *Groth16Worker) error {
+		return setup.SetupWithInputsContext(ctx, inputs)
+	})
+}
 
-	r1cs, err := frontend.Compile(ecc.BLS12_381.ScalarField(), builder, &circuit)
+// setup runs a setup on a Groth16Worker and keeps its verifying key.
+func (w *Groth16Verifier) setup(run func(*Groth16Worker) error) error {
+	setup, err := NewGroth16Worker(w.Path, w.Vk.CurveID())
 	if err != nil {
-		return fmt.Errorf("failed to compile circuit: %w", err)
+		return err
 	}
+	setup.Observer = w.Observer
 
-	var errSetup error
-
-	_, w.Vk, errSetup = groth16.Setup(r1cs)
-
-	if errSetup != nil {
-		return fmt.Errorf("error in setting up circuit: %w", errSetup)
+	if err := run(setup); err != nil {
+		return err
 	}
 
+	w.Vk = setup.Vk
+	w.fingerprint = setup.fingerprint
+
 	return nil
 }
 
-func (w *Groth16Verifier) Verify() error {
-	if w.Vk == nil || w.PublicWitness == nil || w.Proof == nil {
-		return fmt.Errorf("Verifier keys, public witness, or proof are not set")
-	}
-	if err := groth16.Verify(w.Proof, w.Vk, w.PublicWitness); err != nil {
-		return fmt.Errorf("error in verifying proof: %w", err)
+// Fingerprint returns the fingerprint of the circuit set up by Setup, or of
+// the verifying key read with ReadVerifyingKey.
+func (w *Groth16Verifier) Fingerprint() string {
+	if w.fingerprint != "" {
+		return w.fingerprint
 	}
-	return nil
+	return w.Groth16Verifier.Fingerprint()
 }
 
 func (w *Groth16Verifier) ReadVerifyingKey(keyPath string) error {
-	if w.Vk == nil {
-		return fmt.Errorf("verifying key is not initialized")
+	if err := w.Groth16Verifier.ReadVerifyingKey(keyPath); err != nil {
+		return err
 	}
 
-	verifyingKeyFile, err := os.Open(keyPath)
-	if err != nil {
-		return fmt.Errorf("failed to open verifying key file: %w", err)
-	}
-	defer verifyingKeyFile.Close()
-
-	_, err = w.Vk.ReadFrom(verifyingKeyFile)
-	if err != nil {
-		return fmt.Errorf("failed to read verifying key: %w", err)
-	}
+	w.fingerprint = ""
 
 	return nil
 }
@@ -116,7 +117,7 @@ func (w *Groth16Verifier) WriteVerifyingKey(keyPath string) error {
 		return fmt.Errorf("verifying key is not initialized")
 	}
 
-	fVK, err := os.Create(keyPath)
+	fVK, err := artifact.CreateAtomic(keyPath)
 	if err != nil {
 		return fmt.Errorf("failed to create file: %w", err)
 	}
@@ -126,78 +127,8 @@ func (w *Groth16Verifier) WriteVerifyingKey(keyPath string) error {
 		return fmt.Errorf("failed to write verifying key to file: %w", err)
 	}
 
-	return nil
-}
-
-func (w *Groth16Verifier) ReadProof(keyPath string) error {
-	if w.Proof == nil {
-		return fmt.Errorf("proof is not initialized")
-	}
-
-	proofFile, err := os.Open(keyPath)
-	if err != nil {
-		return fmt.Errorf("failed to open proof file: %w", err)
-	}
-	defer proofFile.Close()
-
-	_, err = w.Proof.ReadFrom(proofFile)
-	if err != nil {
-		return fmt.Errorf("failed to read proof: %w", err)
-	}
-
-	return nil
-}
-
-func (w *Groth16Verifier) WriteProof(keyPath string) error {
-	if w.Proof == nil {
-		return fmt.Errorf("verifier or proof is not initialized")
-	}
-
-	fVK, err := os.Create(keyPath)
-	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
-	}
-	defer fVK.Close()
-
-	if _, err := w.Proof.WriteTo(fVK); err != nil {
-		return fmt.Errorf("failed to write proof to file: %w", err)
-	}
-
-	return nil
-}
-
-func (w *Groth16Verifier) ReadPublicInputs(inputPath string) error {
-	if w.PublicWitness == nil {
-		return fmt.Errorf("public inputs is not initialized")
-	}
-
-	publicinputsFile, err := os.Open(inputPath)
-	if err != nil {
-		return fmt.Errorf("failed to open public inputs file: %w", err)
-	}
-	defer publicinputsFile.Close()
-
-	_, err = w.PublicWitness.ReadFrom(publicinputsFile)
-	if err != nil {
-		return fmt.Errorf("failed to read public inputs: %w", err)
-	}
-
-	return nil
-}
-
-func (w *Groth16Verifier) WritePublicInputs(inputPath string) error {
-	if w.PublicWitness == nil {
-		return fmt.Errorf("public inputs is not initialized")
-	}
-
-	publicinputsFile, err := os.Create(inputPath)
-	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
-	}
-	defer publicinputsFile.Close()
-
-	if _, err := w.PublicWitness.WriteTo(publicinputsFile); err != nil {
-		return fmt.Errorf("failed to write proof to file: %w", err)
+	if err := fVK.CommitWithFingerprint(w.Fingerprint()); err != nil {
+		return fmt.Errorf("failed to write verifying key to file: %w", err)
 	}
 
 	return nil
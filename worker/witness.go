@@ -0,0 +1,205 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/frontend"
+	"github.com/zilong-dai/groth16-worker/artifact"
+)
+
+// ErrPublicWitnessMismatch is returned when the public part of a full witness
+// isn't the public witness it was written with.
+var ErrPublicWitnessMismatch = errors.New("public witness mismatch")
+
+// GenerateWitness generates the full witness of the plonky2 inputs on
+// curveId. It needs neither a circuit nor keys, so witnesses can be generated
+// on a light machine, written with WriteWitness and proven elsewhere.
+func GenerateWitness(ctx context.Context, inputs *Plonky2Inputs, curveId ecc.ID, observer Observer) (witness.Witness, error) {
+	// https://github.com/Consensys/gnark/issues/1038
+	// error in generating witness: can't set fr.Element from type expr.LinearExpression
+
+	if err := CheckCurve(curveId); err != nil {
+		return nil, err
+	}
+
+	assignment, err := inputs.Assignment()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load assignment: %w", err)
+	}
+
+	phase := startPhase(contextObserver(ctx, observer), PhaseWitness)
+	var full witness.Witness
+	if err := runWithContext(ctx, func() (err error) {
+		full, err = frontend.NewWitness(assignment, curveId.ScalarField())
+		return err
+	}); err != nil {
+		phase.end(err)
+		return nil, fmt.Errorf("error in generating witness: %w", err)
+	}
+	phase.end(nil)
+
+	return full, nil
+}
+
+// WriteWitness writes the full witness, secret part included, so it can be
+// proven by another process with ReadWitness. The fingerprint of the circuit
+// it was generated for is stored next to it.
+func (w *Groth16Prover) WriteWitness(witnessPath string) error {
+	if w.Witness == nil || witnessLen(w.Witness) == 0 {
+		return fmt.Errorf("witness is not generated")
+	}
+
+	return WriteWitness(witnessPath, w.Witness, w.witnessFingerprint)
+}
+
+// WriteWitness writes a full witness generated by GenerateWitness, with the
+// fingerprint of its circuit next to it, for Groth16Prover.ReadWitness.
+func WriteWitness(witnessPath string, full witness.Witness, fingerprint string) error {
+	witnessFile, err := artifact.CreateAtomic(witnessPath)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer witnessFile.Close()
+
+	if _, err := full.WriteTo(witnessFile); err != nil {
+		return fmt.Errorf("failed to write witness to file: %w", err)
+	}
+	if err := witnessFile.CommitWithFingerprint(fingerprint); err != nil {
+		return fmt.Errorf("failed to write witness to file: %w", err)
+	}
+
+	return nil
+}
+
+// WritePublicInputs writes the public part of a full witness, as
+// Groth16Prover.WritePublicInputs does.
+func WritePublicInputs(inputPath string, full witness.Witness) error {
+	publicWitness, err := full.Public()
+	if err != nil {
+		return fmt.Errorf("failed to create publicWitness: %w", err)
+	}
+
+	publicinputsFile, err := artifact.CreateAtomic(inputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer publicinputsFile.Close()
+
+	if _, err := publicWitness.WriteTo(publicinputsFile); err != nil {
+		return fmt.Errorf("failed to write public inputs to file: %w", err)
+	}
+
+	return publicinputsFile.Commit()
+}
+
+// ReadWitness reads a full witness written by WriteWitness, in place of
+// GenerateWitness. If public inputs were read first with ReadPublicInputs,
+// the public part of the witness must match them.
+func (w *Groth16Prover) ReadWitness(witnessPath string) error {
+	fingerprint, err := checkArtifactFingerprint("witness", witnessPath, w.circuitFingerprint)
+	if err != nil {
+		return err
+	}
+
+	witnessFile, err := os.Open(witnessPath)
+	if err != nil {
+		return fmt.Errorf("failed to open witness file: %w", err)
+	}
+	defer witnessFile.Close()
+
+	full, err := witness.New(w.curveId.ScalarField())
+	if err != nil {
+		return fmt.Errorf("error creating witness: %w", err)
+	}
+	if _, err := full.ReadFrom(witnessFile); err != nil {
+		return fmt.Errorf("failed to read witness: %w", err)
+	}
+
+	if err := checkPublicWitness(full, w.PublicWitness); err != nil {
+		return err
+	}
+
+	w.Witness = full
+	w.witnessFingerprint = fingerprint
+
+	return nil
+}
+
+// ReadPublicInputs reads a public witness written by WritePublicInputs. If a
+// full witness is already loaded, its public part must match.
+func (w *Groth16Prover) ReadPublicInputs(inputPath string) error {
+	publicinputsFile, err := os.Open(inputPath)
+	if err != nil {
+		return fmt.Errorf("failed to open public inputs file: %w", err)
+	}
+	defer publicinputsFile.Close()
+
+	publicWitness, err := witness.New(w.curveId.ScalarField())
+	if err != nil {
+		return fmt.Errorf("error creating public witness: %w", err)
+	}
+	if _, err := publicWitness.ReadFrom(publicinputsFile); err != nil {
+		return fmt.Errorf("failed to read public inputs: %w", err)
+	}
+
+	if err := checkPublicWitness(w.Witness, publicWitness); err != nil {
+		return err
+	}
+
+	w.PublicWitness = publicWitness
+
+	return nil
+}
+
+// circuitFingerprint is the fingerprint of the loaded circuit, or of the
+// plonky2 inputs if no circuit was loaded with one.
+func (w *Groth16Prover) circuitFingerprint() (string, error) {
+	if w.fingerprint != "" {
+		return w.fingerprint, nil
+	}
+	return w.inputsFingerprint()
+}
+
+// checkPublicWitness checks that the public part of full is public. Either
+// may be empty, in which case there is nothing to check.
+func checkPublicWitness(full, public witness.Witness) error {
+	if full == nil || public == nil || witnessLen(full) == 0 || witnessLen(public) == 0 {
+		return nil
+	}
+
+	fullPublic, err := full.Public()
+	if err != nil {
+		return fmt.Errorf("failed to create publicWitness: %w", err)
+	}
+
+	got, err := fullPublic.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("failed to encode public witness: %w", err)
+	}
+	want, err := public.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("failed to encode public witness: %w", err)
+	}
+
+	if !bytes.Equal(got, want) {
+		return fmt.Errorf("%w: the witness doesn't have the public inputs it was written with", ErrPublicWitnessMismatch)
+	}
+
+	return nil
+}
+
+// witnessLen returns the number of values in w, public and secret.
+func witnessLen(w witness.Witness) int {
+	vector := reflect.ValueOf(w.Vector())
+	if vector.Kind() != reflect.Slice {
+		return 0
+	}
+	return vector.Len()
+}
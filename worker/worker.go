@@ -1,34 +1,41 @@
 package worker
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 
-	"github.com/cf/gnark-plonky2-verifier/types"
-	"github.com/cf/gnark-plonky2-verifier/variables"
-	"github.com/cf/gnark-plonky2-verifier/verifier"
 	"github.com/consensys/gnark-crypto/ecc"
 	"github.com/consensys/gnark/backend/groth16"
-	"github.com/consensys/gnark/frontend"
-	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/constraint"
+	"github.com/zilong-dai/groth16-worker/artifact"
 )
 
+// NewGroth16Worker returns a worker for the plonky2 inputs in Path. Nothing
+// is read until a step needs it; KeyGen loads the circuit of the inputs from
+// the compile cache if none was compiled or read, so it can run without
+// Compile.
 func NewGroth16Worker(Path string, curveId ecc.ID) (*Groth16Worker, error) {
+	if err := CheckCurve(curveId); err != nil {
+		return nil, err
+	}
+
 	pk := groth16.NewProvingKey(curveId)
 
 	vk := groth16.NewVerifyingKey(curveId)
 
 	r1cs := groth16.NewCS(curveId)
 
-	return &Groth16Worker{Path: Path, Pk: pk, Vk: vk, curveId: curveId, r1cs: r1cs}, nil
+	return &Groth16Worker{Path: Path, Pk: pk, Vk: vk, r1cs: r1cs, curveId: curveId, Cache: DefaultCompileCache()}, nil
 }
 
 func (w *Groth16Worker) CheckPath() error {
 	files := []string{
-		"common_circuit_data.json",
-		"proof_with_public_inputs.json",
-		"verifier_only_circuit_data.json",
+		CommonCircuitDataFile,
+		ProofWithPublicInputsFile,
+		VerifierOnlyCircuitDataFile,
 	}
 
 	for _, file := range files {
@@ -45,57 +52,194 @@ func (w *Groth16Worker) CheckPath() error {
 	return nil
 }
 
+// inputsFingerprint fingerprints the plonky2 inputs in Path.
+func (w *Groth16Worker) inputsFingerprint() (string, error) {
+	return FingerprintFromDir(w.Path)
+}
+
+// loadCachedCircuit loads the circuit of the plonky2 inputs in Path from the
+// compile cache. It leaves the worker alone on a miss, or if there are no
+// inputs.
+func (w *Groth16Worker) loadCachedCircuit(ctx context.Context) error {
+	circuitData, err := circuitDataFromDir(w.Path)
+	if err != nil || circuitData == nil {
+		return err
+	}
+
+	r1cs, key, err := cachedCircuit(ctx, w.Cache, circuitData, w.curveId, w.Observer)
+	if err != nil || r1cs == nil {
+		return err
+	}
+
+	fingerprint, err := w.inputsFingerprint()
+	if err != nil {
+		return fmt.Errorf("failed to fingerprint circuit: %w", err)
+	}
+
+	w.r1cs, w.cacheKey = r1cs, key
+	w.fingerprint = fingerprint
+
+	return nil
+}
+
 func (w *Groth16Worker) Setup() error {
+	return w.SetupContext(context.Background())
+}
+
+func (w *Groth16Worker) SetupContext(ctx context.Context) error {
 	if err := w.CheckPath(); err != nil {
 		return fmt.Errorf("failed to check path: %w", err)
 	}
 
-	commonCircuitData := types.ReadCommonCircuitData(filepath.Join(w.Path, "common_circuit_data.json"))
-	proofWithPis := variables.DeserializeProofWithPublicInputs(types.ReadProofWithPublicInputs(filepath.Join(w.Path, "proof_with_public_inputs.json")))
-	verifierOnlyCircuitData := variables.DeserializeVerifierOnlyCircuitData(types.ReadVerifierOnlyCircuitData(filepath.Join(w.Path, "verifier_only_circuit_data.json")))
+	inputs, err := NewPlonky2InputsFromDir(w.Path)
+	if err != nil {
+		return fmt.Errorf("failed to load inputs: %w", err)
+	}
+
+	return w.SetupWithInputsContext(ctx, inputs)
+}
+
+func (w *Groth16Worker) SetupWithInputs(inputs *Plonky2Inputs) error {
+	return w.SetupWithInputsContext(context.Background(), inputs)
+}
+
+// SetupWithInputsContext compiles the circuit and generates its keys, the same
+// as CompileWithInputsContext followed by KeyGenContext.
+func (w *Groth16Worker) SetupWithInputsContext(ctx context.Context, inputs *Plonky2Inputs) error {
+	if err := w.CompileWithInputsContext(ctx, inputs); err != nil {
+		return err
+	}
+
+	return w.KeyGenContext(ctx)
+}
+
+// Compile compiles the circuit of the plonky2 inputs in Path, the first step
+// of Setup. The r1cs can then be written with WriteCircuit and the keys
+// generated later, or on another machine, with ReadCircuit and KeyGen.
+func (w *Groth16Worker) Compile() error {
+	return w.CompileContext(context.Background())
+}
 
-	circuit := verifier.ExampleVerifierCircuit{
-		Proof:                   proofWithPis.Proof,
-		PublicInputs:            proofWithPis.PublicInputs,
-		VerifierOnlyCircuitData: verifierOnlyCircuitData,
-		CommonCircuitData:       commonCircuitData,
+func (w *Groth16Worker) CompileContext(ctx context.Context) error {
+	if err := w.CheckPath(); err != nil {
+		return fmt.Errorf("failed to check path: %w", err)
 	}
 
-	builder := r1cs.NewBuilder
+	inputs, err := NewPlonky2InputsFromDir(w.Path)
+	if err != nil {
+		return fmt.Errorf("failed to load inputs: %w", err)
+	}
+
+	return w.CompileWithInputsContext(ctx, inputs)
+}
+
+func (w *Groth16Worker) CompileWithInputs(inputs *Plonky2Inputs) error {
+	return w.CompileWithInputsContext(context.Background(), inputs)
+}
 
-	r1cs, err := frontend.Compile(w.curveId.ScalarField(), builder, &circuit)
+func (w *Groth16Worker) CompileWithInputsContext(ctx context.Context, inputs *Plonky2Inputs) error {
+	fingerprint, err := inputs.Fingerprint()
 	if err != nil {
-		return fmt.Errorf("failed to compile circuit: %w", err)
+		return fmt.Errorf("failed to fingerprint circuit: %w", err)
+	}
+
+	var r1cs constraint.ConstraintSystem
+	var key string
+	if err := runWithContext(ctx, func() (err error) {
+		r1cs, key, err = compileInputs(w.Cache, w.curveId, inputs, w.Observer, w.r1cs, w.cacheKey)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	w.r1cs, w.cacheKey = r1cs, key
+	w.fingerprint = fingerprint
+
+	return nil
+}
+
+// Constraints returns the number of constraints of the compiled or read
+// circuit.
+func (w *Groth16Worker) Constraints() int {
+	if w.r1cs == nil {
+		return 0
 	}
-	w.r1cs = r1cs
+	return w.r1cs.GetNbConstraints()
+}
 
-	var errSetup error
+// KeyGen generates the proving and verifying keys of the circuit compiled by
+// Compile or read by ReadCircuit, the second step of Setup. It doesn't need
+// the plonky2 inputs; without a circuit, it looks up the one of the inputs in
+// Path in the compile cache.
+func (w *Groth16Worker) KeyGen() error {
+	return w.KeyGenContext(context.Background())
+}
 
-	w.Pk, w.Vk, errSetup = groth16.Setup(r1cs)
+func (w *Groth16Worker) KeyGenContext(ctx context.Context) error {
+	if w.Constraints() == 0 {
+		if err := w.loadCachedCircuit(ctx); err != nil {
+			return err
+		}
+	}
+	if w.Constraints() == 0 {
+		return fmt.Errorf("circuit is not compiled or read")
+	}
 
-	if errSetup != nil {
-		return fmt.Errorf("error in setting up circuit: %w", errSetup)
+	var pk groth16.ProvingKey
+	var vk groth16.VerifyingKey
+	phase := startPhase(w.Observer, PhaseSetup)
+	if err := runWithContext(ctx, func() (err error) {
+		pk, vk, err = groth16.Setup(w.r1cs)
+		return err
+	}); err != nil {
+		phase.end(err)
+		return fmt.Errorf("error in setting up circuit: %w", err)
 	}
+	phase.end(nil)
+
+	w.Pk, w.Vk = pk, vk
 
 	return nil
 }
 
 func (w *Groth16Worker) ReadProvingKey(keyPath string) error {
+	return w.ReadProvingKeyContext(context.Background(), keyPath)
+}
+
+func (w *Groth16Worker) ReadProvingKeyContext(ctx context.Context, keyPath string) error {
 	if w.Pk == nil {
 		return fmt.Errorf("proving key is not initialized")
 	}
 
+	fingerprint, err := checkArtifactFingerprint("proving key", keyPath, w.inputsFingerprint)
+	if err != nil {
+		return err
+	}
+
 	provingKeyFile, err := os.Open(keyPath)
 	if err != nil {
 		return fmt.Errorf("failed to open proving key file: %w", err)
 	}
 	defer provingKeyFile.Close()
 
-	_, err = w.Pk.ReadFrom(provingKeyFile)
+	phase := startPhase(w.Observer, PhaseReadProvingKey)
+	progress := newProgressReader(newContextReader(ctx, provingKeyFile), phase)
+
+	_, err = artifact.ReadProvingKey(withFileSize(progress, provingKeyFile), w.Pk)
+	progress.end(err)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		// drop whatever was read so far
+		w.Pk = groth16.NewProvingKey(w.curveId)
+		return fmt.Errorf("failed to read proving key: %w", ctxErr)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to read proving key: %w", err)
 	}
 
+	if fingerprint != "" {
+		w.fingerprint = fingerprint
+	}
+
 	return nil
 }
 
@@ -104,7 +248,7 @@ func (w *Groth16Worker) WriteProvingKey(keyPath string) error {
 		return fmt.Errorf("proving key is not initialized")
 	}
 
-	fPK, err := os.Create(keyPath)
+	fPK, err := artifact.CreateAtomic(keyPath)
 	if err != nil {
 		return fmt.Errorf("failed to create file: %w", err)
 	}
@@ -113,26 +257,62 @@ func (w *Groth16Worker) WriteProvingKey(keyPath string) error {
 	if _, err := w.Pk.WriteRawTo(fPK); err != nil {
 		return fmt.Errorf("failed to write verifying key to file: %w", err)
 	}
+	if err := fPK.CommitWithFingerprint(w.fingerprint); err != nil {
+		return fmt.Errorf("failed to write proving key to file: %w", err)
+	}
 
 	return nil
 }
 
+// WriteProvingKeyFormat writes the proving key in format, one of
+// FormatCompressed, FormatRaw and FormatDump, with its sha256 next to it.
+func (w *Groth16Worker) WriteProvingKeyFormat(keyPath string, format string) error {
+	if w.Pk == nil {
+		return fmt.Errorf("proving key is not initialized")
+	}
+
+	_, _, err := writeKey(keyPath, w.Pk, ArtifactProvingKey, format, w.fingerprint)
+	return err
+}
+
 func (w *Groth16Worker) ReadVerifyingKey(keyPath string) error {
+	return w.ReadVerifyingKeyContext(context.Background(), keyPath)
+}
+
+func (w *Groth16Worker) ReadVerifyingKeyContext(ctx context.Context, keyPath string) error {
 	if w.Vk == nil {
 		return fmt.Errorf("verifying key is not initialized")
 	}
 
+	fingerprint, err := checkArtifactFingerprint("verifying key", keyPath, w.inputsFingerprint)
+	if err != nil {
+		return err
+	}
+
 	verifyingKeyFile, err := os.Open(keyPath)
 	if err != nil {
 		return fmt.Errorf("failed to open verifying key file: %w", err)
 	}
 	defer verifyingKeyFile.Close()
 
-	_, err = w.Vk.ReadFrom(verifyingKeyFile)
+	phase := startPhase(w.Observer, PhaseReadVerifyingKey)
+	progress := newProgressReader(newContextReader(ctx, verifyingKeyFile), phase)
+
+	_, err = artifact.ReadVerifyingKey(withFileSize(progress, verifyingKeyFile), w.Vk)
+	progress.end(err)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		// drop whatever was read so far
+		w.Vk = groth16.NewVerifyingKey(w.curveId)
+		return fmt.Errorf("failed to read verifying key: %w", ctxErr)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to read verifying key: %w", err)
 	}
 
+	if fingerprint != "" {
+		w.fingerprint = fingerprint
+	}
+
 	return nil
 }
 
@@ -141,7 +321,7 @@ func (w *Groth16Worker) WriteVerifyingKey(keyPath string) error {
 		return fmt.Errorf("verifying key is not initialized")
 	}
 
-	fVK, err := os.Create(keyPath)
+	fVK, err := artifact.CreateAtomic(keyPath)
 	if err != nil {
 		return fmt.Errorf("failed to create file: %w", err)
 	}
@@ -150,26 +330,52 @@ func (w *Groth16Worker) WriteVerifyingKey(keyPath string) error {
 	if _, err := w.Vk.WriteTo(fVK); err != nil {
 		return fmt.Errorf("failed to write verifying key to file: %w", err)
 	}
+	if err := fVK.CommitWithFingerprint(w.fingerprint); err != nil {
+		return fmt.Errorf("failed to write verifying key to file: %w", err)
+	}
 
 	return nil
 }
 
 func (w *Groth16Worker) ReadCircuit(keyPath string) error {
+	return w.ReadCircuitContext(context.Background(), keyPath)
+}
+
+func (w *Groth16Worker) ReadCircuitContext(ctx context.Context, keyPath string) error {
 	if w.r1cs == nil {
 		return fmt.Errorf("r1cs is not initialized")
 	}
 
+	fingerprint, err := checkArtifactFingerprint("circuit", keyPath, w.inputsFingerprint)
+	if err != nil {
+		return err
+	}
+
 	circuitFile, err := os.Open(keyPath)
 	if err != nil {
 		return fmt.Errorf("failed to open circuit file: %w", err)
 	}
 	defer circuitFile.Close()
 
-	_, err = w.r1cs.ReadFrom(circuitFile)
+	phase := startPhase(w.Observer, PhaseReadCircuit)
+	progress := newProgressReader(newContextReader(ctx, circuitFile), phase)
+
+	w.cacheKey = ""
+	_, err = w.r1cs.ReadFrom(progress)
+	progress.end(err)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		// drop whatever was read so far
+		w.r1cs = groth16.NewCS(w.curveId)
+		return fmt.Errorf("failed to read circuit: %w", ctxErr)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to read circuit: %w", err)
 	}
 
+	if fingerprint != "" {
+		w.fingerprint = fingerprint
+	}
+
 	return nil
 }
 
@@ -178,7 +384,7 @@ func (w *Groth16Worker) WriteCircuit(keyPath string) error {
 		return fmt.Errorf("r1cs is not initialized")
 	}
 
-	circuitFile, err := os.Create(keyPath)
+	circuitFile, err := artifact.CreateAtomic(keyPath)
 	if err != nil {
 		return fmt.Errorf("failed to circuit file: %w", err)
 	}
@@ -187,6 +393,69 @@ func (w *Groth16Worker) WriteCircuit(keyPath string) error {
 	if _, err := w.r1cs.WriteTo(circuitFile); err != nil {
 		return fmt.Errorf("failed to write circuit to file: %w", err)
 	}
+	if err := circuitFile.CommitWithFingerprint(w.fingerprint); err != nil {
+		return fmt.Errorf("failed to write circuit to file: %w", err)
+	}
 
 	return nil
 }
+
+// WriteBundle writes the circuit, proving key, verifying key and metadata of
+// the last setup to a single file, see artifact.OpenBundle.
+func (w *Groth16Worker) WriteBundle(bundlePath string) error {
+	if w.r1cs == nil || w.Pk == nil || w.Vk == nil {
+		return fmt.Errorf("r1cs, proving key or verifying key is not initialized")
+	}
+
+	bundleFile, err := artifact.CreateAtomic(bundlePath)
+	if err != nil {
+		return fmt.Errorf("failed to create bundle file: %w", err)
+	}
+	defer bundleFile.Close()
+
+	writer, err := artifact.NewBundleWriter(bundleFile, artifact.BundleHeader{
+		Curve:        w.curveId.String(),
+		Backend:      artifact.BackendGroth16,
+		GnarkVersion: artifact.GnarkVersion(),
+		Fingerprint:  w.fingerprint,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+
+	nbPublic, nbSecret := w.r1cs.GetNbPublicVariables(), w.r1cs.GetNbSecretVariables()
+	metadata := artifact.BundleMetadata{
+		NumConstraints: w.r1cs.GetNbConstraints(),
+		// the first public variable is the constant one wire
+		NumPublicInputs: nbPublic - 1,
+		NumSecretInputs: nbSecret,
+	}
+
+	if err := writer.Section(artifact.SectionCircuit, w.r1cs); err != nil {
+		return err
+	}
+	if err := writer.Section(artifact.SectionProvingKey, rawProvingKey{w.Pk}); err != nil {
+		return err
+	}
+	if err := writer.Section(artifact.SectionVerifyingKey, w.Vk); err != nil {
+		return err
+	}
+	if err := writer.Section(artifact.SectionMetadata, &metadata); err != nil {
+		return err
+	}
+
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	return bundleFile.Commit()
+}
+
+// rawProvingKey writes a proving key with uncompressed points, like WriteProvingKey.
+type rawProvingKey struct {
+	groth16.ProvingKey
+}
+
+func (pk rawProvingKey) WriteTo(w io.Writer) (int64, error) {
+	return pk.WriteRawTo(w)
+}
@@ -1,12 +1,39 @@
 package worker_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/constraint/solver"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/zilong-dai/groth16-worker/artifact"
+	"github.com/zilong-dai/groth16-worker/verifier"
 	"github.com/zilong-dai/groth16-worker/worker"
 )
 
+func TestMain(m *testing.M) {
+	// tests that want the compile cache point it at a temporary directory
+	os.Setenv(worker.EnvCacheDir, "")
+	os.Exit(m.Run())
+}
+
 func TestGroth16Verifier(t *testing.T) {
 	verifierPath := "../testdata"
 
@@ -22,6 +49,140 @@ func TestGroth16Verifier(t *testing.T) {
 
 }
 
+func TestGroth16VerifierFromBytes(t *testing.T) {
+	circuitPath, pkPath, _, vk := setupCubic(t, t.TempDir())
+
+	prover, err := worker.NewGroth16Prover("../testdata", ecc.BLS12_381)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := prover.ReadCircuit(circuitPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := prover.ReadProvingKey(pkPath); err != nil {
+		t.Fatal(err)
+	}
+
+	witness, err := frontend.NewWitness(&cubicCircuit{X: 3, Y: 35}, ecc.BLS12_381.ScalarField())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := prover.ProveWitness(context.Background(), witness)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the verifier is built from the key alone, as if it was embedded
+	var key bytes.Buffer
+	if _, err := vk.WriteTo(&key); err != nil {
+		t.Fatal(err)
+	}
+
+	groth16Verifier, err := verifier.NewGroth16VerifierFromBytes(key.Bytes(), ecc.BLS12_381)
+	if err != nil {
+		t.Fatal(err)
+	}
+	groth16Verifier.Proof, groth16Verifier.PublicWitness = result.Proof, result.PublicWitness
+
+	if err := groth16Verifier.Verify(); err != nil {
+		t.Fatal(err)
+	}
+
+	wrong, err := frontend.NewWitness(&cubicCircuit{Y: 36}, ecc.BLS12_381.ScalarField(), frontend.PublicOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+	groth16Verifier.PublicWitness = wrong
+
+	if err := groth16Verifier.Verify(); err == nil {
+		t.Fatal("expected verification to fail for the wrong public inputs")
+	}
+}
+
+func TestVerifyReport(t *testing.T) {
+	circuitPath, pkPath, _, vk := setupCubic(t, t.TempDir())
+
+	prover, err := worker.NewGroth16Prover("../testdata", ecc.BLS12_381)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := prover.ReadCircuit(circuitPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := prover.ReadProvingKey(pkPath); err != nil {
+		t.Fatal(err)
+	}
+
+	full, err := frontend.NewWitness(&cubicCircuit{X: 3, Y: 35}, ecc.BLS12_381.ScalarField())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := prover.ProveWitness(context.Background(), full)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	groth16Verifier, err := verifier.NewGroth16VerifierFromKey(vk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	groth16Verifier.Proof, groth16Verifier.PublicWitness = result.Proof, result.PublicWitness
+
+	report, err := groth16Verifier.Report()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Valid || report.Failure != "" {
+		t.Fatalf("expected a valid report, got %+v", report)
+	}
+	if len(report.PublicInputs) != 1 || report.PublicInputs[0] != 35 {
+		t.Fatalf("expected public inputs [35], got %v", report.PublicInputs)
+	}
+	if len(report.VerifyingKey) != 64 || len(report.Proof) != 64 {
+		t.Fatalf("expected sha256 hashes of the key and proof, got %q and %q", report.VerifyingKey, report.Proof)
+	}
+
+	wrong, err := frontend.NewWitness(&cubicCircuit{Y: 36}, ecc.BLS12_381.ScalarField(), frontend.PublicOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+	groth16Verifier.PublicWitness = wrong
+
+	report, err = groth16Verifier.Report()
+	if !errors.Is(err, verifier.ErrInvalidProof) || report.Valid || report.Failure != verifier.FailurePairing {
+		t.Fatalf("expected a pairing failure, got %+v, %v", report, err)
+	}
+	if len(report.PublicInputs) != 1 || report.PublicInputs[0] != 36 {
+		t.Fatalf("expected the wrong public inputs to be reported, got %v", report.PublicInputs)
+	}
+
+	tooMany, err := witness.New(ecc.BLS12_381.ScalarField())
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := make(chan any, 2)
+	values <- 35
+	values <- 35
+	close(values)
+	if err := tooMany.Fill(2, 0, values); err != nil {
+		t.Fatal(err)
+	}
+	groth16Verifier.PublicWitness = tooMany
+
+	report, err = groth16Verifier.Report()
+	if !errors.Is(err, verifier.ErrPublicInputCount) || report.Failure != verifier.FailurePublicInputCount {
+		t.Fatalf("expected a public input count failure, got %+v, %v", report, err)
+	}
+
+	notGoldilocks, err := frontend.NewWitness(&cubicCircuit{Y: "18446744069414584321"}, ecc.BLS12_381.ScalarField(), frontend.PublicOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifier.DecodePublicInputs(notGoldilocks); !errors.Is(err, verifier.ErrInvalidEncoding) {
+		t.Fatalf("expected an encoding error for a public input above the goldilocks modulus, got %v", err)
+	}
+}
+
 func TestGroth16Prover(t *testing.T) {
 	proverPath := "../testdata"
 
@@ -35,3 +196,1432 @@ func TestGroth16Prover(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestPlonky2InputsFromReaders(t *testing.T) {
+	inputsPath := "../testdata"
+
+	fromDir, err := worker.NewPlonky2InputsFromDir(inputsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fromReaders, err := worker.NewPlonky2InputsFromReaders(
+		bytes.NewReader(fromDir.CommonCircuitData),
+		bytes.NewReader(fromDir.ProofWithPublicInputs),
+		bytes.NewReader(fromDir.VerifierOnlyCircuitData),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	circuit, err := fromReaders.Circuit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(circuit.PublicInputs) != int(circuit.CommonCircuitData.NumPublicInputs) {
+		t.Fatalf("expected %d public inputs, got %d", circuit.CommonCircuitData.NumPublicInputs, len(circuit.PublicInputs))
+	}
+
+	if _, err := worker.NewPlonky2InputsFromBytes([]byte("{"), fromDir.ProofWithPublicInputs, fromDir.VerifierOnlyCircuitData).Circuit(); err == nil {
+		t.Fatal("expected an error for truncated common circuit data")
+	}
+}
+
+func TestPlonky2InputsValidation(t *testing.T) {
+	inputs, err := worker.NewPlonky2InputsFromDir("../testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := inputs.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	mutate := func(data []byte, edit func(doc map[string]any)) []byte {
+		var doc map[string]any
+		if err := json.Unmarshal(data, &doc); err != nil {
+			t.Fatal(err)
+		}
+		edit(doc)
+		out, err := json.Marshal(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name   string
+		inputs *worker.Plonky2Inputs
+		file   string
+		path   string
+		err    error
+	}{
+		{
+			name:   "truncated proof",
+			inputs: worker.NewPlonky2InputsFromBytes(inputs.CommonCircuitData, inputs.ProofWithPublicInputs[:1000], inputs.VerifierOnlyCircuitData),
+			file:   worker.ProofWithPublicInputsFile,
+			err:    worker.ErrInvalidJSON,
+		},
+		{
+			name: "short wires cap",
+			inputs: worker.NewPlonky2InputsFromBytes(inputs.CommonCircuitData, mutate(inputs.ProofWithPublicInputs, func(doc map[string]any) {
+				proof := doc["proof"].(map[string]any)
+				proof["wires_cap"] = proof["wires_cap"].([]any)[1:]
+			}), inputs.VerifierOnlyCircuitData),
+			file: worker.ProofWithPublicInputsFile,
+			path: "proof.wires_cap",
+			err:  worker.ErrWrongLength,
+		},
+		{
+			name: "unsupported gate",
+			inputs: worker.NewPlonky2InputsFromBytes(mutate(inputs.CommonCircuitData, func(doc map[string]any) {
+				doc["gates"].([]any)[0] = "LookupGate"
+			}), inputs.ProofWithPublicInputs, inputs.VerifierOnlyCircuitData),
+			file: worker.CommonCircuitDataFile,
+			path: "gates[0]",
+			err:  worker.ErrUnsupportedGate,
+		},
+		{
+			name: "missing public inputs",
+			inputs: worker.NewPlonky2InputsFromBytes(inputs.CommonCircuitData, mutate(inputs.ProofWithPublicInputs, func(doc map[string]any) {
+				delete(doc, "public_inputs")
+			}), inputs.VerifierOnlyCircuitData),
+			file: worker.ProofWithPublicInputsFile,
+			path: "public_inputs",
+			err:  worker.ErrMissingPublicInputs,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.inputs.Validate()
+
+			var inputErr *worker.InputError
+			if !errors.As(err, &inputErr) {
+				t.Fatalf("expected an input error, got %v", err)
+			}
+			if inputErr.File != tt.file || inputErr.Path != tt.path || !errors.Is(err, tt.err) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestProofHash(t *testing.T) {
+	inputs, err := worker.NewPlonky2InputsFromDir("../testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := inputs.ProofHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, inputs.ProofWithPublicInputs, "", "  "); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := inputs.WithProof(indented.Bytes()).ProofHash(); err != nil || got != hash {
+		t.Fatalf("expected a reformatted proof to hash the same, got %s, %v", got, err)
+	}
+
+	var proof map[string]any
+	if err := json.Unmarshal(inputs.ProofWithPublicInputs, &proof); err != nil {
+		t.Fatal(err)
+	}
+	proof["public_inputs"].([]any)[0] = 1
+	otherProof, err := json.Marshal(proof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := inputs.WithProof(otherProof).ProofHash(); err != nil || got == hash {
+		t.Fatalf("expected another proof to hash differently, got %s, %v", got, err)
+	}
+
+	var verifierOnly map[string]any
+	if err := json.Unmarshal(inputs.VerifierOnlyCircuitData, &verifierOnly); err != nil {
+		t.Fatal(err)
+	}
+	verifierOnly["circuit_digest"] = "1"
+	otherVerifierOnly, err := json.Marshal(verifierOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := worker.NewPlonky2InputsFromBytes(inputs.CommonCircuitData, inputs.ProofWithPublicInputs, otherVerifierOnly)
+	if got, err := other.ProofHash(); err != nil || got == hash {
+		t.Fatalf("expected the proof to hash differently for another circuit, got %s, %v", got, err)
+	}
+
+	if _, err := inputs.WithProof([]byte(`{"proof": {}}`)).ProofHash(); err == nil {
+		t.Fatal("expected a malformed proof to be rejected")
+	}
+}
+
+func TestUnsupportedCurve(t *testing.T) {
+	if _, err := worker.NewGroth16Prover("../testdata", ecc.BN254); !errors.Is(err, worker.ErrUnsupportedCurve) {
+		t.Fatalf("expected unsupported curve error for prover, got %v", err)
+	}
+
+	if _, err := worker.NewGroth16Worker("../testdata", ecc.BN254); !errors.Is(err, worker.ErrUnsupportedCurve) {
+		t.Fatalf("expected unsupported curve error for worker, got %v", err)
+	}
+
+	if _, err := worker.NewGroth16Verifier("../testdata", ecc.BN254); !errors.Is(err, worker.ErrUnsupportedCurve) {
+		t.Fatalf("expected unsupported curve error for verifier, got %v", err)
+	}
+}
+
+func TestVerifierRejectsMixedCurves(t *testing.T) {
+	groth16Verifier, err := verifier.NewGroth16Verifier(ecc.BLS12_381)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	groth16Verifier.Proof = groth16.NewProof(ecc.BN254)
+
+	if err := groth16Verifier.Verify(); !errors.Is(err, worker.ErrCurveMismatch) {
+		t.Fatalf("expected curve mismatch error, got %v", err)
+	}
+}
+
+func TestCancelledContext(t *testing.T) {
+	prover, err := worker.NewGroth16Prover("../testdata", ecc.BLS12_381)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := prover.SetupContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected setup to be cancelled, got %v", err)
+	}
+
+	if err := prover.ProveContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected prove to be cancelled, got %v", err)
+	}
+
+	keyPath := filepath.Join(t.TempDir(), "proving.key")
+	if err := os.WriteFile(keyPath, make([]byte, 1024), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := prover.ReadProvingKeyContext(ctx, keyPath); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected proving key read to be cancelled, got %v", err)
+	}
+}
+
+type cubicCircuit struct {
+	X frontend.Variable `gnark:"x"`
+	Y frontend.Variable `gnark:",public"`
+}
+
+func (c *cubicCircuit) Define(api frontend.API) error {
+	x3 := api.Mul(c.X, c.X, c.X)
+	api.AssertIsEqual(c.Y, api.Add(x3, c.X, 5))
+	return nil
+}
+
+func TestObserverKeyRead(t *testing.T) {
+	ccs, err := frontend.Compile(ecc.BLS12_381.ScalarField(), r1cs.NewBuilder, &cubicCircuit{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, vk, err := groth16.Setup(ccs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writer, err := worker.NewGroth16Worker("../testdata", ecc.BLS12_381)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer.Vk = vk
+
+	vkPath := filepath.Join(t.TempDir(), "verifying.key")
+	if err := writer.WriteVerifyingKey(vkPath); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := worker.NewGroth16Worker("../testdata", ecc.BLS12_381)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var events []worker.Event
+	reader.Observer = worker.ObserverFunc(func(e worker.Event) {
+		events = append(events, e)
+	})
+
+	if err := reader.ReadVerifyingKey(vkPath); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 2 || events[0].Kind != worker.PhaseStarted || events[1].Kind != worker.PhaseFinished {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+	if events[1].Phase != worker.PhaseReadVerifyingKey || events[1].BytesRead == 0 {
+		t.Fatalf("unexpected finish event: %+v", events[1])
+	}
+}
+
+func TestProvePlonky2RejectsBadProof(t *testing.T) {
+	prover, err := worker.NewGroth16Prover("../testdata", ecc.BLS12_381)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inputs, err := worker.NewPlonky2InputsFromDir("../testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := prover.SetCircuitData(inputs); err != nil {
+		t.Fatal(err)
+	}
+
+	witness, proof := prover.Witness, prover.Proof
+
+	if _, err := prover.ProvePlonky2(context.Background(), []byte(`{"proof":{}}`)); !errors.Is(err, worker.ErrWrongLength) {
+		t.Fatalf("expected wrong length error, got %v", err)
+	}
+
+	if prover.Witness != witness || prover.Proof != proof {
+		t.Fatal("ProvePlonky2 changed the prover state")
+	}
+}
+
+func TestProvePlonky2(t *testing.T) {
+	if testing.Short() {
+		t.Skip("sets up the plonky2 verifier circuit")
+	}
+
+	// the keys are set up with the proof in testdata and prove another one
+	prover, err := worker.NewGroth16Prover("../testdata", ecc.BLS12_381)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := prover.Setup(); err != nil {
+		t.Fatal(err)
+	}
+	vkPath := filepath.Join(t.TempDir(), "verifying.key")
+	if err := prover.WriteVerifyingKey(vkPath); err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := os.ReadFile(filepath.Join("../testdata/groth16", worker.ProofWithPublicInputsFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	setup, err := os.ReadFile(filepath.Join("../testdata", worker.ProofWithPublicInputsFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(other, setup) {
+		t.Fatal("expected another proof than the one of the setup")
+	}
+
+	result, err := prover.ProvePlonky2(context.Background(), other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	groth16Verifier, err := verifier.NewGroth16VerifierFromFile(vkPath, ecc.BLS12_381)
+	if err != nil {
+		t.Fatal(err)
+	}
+	groth16Verifier.Proof, groth16Verifier.PublicWitness = result.Proof, result.PublicWitness
+	if err := groth16Verifier.Verify(); err != nil {
+		t.Fatalf("expected the proof of another plonky2 proof to verify: %v", err)
+	}
+
+	// the proof is checked, not only its public inputs
+	tampered := bytes.Replace(other, []byte(`"pow_witness":720575940211511267`), []byte(`"pow_witness":720575940211511268`), 1)
+	if bytes.Equal(tampered, other) {
+		t.Fatal("expected the proof to be tampered with")
+	}
+	if _, err := prover.ProvePlonky2(context.Background(), tampered); err == nil {
+		t.Fatal("expected a tampered plonky2 proof not to be proven")
+	}
+}
+
+// setupCubic runs a groth16 setup of cubicCircuit and writes its circuit and
+// raw proving key to dir.
+func setupCubic(t *testing.T, dir string) (circuitPath, pkPath string, pk groth16.ProvingKey, vk groth16.VerifyingKey) {
+	t.Helper()
+
+	ccs, err := frontend.Compile(ecc.BLS12_381.ScalarField(), r1cs.NewBuilder, &cubicCircuit{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pk, vk, err = groth16.Setup(ccs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	circuitPath = filepath.Join(dir, "circuit")
+	pkPath = filepath.Join(dir, "proving.key")
+
+	circuitFile, err := os.Create(circuitPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer circuitFile.Close()
+	if _, err := ccs.WriteTo(circuitFile); err != nil {
+		t.Fatal(err)
+	}
+
+	pkFile, err := os.Create(pkPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pkFile.Close()
+	if _, err := pk.WriteRawTo(pkFile); err != nil {
+		t.Fatal(err)
+	}
+
+	return circuitPath, pkPath, pk, vk
+}
+
+func TestKeyGen(t *testing.T) {
+	dir := t.TempDir()
+	circuitPath, _, _, _ := setupCubic(t, dir)
+
+	setupworker, err := worker.NewGroth16Worker(dir, ecc.BLS12_381)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := setupworker.KeyGen(); err == nil {
+		t.Fatal("expected key generation to fail without a circuit")
+	}
+
+	if err := setupworker.ReadCircuit(circuitPath); err != nil {
+		t.Fatal(err)
+	}
+	if setupworker.Constraints() == 0 {
+		t.Fatal("expected the constraints of the read circuit")
+	}
+	if err := setupworker.KeyGen(); err != nil {
+		t.Fatal(err)
+	}
+
+	pkPath := filepath.Join(dir, "keygen.pk")
+	if err := setupworker.WriteProvingKey(pkPath); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if strings.Contains(entry.Name(), ".tmp-") {
+			t.Fatalf("expected no temporary files to be left, found %s", entry.Name())
+		}
+	}
+
+	prover, err := worker.NewGroth16Prover("../testdata", ecc.BLS12_381)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := prover.ReadCircuit(circuitPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := prover.ReadProvingKey(pkPath); err != nil {
+		t.Fatal(err)
+	}
+	full, err := frontend.NewWitness(&cubicCircuit{X: 3, Y: 35}, ecc.BLS12_381.ScalarField())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := prover.ProveWitness(context.Background(), full)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := groth16.Verify(result.Proof, setupworker.Vk, result.PublicWitness); err != nil {
+		t.Fatalf("expected a proof with the generated proving key to verify with its verifying key: %v", err)
+	}
+}
+
+func TestCompileCache(t *testing.T) {
+	cacheDir := t.TempDir()
+	t.Setenv(worker.EnvCacheDir, cacheDir)
+	cache := worker.DefaultCompileCache()
+	if cache == nil || cache.Dir != cacheDir {
+		t.Fatalf("expected the cache in %s, got %+v", cacheDir, cache)
+	}
+
+	inputs, err := worker.NewPlonky2InputsFromDir("../testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := cache.Key(inputs, ecc.BLS12_381)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the key depends on the circuit data and the curve, not on the proof
+	reformatted := worker.NewPlonky2InputsFromBytes(append([]byte(" "), inputs.CommonCircuitData...), nil, inputs.VerifierOnlyCircuitData)
+	if other, err := cache.Key(reformatted, ecc.BLS12_381); err != nil || other != key {
+		t.Fatalf("expected the same key for reformatted common data, got %s, %v", other, err)
+	}
+	if other, err := cache.Key(inputs, ecc.BN254); err != nil || other == key {
+		t.Fatalf("expected another key on another curve, got %s, %v", other, err)
+	}
+
+	// the verifier-only data is compiled in, another circuit digest is
+	// another constraint system
+	var verifierOnly map[string]any
+	if err := json.Unmarshal(inputs.VerifierOnlyCircuitData, &verifierOnly); err != nil {
+		t.Fatal(err)
+	}
+	verifierOnly["circuit_digest"] = "1"
+	otherVerifierOnly, err := json.Marshal(verifierOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherCircuit := worker.NewPlonky2InputsFromBytes(inputs.CommonCircuitData, nil, otherVerifierOnly)
+	if other, err := cache.Key(otherCircuit, ecc.BLS12_381); err != nil || other == key {
+		t.Fatalf("expected another key for another circuit digest, got %s, %v", other, err)
+	}
+
+	if r1cs, err := cache.Load(key, ecc.BLS12_381, nil); err != nil || r1cs != nil {
+		t.Fatalf("expected a miss on an empty cache, got %v, %v", r1cs, err)
+	}
+
+	// a small circuit stands in for the verifier circuit, the prover can
+	// only get it from the cache
+	ccs, err := frontend.Compile(ecc.BLS12_381.ScalarField(), r1cs.NewBuilder, &cubicCircuit{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.Store(key, ccs); err != nil {
+		t.Fatal(err)
+	}
+
+	prover, err := worker.NewGroth16Prover("../testdata", ecc.BLS12_381)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := prover.GenerateR1CS(); err != nil {
+		t.Fatal(err)
+	}
+
+	circuitPath := filepath.Join(t.TempDir(), "circuit")
+	if err := prover.WriteCircuit(circuitPath); err != nil {
+		t.Fatal(err)
+	}
+	info, err := worker.InspectArtifact(circuitPath, ecc.BLS12_381)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Constraints != ccs.GetNbConstraints() {
+		t.Fatalf("expected the cached circuit with %d constraints, got %d", ccs.GetNbConstraints(), info.Constraints)
+	}
+	if info.Fingerprint == "" {
+		t.Fatal("expected the circuit loaded from cache to keep the fingerprint of the inputs")
+	}
+
+	// the cached circuit is only loaded by the step that needs it
+	setupworker, err := worker.NewGroth16Worker("../testdata", ecc.BLS12_381)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if setupworker.Constraints() != 0 {
+		t.Fatal("expected the constructor not to load the cached circuit")
+	}
+	if err := setupworker.KeyGen(); err != nil {
+		t.Fatal(err)
+	}
+	if setupworker.Constraints() != ccs.GetNbConstraints() {
+		t.Fatalf("expected key generation of the cached circuit with %d constraints, got %d", ccs.GetNbConstraints(), setupworker.Constraints())
+	}
+}
+
+func TestWitnessReadWrite(t *testing.T) {
+	dir := t.TempDir()
+	circuitPath, pkPath, _, vk := setupCubic(t, dir)
+
+	// the witness is generated by one prover, without circuit or keys
+	generator, err := worker.NewGroth16Prover("../testdata", ecc.BLS12_381)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := generator.WriteWitness(filepath.Join(dir, "empty")); err == nil {
+		t.Fatal("expected writing a witness that wasn't generated to fail")
+	}
+	if generator.Witness, err = frontend.NewWitness(&cubicCircuit{X: 3, Y: 35}, ecc.BLS12_381.ScalarField()); err != nil {
+		t.Fatal(err)
+	}
+	witnessPath, publicInputsPath := filepath.Join(dir, "witness"), filepath.Join(dir, "public_inputs")
+	if err := generator.WriteWitness(witnessPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := generator.WritePublicInputs(publicInputsPath); err != nil {
+		t.Fatal(err)
+	}
+
+	// and proven by another
+	prover, err := worker.NewGroth16Prover("../testdata", ecc.BLS12_381)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := prover.ReadCircuit(circuitPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := prover.ReadProvingKey(pkPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := prover.ReadPublicInputs(publicInputsPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := prover.ReadWitness(witnessPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := prover.Prove(); err != nil {
+		t.Fatal(err)
+	}
+	if err := groth16.Verify(prover.Proof, vk, prover.PublicWitness); err != nil {
+		t.Fatalf("expected the proof of the read witness to verify: %v", err)
+	}
+
+	wrong, err := frontend.NewWitness(&cubicCircuit{Y: 36}, ecc.BLS12_381.ScalarField(), frontend.PublicOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrongPath := filepath.Join(dir, "wrong_public_inputs")
+	wrongFile, err := os.Create(wrongPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wrong.WriteTo(wrongFile); err != nil {
+		t.Fatal(err)
+	}
+	wrongFile.Close()
+
+	if err := prover.ReadPublicInputs(wrongPath); !errors.Is(err, worker.ErrPublicWitnessMismatch) {
+		t.Fatalf("expected a public witness mismatch, got %v", err)
+	}
+
+	prover.PublicWitness = wrong
+	if err := prover.Prove(); !errors.Is(err, worker.ErrPublicWitnessMismatch) {
+		t.Fatalf("expected proving to check the public witness, got %v", err)
+	}
+
+	if err := artifact.WriteFingerprint(witnessPath, strings.Repeat("0", 64)); err != nil {
+		t.Fatal(err)
+	}
+	if err := prover.ReadWitness(witnessPath); !errors.Is(err, worker.ErrFingerprintMismatch) {
+		t.Fatalf("expected a witness of another circuit to be rejected, got %v", err)
+	}
+}
+
+func TestGenerateWitness(t *testing.T) {
+	inputs, err := worker.NewPlonky2InputsFromDir("../testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fingerprint, err := inputs.Fingerprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// generated from the inputs alone, no prover is built
+	full, err := worker.GenerateWitness(context.Background(), inputs, ecc.BLS12_381, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	witnessPath, publicInputsPath := filepath.Join(dir, "witness"), filepath.Join(dir, "public_inputs")
+	if err := worker.WriteWitness(witnessPath, full, fingerprint); err != nil {
+		t.Fatal(err)
+	}
+	if err := worker.WritePublicInputs(publicInputsPath, full); err != nil {
+		t.Fatal(err)
+	}
+
+	prover, err := worker.NewGroth16Prover("../testdata", ecc.BLS12_381)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := prover.ReadPublicInputs(publicInputsPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := prover.ReadWitness(witnessPath); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := worker.GenerateWitness(context.Background(), inputs, ecc.BN254, nil); !errors.Is(err, worker.ErrUnsupportedCurve) {
+		t.Fatalf("expected an unsupported curve error, got %v", err)
+	}
+}
+
+func TestConcurrentProveWitness(t *testing.T) {
+	circuitPath, pkPath, _, vk := setupCubic(t, t.TempDir())
+
+	prover, err := worker.NewGroth16Prover("../testdata", ecc.BLS12_381)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := prover.ReadCircuit(circuitPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := prover.ReadProvingKey(pkPath); err != nil {
+		t.Fatal(err)
+	}
+	prover.SetLimits(worker.ProverLimits{MaxConcurrentProofs: 2})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func(x int) {
+			defer wg.Done()
+
+			witness, err := frontend.NewWitness(&cubicCircuit{X: x, Y: x*x*x + x + 5}, ecc.BLS12_381.ScalarField())
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			result, err := prover.ProveWitness(context.Background(), witness)
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			errs <- groth16.Verify(result.Proof, vk, result.PublicWitness)
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// blockingCircuit proves the knowledge of X through blockingHint, which waits
+// for unblock, so a proof can be held in the middle of gnark's solver.
+type blockingCircuit struct {
+	X frontend.Variable
+	Y frontend.Variable `gnark:",public"`
+}
+
+var unblock = make(chan struct{})
+
+func blockingHint(_ *big.Int, inputs []*big.Int, outputs []*big.Int) error {
+	<-unblock
+	outputs[0].Set(inputs[0])
+	return nil
+}
+
+// afterBlocking counts the calls of countingHint, which the solver only
+// reaches once blockingHint has returned.
+var afterBlocking atomic.Int32
+
+func countingHint(_ *big.Int, inputs []*big.Int, outputs []*big.Int) error {
+	afterBlocking.Add(1)
+	outputs[0].Set(inputs[0])
+	return nil
+}
+
+func (c *blockingCircuit) Define(api frontend.API) error {
+	x, err := api.Compiler().NewHint(blockingHint, 1, c.X)
+	if err != nil {
+		return err
+	}
+	y, err := api.Compiler().NewHint(countingHint, 1, x[0])
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(c.Y, y[0])
+	return nil
+}
+
+func TestCancelledProveKeepsSlot(t *testing.T) {
+	solver.RegisterHint(blockingHint, countingHint)
+
+	ccs, err := frontend.Compile(ecc.BLS12_381.ScalarField(), r1cs.NewBuilder, &blockingCircuit{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk, _, err := groth16.Setup(ccs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	circuitPath, pkPath := filepath.Join(dir, "circuit"), filepath.Join(dir, "proving.key")
+	for path, from := range map[string]io.WriterTo{circuitPath: ccs, pkPath: pk} {
+		file, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := from.WriteTo(file); err != nil {
+			t.Fatal(err)
+		}
+		file.Close()
+	}
+
+	prover, err := worker.NewGroth16Prover("../testdata", ecc.BLS12_381)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := prover.ReadCircuit(circuitPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := prover.ReadProvingKey(pkPath); err != nil {
+		t.Fatal(err)
+	}
+	prover.SetLimits(worker.ProverLimits{MaxConcurrentProofs: 1})
+	var started atomic.Int32
+	prover.Observer = worker.ObserverFunc(func(e worker.Event) {
+		if e.Phase == worker.PhaseProve && e.Kind == worker.PhaseStarted {
+			started.Add(1)
+		}
+	})
+
+	full, err := frontend.NewWitness(&blockingCircuit{X: 3, Y: 3}, ecc.BLS12_381.ScalarField())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the first proof is cancelled while gnark is blocked in the hint
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := prover.ProveWitness(ctx, full); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the proof to be cancelled, got %v", err)
+	}
+
+	// gnark is still proving it, so its slot isn't free
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := prover.ProveWitness(ctx, full); !errors.Is(err, context.DeadlineExceeded) || started.Load() != 1 {
+		t.Fatalf("expected the proof to wait for the slot, got %v after %d proofs started", err, started.Load())
+	}
+
+	// shutdown waits for the cancelled proof
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := worker.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected Wait to wait for the cancelled proof, got %v", err)
+	}
+
+	close(unblock)
+	if err := worker.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// the cancelled solve stopped at the hint after the blocking one
+	if n := afterBlocking.Load(); n != 0 {
+		t.Fatalf("expected the cancelled proof to stop solving, the next hint ran %d times", n)
+	}
+
+	// the proof calls a hint, so it is split into an estimated solve and msm
+	var mu sync.Mutex
+	events := make(map[worker.Phase][]worker.Event)
+	prover.Observer = worker.ObserverFunc(func(e worker.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		events[e.Phase] = append(events[e.Phase], e)
+	})
+	if _, err := prover.ProveWitness(context.Background(), full); err != nil {
+		t.Fatal(err)
+	}
+	for _, phase := range []worker.Phase{worker.PhaseSolve, worker.PhaseMSM} {
+		got := events[phase]
+		if len(got) != 2 || got[0].Kind != worker.PhaseStarted || got[1].Kind != worker.PhaseFinished || !got[0].Estimated || !got[1].Estimated {
+			t.Fatalf("expected an estimated started and finished %s event, got %+v", phase, got)
+		}
+	}
+}
+
+func TestBundle(t *testing.T) {
+	dir := t.TempDir()
+	circuitPath, _, pk, vk := setupCubic(t, dir)
+
+	setupworker, err := worker.NewGroth16Worker("../testdata", ecc.BLS12_381)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := setupworker.ReadCircuit(circuitPath); err != nil {
+		t.Fatal(err)
+	}
+	setupworker.Pk, setupworker.Vk = pk, vk
+
+	bundlePath := filepath.Join(dir, "setup.bundle")
+	if err := setupworker.WriteBundle(bundlePath); err != nil {
+		t.Fatal(err)
+	}
+
+	bundle, err := artifact.OpenBundle(bundlePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata, err := bundle.ReadMetadata()
+	bundle.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bundle.Header.Curve != ecc.BLS12_381.String() || metadata.NumPublicInputs != 1 {
+		t.Fatalf("unexpected bundle header %+v, metadata %+v", bundle.Header, metadata)
+	}
+
+	// a bundle written by another gnark release may not decode
+	other := bundle.Header
+	other.GnarkVersion = "v0.1.0"
+	if err := other.Check(ecc.BLS12_381); !errors.Is(err, artifact.ErrGnarkVersionMismatch) {
+		t.Fatalf("expected gnark version mismatch, got %v", err)
+	}
+	other.GnarkVersion = "unknown"
+	if err := other.Check(ecc.BLS12_381); err != nil {
+		t.Fatal(err)
+	}
+
+	prover, err := worker.NewGroth16Prover("../testdata", ecc.BLS12_381)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := prover.ReadBundle(bundlePath); err != nil {
+		t.Fatal(err)
+	}
+
+	// corrupt the proving key, a verifier only reads the verifying key and
+	// must not notice
+	section, _ := bundle.Header.Section(artifact.SectionProvingKey)
+	data, err := os.ReadFile(bundlePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[section.Offset+section.Length-1] ^= 0xff
+	if err := os.WriteFile(bundlePath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	groth16Verifier, err := verifier.NewGroth16Verifier(ecc.BLS12_381)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := groth16Verifier.ReadBundle(bundlePath); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := prover.ReadBundle(bundlePath); !errors.Is(err, artifact.ErrChecksumMismatch) {
+		t.Fatalf("expected checksum mismatch, got %v", err)
+	}
+}
+
+func TestFingerprintMismatch(t *testing.T) {
+	dir := t.TempDir()
+	_, _, _, vk := setupCubic(t, dir)
+
+	inputs, err := worker.NewPlonky2InputsFromDir("../testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a second circuit that only differs in its circuit digest
+	var verifierOnly map[string]any
+	if err := json.Unmarshal(inputs.VerifierOnlyCircuitData, &verifierOnly); err != nil {
+		t.Fatal(err)
+	}
+	verifierOnly["circuit_digest"] = "1"
+	otherVerifierOnly, err := json.Marshal(verifierOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherPath := filepath.Join(dir, "other")
+	if err := os.Mkdir(otherPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(otherPath, worker.CommonCircuitDataFile), inputs.CommonCircuitData, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(otherPath, worker.VerifierOnlyCircuitDataFile), otherVerifierOnly, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fingerprint, err := inputs.Fingerprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherFingerprint, err := worker.FingerprintFromDir(otherPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fingerprint == otherFingerprint {
+		t.Fatal("different circuits have the same fingerprint")
+	}
+
+	writer, err := worker.NewGroth16Worker("../testdata", ecc.BLS12_381)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer.Vk = vk
+
+	vkPath := filepath.Join(dir, "verifying.key")
+	if err := writer.WriteVerifyingKey(vkPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(vkPath+artifact.FingerprintSuffix, []byte(fingerprint), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := worker.NewGroth16Worker("../testdata", ecc.BLS12_381)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := reader.ReadVerifyingKey(vkPath); err != nil {
+		t.Fatal(err)
+	}
+
+	// the fingerprint travels with the key when it is written again
+	copyPath := filepath.Join(dir, "verifying.key.copy")
+	if err := reader.WriteVerifyingKey(copyPath); err != nil {
+		t.Fatal(err)
+	}
+	if stored, err := artifact.ReadFingerprint(copyPath); err != nil || stored != fingerprint {
+		t.Fatalf("expected fingerprint %s, got %s (%v)", fingerprint, stored, err)
+	}
+
+	// a key without one written over it doesn't keep the stale fingerprint
+	if err := writer.WriteVerifyingKey(copyPath); err != nil {
+		t.Fatal(err)
+	}
+	if stored, err := artifact.ReadFingerprint(copyPath); err != nil || stored != "" {
+		t.Fatalf("expected no fingerprint, got %s (%v)", stored, err)
+	}
+
+	// a verifier has no circuit data to check against, it only reports the
+	// fingerprint
+	groth16Verifier, err := verifier.NewGroth16VerifierFromFile(vkPath, ecc.BLS12_381)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if groth16Verifier.Fingerprint() != fingerprint {
+		t.Fatalf("expected fingerprint %s, got %s", fingerprint, groth16Verifier.Fingerprint())
+	}
+
+	other, err := worker.NewGroth16Worker(otherPath, ecc.BLS12_381)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := other.ReadVerifyingKey(vkPath); !errors.Is(err, worker.ErrFingerprintMismatch) {
+		t.Fatalf("expected fingerprint mismatch, got %v", err)
+	}
+}
+
+func TestInspectArtifact(t *testing.T) {
+	dir := t.TempDir()
+	circuitPath, pkPath, pk, vk := setupCubic(t, dir)
+
+	prover, err := worker.NewGroth16Prover("../testdata", ecc.BLS12_381)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := prover.ReadCircuit(circuitPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := prover.ReadProvingKey(pkPath); err != nil {
+		t.Fatal(err)
+	}
+
+	witness, err := frontend.NewWitness(&cubicCircuit{X: 3, Y: 35}, ecc.BLS12_381.ScalarField())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := prover.ProveWitness(context.Background(), witness)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	write := func(name string, from interface {
+		WriteTo(w io.Writer) (int64, error)
+	}) string {
+		path := filepath.Join(dir, name)
+		file, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		if _, err := from.WriteTo(file); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	setupworker, err := worker.NewGroth16Worker("../testdata", ecc.BLS12_381)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := setupworker.ReadCircuit(circuitPath); err != nil {
+		t.Fatal(err)
+	}
+	setupworker.Pk, setupworker.Vk = pk, vk
+	bundlePath := filepath.Join(dir, "setup.bundle")
+	if err := setupworker.WriteBundle(bundlePath); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path         string
+		typ          worker.ArtifactType
+		format       string
+		publicInputs int
+	}{
+		{circuitPath, worker.ArtifactCircuit, "", 1},
+		{pkPath, worker.ArtifactProvingKey, worker.FormatRaw, 0},
+		{write("proving.key.compressed", pk), worker.ArtifactProvingKey, worker.FormatCompressed, 0},
+		{write("verifying.key", vk), worker.ArtifactVerifyingKey, worker.FormatCompressed, 1},
+		{write("proof", result.Proof), worker.ArtifactProof, worker.FormatCompressed, 0},
+		{write("public_witness", result.PublicWitness), worker.ArtifactPublicWitness, "", 1},
+		{write("witness", witness), worker.ArtifactWitness, "", 1},
+		{bundlePath, worker.ArtifactBundle, "", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.typ)+"/"+filepath.Base(tt.path), func(t *testing.T) {
+			info, err := worker.InspectArtifact(tt.path, ecc.BLS12_381)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Type != tt.typ || info.Format != tt.format || info.PublicInputs != tt.publicInputs {
+				t.Fatalf("unexpected info %+v", info)
+			}
+		})
+	}
+
+	proofInfo, err := worker.InspectArtifact(filepath.Join(dir, "proof"), ecc.BLS12_381)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(proofInfo.Points) != 3 || proofInfo.Points[1].Group != "G2" {
+		t.Fatalf("unexpected proof points %+v", proofInfo.Points)
+	}
+
+	pkInfo, err := worker.InspectArtifact(pkPath, ecc.BLS12_381)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkInfo.G1Points != pk.NbG1() || pkInfo.G2Points != pk.NbG2() {
+		t.Fatalf("expected %d G1 and %d G2 points, got %+v", pk.NbG1(), pk.NbG2(), pkInfo)
+	}
+
+	garbagePath := filepath.Join(dir, "garbage")
+	if err := os.WriteFile(garbagePath, []byte("not an artifact"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := worker.InspectArtifact(garbagePath, ecc.BLS12_381); !errors.Is(err, worker.ErrUnknownArtifact) {
+		t.Fatalf("expected unknown artifact error, got %v", err)
+	}
+}
+
+func TestConvertKey(t *testing.T) {
+	dir := t.TempDir()
+	circuitPath, pkPath, pk, vk := setupCubic(t, dir)
+
+	vkPath := filepath.Join(dir, "verifying.key")
+	vkFile, err := os.Create(vkPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer vkFile.Close()
+	if _, err := vk.WriteTo(vkFile); err != nil {
+		t.Fatal(err)
+	}
+
+	var pkDigest string
+	for _, format := range []string{worker.FormatDump, worker.FormatCompressed, worker.FormatRaw} {
+		outPath := filepath.Join(dir, "proving.key."+format)
+		conversion, err := worker.ConvertKey(pkPath, outPath, format, ecc.BLS12_381, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !conversion.Verified || conversion.From != worker.FormatRaw || conversion.To != format {
+			t.Fatalf("unexpected conversion %+v", conversion)
+		}
+		if pkDigest == "" {
+			pkDigest = conversion.KeyDigest
+		} else if conversion.KeyDigest != pkDigest {
+			t.Fatalf("%s key digest %s, expected %s", format, conversion.KeyDigest, pkDigest)
+		}
+
+		info, err := worker.InspectArtifact(outPath, ecc.BLS12_381)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Type != worker.ArtifactProvingKey || info.Format != format || info.G1Points != pk.NbG1() || info.G2Points != pk.NbG2() {
+			t.Fatalf("unexpected info %+v", info)
+		}
+		if ok, err := artifact.VerifyChecksum(outPath); !ok || err != nil {
+			t.Fatalf("checksum of %s not verified: %v", outPath, err)
+		}
+	}
+
+	// a dump converts back to the same key
+	conversion, err := worker.ConvertKey(filepath.Join(dir, "proving.key.dump"), filepath.Join(dir, "proving.key.back"), worker.FormatCompressed, ecc.BLS12_381, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conversion.From != worker.FormatDump || conversion.KeyDigest != pkDigest {
+		t.Fatalf("unexpected conversion %+v", conversion)
+	}
+
+	vkDumpPath := filepath.Join(dir, "verifying.key.dump")
+	if _, err := worker.ConvertKey(vkPath, vkDumpPath, worker.FormatDump, ecc.BLS12_381, true); err != nil {
+		t.Fatal(err)
+	}
+	vkInfo, err := worker.InspectArtifact(vkDumpPath, ecc.BLS12_381)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vkInfo.Type != worker.ArtifactVerifyingKey || vkInfo.Format != worker.FormatDump || vkInfo.PublicInputs != 1 {
+		t.Fatalf("unexpected info %+v", vkInfo)
+	}
+
+	// dumps load wherever keys are read
+	prover, err := worker.NewGroth16Prover("../testdata", ecc.BLS12_381)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := prover.ReadCircuit(circuitPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := prover.ReadProvingKey(filepath.Join(dir, "proving.key.dump")); err != nil {
+		t.Fatal(err)
+	}
+	witness, err := frontend.NewWitness(&cubicCircuit{X: 3, Y: 35}, ecc.BLS12_381.ScalarField())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := prover.ProveWitness(context.Background(), witness)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := verifier.NewGroth16VerifierFromFile(vkDumpPath, ecc.BLS12_381)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.Proof, v.PublicWitness = result.Proof, result.PublicWitness
+	if err := v.Verify(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := worker.ConvertKey(circuitPath, filepath.Join(dir, "circuit.key"), worker.FormatRaw, ecc.BLS12_381, false); !errors.Is(err, worker.ErrUnknownArtifact) {
+		t.Fatalf("expected unknown artifact error, got %v", err)
+	}
+
+	data, err := os.ReadFile(vkDumpPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a corrupted slice length fails without allocating it, whether or not
+	// the size of the input is known
+	corrupted := bytes.Clone(data)
+	binary.BigEndian.PutUint64(corrupted[artifact.DumpHeaderSize:], 1<<50)
+	corruptedPath := filepath.Join(dir, "corrupted.key.dump")
+	if err := os.WriteFile(corruptedPath, corrupted, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifier.NewGroth16VerifierFromFile(corruptedPath, ecc.BLS12_381); !errors.Is(err, artifact.ErrInvalidDump) {
+		t.Fatalf("expected invalid dump error, got %v", err)
+	}
+	if _, err := artifact.ReadVerifyingKey(io.MultiReader(bytes.NewReader(corrupted)), groth16.NewVerifyingKey(ecc.BLS12_381)); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("expected unexpected EOF, got %v", err)
+	}
+
+	data[len(data)-1] ^= 1
+	if err := os.WriteFile(vkDumpPath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := artifact.VerifyChecksum(vkDumpPath); !errors.Is(err, artifact.ErrChecksumMismatch) {
+		t.Fatalf("expected checksum mismatch, got %v", err)
+	}
+}
+
+func TestDeprecatedVerifier(t *testing.T) {
+	dir := t.TempDir()
+	_, _, pk, vk := setupCubic(t, dir)
+
+	vkPath := filepath.Join(dir, "verifying.key")
+	written, err := verifier.NewGroth16VerifierFromKey(vk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := written.WriteVerifyingKey(vkPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := artifact.WriteFingerprint(vkPath, "cubic"); err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := worker.NewGroth16Verifier("../testdata", ecc.BLS12_381)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := v.CheckPath(); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.ReadVerifyingKey(vkPath); err != nil {
+		t.Fatal(err)
+	}
+	if v.Fingerprint() != "cubic" {
+		t.Fatalf("expected fingerprint cubic, got %q", v.Fingerprint())
+	}
+
+	full, err := frontend.NewWitness(&cubicCircuit{X: 3, Y: 35}, ecc.BLS12_381.ScalarField())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ccs, err := frontend.Compile(ecc.BLS12_381.ScalarField(), r1cs.NewBuilder, &cubicCircuit{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof, err := groth16.Prove(ccs, pk, full)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, err := full.Public()
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.Proof, v.PublicWitness = proof, public
+	if err := v.Verify(); err != nil {
+		t.Fatal(err)
+	}
+
+	bundle, err := worker.OpenBundle(vkPath)
+	if err == nil {
+		bundle.Close()
+	}
+	if !errors.Is(err, worker.ErrInvalidBundle) {
+		t.Fatalf("expected invalid bundle error, got %v", err)
+	}
+}
+
+func TestMeter(t *testing.T) {
+	dir := t.TempDir()
+	_, pkPath, _, _ := setupCubic(t, dir)
+
+	var forwarded int
+	meter := &worker.Meter{Next: worker.ObserverFunc(func(worker.Event) { forwarded++ })}
+
+	prover, err := worker.NewGroth16Prover("../testdata", ecc.BLS12_381)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prover.Observer = meter
+	if err := prover.ReadProvingKey(pkPath); err != nil {
+		t.Fatal(err)
+	}
+
+	failed := errors.New("failed")
+	if err := meter.Measure(worker.PhaseVerify, func() error { return failed }); err != failed {
+		t.Fatalf("expected the error of the measured step, got %v", err)
+	}
+
+	stats := meter.Take()
+	if len(stats) != 2 || forwarded != 4 {
+		t.Fatalf("unexpected stats %+v after %d events", stats, forwarded)
+	}
+	read := stats[0]
+	if read.Phase != worker.PhaseReadProvingKey || read.Wall <= 0 || read.BytesRead == 0 || read.Allocs == 0 || read.Err != "" {
+		t.Fatalf("unexpected key read stats %+v", read)
+	}
+	if runtime.GOOS == "linux" && read.PeakRSS <= 0 {
+		t.Fatalf("expected a peak rss, got %+v", read)
+	}
+	if stats[1].Phase != worker.PhaseVerify || stats[1].Err != failed.Error() {
+		t.Fatalf("unexpected verify stats %+v", stats[1])
+	}
+
+	if stats := meter.Take(); len(stats) != 0 {
+		t.Fatalf("expected no stats after take, got %+v", stats)
+	}
+}
+
+func TestPreflight(t *testing.T) {
+	dir := t.TempDir()
+	circuitPath, pkPath, _, vk := setupCubic(t, dir)
+
+	write := func(name string, vk groth16.VerifyingKey) string {
+		path := filepath.Join(dir, name)
+		file, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		if _, err := vk.WriteTo(file); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	vkPath := write("verifying.key", vk)
+
+	status := func(report *worker.PreflightReport, name string) worker.CheckStatus {
+		for _, check := range report.Checks {
+			if check.Name == name {
+				return check.Status
+			}
+		}
+		return ""
+	}
+
+	report := worker.Preflight(worker.PreflightOptions{
+		Phase:        worker.PhaseProve,
+		Curve:        ecc.BLS12_381,
+		Dir:          "../testdata",
+		Circuit:      circuitPath,
+		ProvingKey:   pkPath,
+		VerifyingKey: vkPath,
+		Outputs:      []string{filepath.Join(dir, "proof"), filepath.Join(dir, "public_inputs")},
+	})
+	if !report.OK || report.Err() != nil || report.Constraints == 0 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	for _, name := range []string{"inputs", "circuit", "proving_key", "verifying_key", "consistency", "outputs"} {
+		if got := status(report, name); got != worker.CheckOK {
+			t.Fatalf("%s check is %q in %+v", name, got, report.Checks)
+		}
+	}
+
+	ccs, err := frontend.Compile(ecc.BLS12_381.ScalarField(), r1cs.NewBuilder, &cubicCircuit{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherVk, err := groth16.Setup(ccs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := artifact.WriteFingerprint(pkPath, "deadbeef"); err != nil {
+		t.Fatal(err)
+	}
+
+	report = worker.Preflight(worker.PreflightOptions{
+		Phase:        worker.PhaseSetup,
+		Curve:        ecc.BLS12_381,
+		Dir:          "../testdata",
+		ProvingKey:   pkPath,
+		VerifyingKey: write("other.key", otherVk),
+		Outputs:      []string{filepath.Join(dir, "missing", "circuit")},
+	})
+	if report.OK || !errors.Is(report.Err(), worker.ErrPreflightFailed) {
+		t.Fatalf("expected the preflight to fail, got %+v", report)
+	}
+	for _, name := range []string{"fingerprint", "consistency", "outputs"} {
+		if got := status(report, name); got != worker.CheckFail {
+			t.Fatalf("%s check is %q in %+v", name, got, report.Checks)
+		}
+	}
+
+	report = worker.Preflight(worker.PreflightOptions{Phase: worker.PhaseSetup, Curve: ecc.BLS12_381, Constraints: 1 << 40})
+	if runtime.GOOS == "linux" && status(report, "memory") != worker.CheckFail {
+		t.Fatalf("expected the memory check to fail in %+v", report.Checks)
+	}
+}